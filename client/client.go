@@ -0,0 +1,530 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"http_api/models"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 200 * time.Millisecond
+	defaultPollInterval = 2 * time.Second
+	defaultPageSize     = 100
+)
+
+// Client - типизированный клиент HTTP API задач
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	// timeout применяется к копии httpClient после всех опций, если задан
+	timeout      *time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	pollInterval time.Duration
+	token        string
+	namespace    string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient заменяет используемый http.Client. Сам клиент не изменяется,
+// в том числе WithTimeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout задает таймаут одной попытки запроса независимо от порядка
+// относительно WithHTTPClient (0 - без таймаута)
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = &timeout
+	}
+}
+
+// WithRetries задает число повторов и базовую задержку между ними
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithToken добавляет к запросам заголовок Authorization: Bearer
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithNamespace выбирает пространство имен задач через заголовок X-Namespace
+func WithNamespace(namespace string) Option {
+	return func(c *Client) {
+		c.namespace = namespace
+	}
+}
+
+// WithPollInterval задает интервал опроса в WaitForCompletion
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = interval
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:      u,
+		httpClient:   &http.Client{Timeout: defaultTimeout},
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		pollInterval: defaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.timeout != nil {
+		// Переданный клиент может быть общим, например http.DefaultClient
+		httpClient := *c.httpClient
+		httpClient.Timeout = *c.timeout
+		c.httpClient = &httpClient
+	}
+	return c, nil
+}
+
+type CreateTaskRequest = models.TaskCreate
+
+type ListOptions struct {
+	Page     int
+	PageSize int
+	// AllNamespaces запрашивает задачи всех пространств имен (требует права admin)
+	AllNamespaces bool
+	// Selector отбирает задачи по меткам, например "env=prod,team in (a,b),!draft"
+	Selector string
+}
+
+func (c *Client) CreateTask(ctx context.Context, req CreateTaskRequest) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodPost, "/tasks", nil, req, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListTaskTypes возвращает каталог зарегистрированных типов задач
+func (c *Client) ListTaskTypes(ctx context.Context) (*models.TaskTypeList, error) {
+	var list models.TaskTypeList
+	if err := c.do(ctx, http.MethodGet, "/task-types", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetTaskTypeStats возвращает статистику выполнения задач типа за скользящее окно
+func (c *Client) GetTaskTypeStats(ctx context.Context, name string) (*models.TaskTypeStats, error) {
+	var stats models.TaskTypeStats
+	if err := c.do(ctx, http.MethodGet, "/task-types/"+url.PathEscape(name)+"/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetTaskType возвращает описание типа задачи со схемами входа и результата
+func (c *Client) GetTaskType(ctx context.Context, name string) (*models.TaskTypeInfo, error) {
+	var info models.TaskTypeInfo
+	if err := c.do(ctx, http.MethodGet, "/task-types/"+url.PathEscape(name), nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) GetTask(ctx context.Context, id string) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodGet, taskPath(id), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) ListTasks(ctx context.Context, opts ListOptions) (*models.TaskList, error) {
+	query := url.Values{}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	if opts.AllNamespaces {
+		query.Set("all_namespaces", "true")
+	}
+	if opts.Selector != "" {
+		query.Set("selector", opts.Selector)
+	}
+
+	var list models.TaskList
+	if err := c.do(ctx, http.MethodGet, "/tasks", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListAllTasks обходит все страницы списка задач, начиная с opts.Page.
+// Итерация прекращается после первой ошибки.
+func (c *Client) ListAllTasks(ctx context.Context, opts ListOptions) iter.Seq2[models.Task, error] {
+	return func(yield func(models.Task, error) bool) {
+		if opts.Page < 1 {
+			opts.Page = 1
+		}
+		if opts.PageSize < 1 {
+			opts.PageSize = defaultPageSize
+		}
+
+		seen := (opts.Page - 1) * opts.PageSize
+		for {
+			list, err := c.ListTasks(ctx, opts)
+			if err != nil {
+				yield(models.Task{}, err)
+				return
+			}
+
+			for _, task := range list.Tasks {
+				if !yield(task, nil) {
+					return
+				}
+			}
+
+			seen += len(list.Tasks)
+			if len(list.Tasks) == 0 || seen >= list.Total {
+				return
+			}
+			opts.Page++
+		}
+	}
+}
+
+// LogOptions отбирает строки журнала задачи
+type LogOptions struct {
+	// Tail - сколько последних строк вернуть (0 - все)
+	Tail int
+	// Since отбрасывает строки, записанные раньше этого момента
+	Since time.Time
+}
+
+func (o LogOptions) query() url.Values {
+	query := url.Values{}
+	if o.Tail > 0 {
+		query.Set("tail", strconv.Itoa(o.Tail))
+	}
+	if !o.Since.IsZero() {
+		query.Set("since", o.Since.Format(time.RFC3339Nano))
+	}
+	return query
+}
+
+// GetTaskLogs возвращает журнал выполнения задачи
+func (c *Client) GetTaskLogs(ctx context.Context, id string, opts LogOptions) ([]models.TaskLogEntry, error) {
+	var list models.TaskLogList
+	if err := c.do(ctx, http.MethodGet, taskPath(id)+"/logs", opts.query(), nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Entries, nil
+}
+
+// FollowTaskLogs передает в fn строки журнала задачи по мере появления, пока
+// задача не завершится или не отменится ctx. Поток не ограничен таймаутом
+// клиента и не повторяется при обрыве.
+func (c *Client) FollowTaskLogs(ctx context.Context, id string, opts LogOptions, fn func(models.TaskLogEntry) error) error {
+	query := opts.query()
+	query.Set("follow", "true")
+	resp, err := c.stream(ctx, taskPath(id)+"/logs", query, "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var entry models.TaskLogEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to decode log stream: %w", err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// ListArtifacts возвращает артефакты задачи
+func (c *Client) ListArtifacts(ctx context.Context, id string) ([]models.Artifact, error) {
+	var list models.ArtifactList
+	if err := c.do(ctx, http.MethodGet, taskPath(id)+"/artifacts", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Artifacts, nil
+}
+
+// DownloadArtifact записывает содержимое артефакта в w и возвращает число
+// записанных байт. Загрузка не ограничена таймаутом клиента и не повторяется.
+func (c *Client) DownloadArtifact(ctx context.Context, id, name string, w io.Writer) (int64, error) {
+	resp, err := c.stream(ctx, taskPath(id)+"/artifacts/"+url.PathEscape(name), nil, "*/*")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
+
+// stream выполняет GET без таймаута клиента и повторов для длинных ответов;
+// тело успешного ответа должен закрыть вызывающий
+func (c *Client) stream(ctx context.Context, path string, query url.Values, accept string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.url(path, query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	stream := *c.httpClient
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+// UpdateTask заменяет изменяемые поля задачи: не заданные в update поля сбрасываются
+func (c *Client) UpdateTask(ctx context.Context, id string, update models.TaskUpdate) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodPut, taskPath(id), nil, update, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// PatchTask частично изменяет задачу по JSON Merge Patch (RFC 7396):
+// ключ со значением nil удаляет поле, вложенные объекты сливаются
+func (c *Client) PatchTask(ctx context.Context, id string, patch map[string]interface{}) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodPatch, taskPath(id), nil, patch, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) CancelTask(ctx context.Context, id string) (*models.Task, error) {
+	var task models.Task
+	if err := c.do(ctx, http.MethodPost, taskPath(id)+"/cancel", nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, taskPath(id), nil, nil, nil)
+}
+
+// CancelTasks отменяет все незавершенные задачи, подходящие под селектор меток
+func (c *Client) CancelTasks(ctx context.Context, selector string) (*models.BulkResult, error) {
+	var result models.BulkResult
+	if err := c.do(ctx, http.MethodPost, "/tasks/cancel", url.Values{"selector": {selector}}, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteTasks удаляет все задачи, подходящие под селектор меток
+func (c *Client) DeleteTasks(ctx context.Context, selector string) (*models.BulkResult, error) {
+	var result models.BulkResult
+	if err := c.do(ctx, http.MethodDelete, "/tasks", url.Values{"selector": {selector}}, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// WaitForCompletion опрашивает задачу, пока она не перейдет в конечный статус
+// (completed, failed или cancelled), и возвращает ее последнее состояние.
+func (c *Client) WaitForCompletion(ctx context.Context, id string) (*models.Task, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		task, err := c.GetTask(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if task.Status.IsTerminal() {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func taskPath(id string) string {
+	return "/tasks/" + url.PathEscape(id)
+}
+
+// url добавляет к адресу сервера путь, сегменты которого уже экранированы
+// (taskPath, url.PathEscape), не экранируя их повторно
+func (c *Client) url(path string, query url.Values) string {
+	u := *c.baseURL
+	u.RawPath = u.EscapedPath() + path
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	rawURL := c.url(path, query)
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, rawURL, body)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil || resp.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+			return nil
+		}
+
+		var wait time.Duration
+		if err == nil {
+			err = readAPIError(resp)
+			wait = retryAfter(resp)
+		}
+
+		if attempt >= c.maxRetries || !shouldRetry(ctx, method, resp) {
+			return err
+		}
+
+		if wait == 0 {
+			wait = c.retryBackoff << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, rawURL string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Namespace", c.namespace)
+	}
+	if body != nil && method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	} else if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// shouldRetry повторяет идемпотентные запросы при сетевых ошибках и сбоях сервера,
+// а POST - только когда сервер явно отказался принимать запрос.
+func shouldRetry(ctx context.Context, method string, resp *http.Response) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	idempotent := method != http.MethodPost
+	if resp == nil {
+		return idempotent
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+		return idempotent
+	}
+	return false
+}
+
+func readAPIError(resp *http.Response) error {
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	// Ошибки проверки запроса приходят в JSON с общим сообщением и списком полей
+	var structured struct {
+		Message string `json:"message"`
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") &&
+		json.Unmarshal(message, &structured) == nil && structured.Message != "" {
+		message = []byte(structured.Message)
+	}
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+	}
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
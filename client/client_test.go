@@ -0,0 +1,339 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"http_api/internal/artifacts"
+	"http_api/internal/services"
+	"http_api/internal/testsupport"
+	"http_api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := testsupport.NewServer(t)
+
+	c, err := New(server.URL, WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	t.Run("Task lifecycle", func(t *testing.T) {
+		created, err := c.CreateTask(ctx, CreateTaskRequest{Description: "sdk task"})
+		require.NoError(t, err)
+		assert.Equal(t, "sdk task", created.Description)
+		assert.Equal(t, models.StatusPending, created.Status)
+
+		task, err := c.GetTask(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, task.ID)
+
+		description := "updated by sdk"
+		task, err = c.UpdateTask(ctx, created.ID, models.TaskUpdate{
+			Description: &description,
+			Labels:      map[string]string{"env": "dev"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, description, task.Description)
+
+		task, err = c.PatchTask(ctx, created.ID, map[string]interface{}{"labels": map[string]string{"env": "prod"}})
+		require.NoError(t, err)
+		assert.Equal(t, description, task.Description)
+		assert.Equal(t, map[string]string{"env": "prod"}, task.Labels)
+
+		_, err = c.PatchTask(ctx, created.ID, map[string]interface{}{"status": "completed"})
+		assert.True(t, errors.Is(err, ErrBadRequest))
+
+		task, err = c.CancelTask(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, task.Status)
+
+		task, err = c.WaitForCompletion(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, task.Status)
+
+		_, err = c.CancelTask(ctx, created.ID)
+		assert.True(t, errors.Is(err, ErrBadRequest))
+
+		require.NoError(t, c.DeleteTask(ctx, created.ID))
+
+		_, err = c.GetTask(ctx, created.ID)
+		assert.True(t, errors.Is(err, ErrNotFound))
+
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	})
+
+	t.Run("Iterate over all pages", func(t *testing.T) {
+		ids := make(map[string]bool)
+		for i := 0; i < 25; i++ {
+			task, err := c.CreateTask(ctx, CreateTaskRequest{Description: "page task"})
+			require.NoError(t, err)
+			ids[task.ID] = true
+		}
+
+		list, err := c.ListTasks(ctx, ListOptions{Page: 1, PageSize: 10})
+		require.NoError(t, err)
+		assert.Len(t, list.Tasks, 10)
+		assert.Equal(t, 25, list.Total)
+
+		seen := 0
+		for task, err := range c.ListAllTasks(ctx, ListOptions{PageSize: 10}) {
+			require.NoError(t, err)
+			assert.True(t, ids[task.ID])
+			delete(ids, task.ID)
+			seen++
+		}
+		assert.Equal(t, 25, seen)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("Wait respects context", func(t *testing.T) {
+		task, err := c.CreateTask(ctx, CreateTaskRequest{Description: "long task"})
+		require.NoError(t, err)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err = c.WaitForCompletion(waitCtx, task.ID)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func TestClientNamespaces(t *testing.T) {
+	ctx := context.Background()
+	server := testsupport.NewServer(t)
+
+	teamA, err := New(server.URL, WithNamespace("team-a"))
+	require.NoError(t, err)
+	teamB, err := New(server.URL, WithNamespace("team-b"))
+	require.NoError(t, err)
+
+	task, err := teamA.CreateTask(ctx, CreateTaskRequest{Description: "team a"})
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", task.Namespace)
+
+	_, err = teamB.GetTask(ctx, task.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	list, err := teamB.ListTasks(ctx, ListOptions{AllNamespaces: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+}
+
+func TestClientSelectors(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(testsupport.NewServer(t).URL, WithNamespace("selectors"))
+	require.NoError(t, err)
+
+	for _, env := range []string{"prod", "prod", "dev"} {
+		_, err := c.CreateTask(ctx, CreateTaskRequest{Description: env, Labels: map[string]string{"env": env}})
+		require.NoError(t, err)
+	}
+
+	list, err := c.ListTasks(ctx, ListOptions{Selector: "env=prod"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+
+	result, err := c.DeleteTasks(ctx, "env in (prod)")
+	require.NoError(t, err)
+	assert.Len(t, result.TaskIDs, 2)
+
+	_, err = c.CancelTasks(ctx, "env in (prod")
+	assert.True(t, errors.Is(err, ErrBadRequest))
+}
+
+func TestClientTaskTypes(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(testsupport.NewServer(t).URL)
+	require.NoError(t, err)
+
+	list, err := c.ListTaskTypes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, list.Total)
+	assert.Equal(t, models.DefaultTaskType, list.TaskTypes[0].Name)
+
+	info, err := c.GetTaskType(ctx, models.DefaultTaskType)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "string"}`, string(info.OutputSchema))
+
+	stats, err := c.GetTaskTypeStats(ctx, models.DefaultTaskType)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultTaskType, stats.Type)
+
+	_, err = c.GetTaskTypeStats(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestClientTaskLogs(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(testsupport.NewServer(t).URL, WithTimeout(time.Second))
+	require.NoError(t, err)
+
+	task, err := c.CreateTask(ctx, CreateTaskRequest{Description: "logs"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		entries, err := c.GetTaskLogs(ctx, task.ID, LogOptions{Tail: 1})
+		return err == nil && len(entries) == 1 && entries[0].Line == "attempt 1 started"
+	}, time.Second, 10*time.Millisecond)
+
+	// Поток не прерывается таймаутом клиента и заканчивается вместе с задачей
+	time.AfterFunc(1500*time.Millisecond, func() { c.CancelTask(ctx, task.ID) })
+	var streamed []models.TaskLogEntry
+	err = c.FollowTaskLogs(ctx, task.ID, LogOptions{}, func(entry models.TaskLogEntry) error {
+		streamed = append(streamed, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, streamed, 1)
+	assert.Equal(t, int64(1), streamed[0].Seq)
+
+	err = c.FollowTaskLogs(ctx, "missing", LogOptions{}, func(models.TaskLogEntry) error { return nil })
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestClientArtifacts(t *testing.T) {
+	ctx := context.Background()
+	store, err := artifacts.NewFSStore(t.TempDir())
+	require.NoError(t, err)
+	c, err := New(testsupport.NewServer(t, services.WithWorkers(0), services.WithArtifacts(store)).URL)
+	require.NoError(t, err)
+
+	task, err := c.CreateTask(ctx, CreateTaskRequest{Description: "export"})
+	require.NoError(t, err)
+	_, err = store.Put(ctx, task.ID, "report.txt", "text/plain", strings.NewReader("totals"))
+	require.NoError(t, err)
+
+	list, err := c.ListArtifacts(ctx, task.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "report.txt", list[0].Name)
+
+	var buf bytes.Buffer
+	n, err := c.DownloadArtifact(ctx, task.ID, "report.txt", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+	assert.Equal(t, "totals", buf.String())
+
+	_, err = c.DownloadArtifact(ctx, task.ID, "missing.txt", &buf)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestClientEscapesPathOnce(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"a/b","status":"pending"}`))
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL + "/api%20v1")
+	require.NoError(t, err)
+	_, err = c.GetTask(context.Background(), "a/b")
+	require.NoError(t, err)
+	_, err = c.DownloadArtifact(context.Background(), "a b", "report.csv", io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/api%20v1/tasks/a%2Fb", "/api%20v1/tasks/a%20b/artifacts/report.csv"}, paths)
+}
+
+func TestClientTimeoutOption(t *testing.T) {
+	shared := &http.Client{Timeout: time.Minute}
+
+	for name, opts := range map[string][]Option{
+		"Timeout after client":  {WithHTTPClient(shared), WithTimeout(time.Second)},
+		"Timeout before client": {WithTimeout(time.Second), WithHTTPClient(shared)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := New("http://localhost", opts...)
+			require.NoError(t, err)
+			assert.Equal(t, time.Second, c.httpClient.Timeout)
+			assert.NotSame(t, shared, c.httpClient)
+			assert.Equal(t, time.Minute, shared.Timeout, "caller's client must not change")
+		})
+	}
+
+	c, err := New("http://localhost", WithHTTPClient(shared))
+	require.NoError(t, err)
+	assert.Same(t, shared, c.httpClient)
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Retries server errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"abc","status":"processing"}`))
+		}))
+		defer server.Close()
+
+		c, err := New(server.URL, WithRetries(3, time.Millisecond))
+		require.NoError(t, err)
+
+		task, err := c.GetTask(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusProcessing, task.Status)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Gives up after max retries", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		c, err := New(server.URL, WithRetries(2, time.Millisecond))
+		require.NoError(t, err)
+
+		err = c.DeleteTask(ctx, "abc")
+		assert.True(t, errors.Is(err, ErrServer))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Does not retry non-idempotent requests on server errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		c, err := New(server.URL, WithRetries(3, time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = c.CreateTask(ctx, CreateTaskRequest{Description: "x"})
+		assert.True(t, errors.Is(err, ErrServer))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		c, err := New(server.URL, WithTimeout(20*time.Millisecond), WithRetries(0, 0))
+		require.NoError(t, err)
+
+		_, err = c.GetTask(ctx, "abc")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("task not found")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrUnavailable        = errors.New("service unavailable")
+	ErrServer             = errors.New("server error")
+)
+
+// APIError описывает неуспешный ответ сервера
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("task api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("task api: %d %s", e.StatusCode, e.Message)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, client.ErrNotFound)
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case e.StatusCode == http.StatusServiceUnavailable:
+		return ErrUnavailable
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"http_api/client"
+	"http_api/models"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// session - разобранные флаги команды и готовый клиент
+type session struct {
+	client   *client.Client
+	settings settings
+	out      *printer
+	args     []string
+}
+
+// setup разбирает флаги команды и создает клиент.
+// При ошибке возвращает nil и код завершения.
+func setup(set *flag.FlagSet, common *commonFlags, args []string, nargs int, stdout, stderr io.Writer) (*session, int) {
+	set.SetOutput(stderr)
+	common.register(set)
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, exitOK
+		}
+		return nil, exitUsage
+	}
+	if set.NArg() != nargs {
+		fmt.Fprintf(stderr, "taskctl %s: expected %d argument(s), got %d\n", set.Name(), nargs, set.NArg())
+		set.Usage()
+		return nil, exitUsage
+	}
+
+	s, err := common.resolve()
+	if err != nil {
+		fmt.Fprintf(stderr, "taskctl: %v\n", err)
+		return nil, exitUsage
+	}
+
+	sess := &session{
+		settings: s,
+		out:      &printer{w: stdout, format: s.Output},
+		args:     set.Args(),
+	}
+	if sess.client, err = sess.newClient(); err != nil {
+		fmt.Fprintf(stderr, "taskctl: %v\n", err)
+		return nil, exitUsage
+	}
+	return sess, -1
+}
+
+func (s *session) newClient(opts ...client.Option) (*client.Client, error) {
+	opts = append([]client.Option{
+		client.WithToken(s.settings.Token),
+		client.WithNamespace(s.settings.Namespace),
+		client.WithTimeout(s.settings.Timeout),
+	}, opts...)
+	return client.New(s.settings.Server, opts...)
+}
+
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "taskctl: %v\n", err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return exitTimeout
+	}
+	return exitError
+}
+
+// outcomeCode переводит конечный статус задачи в код завершения
+func outcomeCode(task *models.Task) int {
+	switch task.Status {
+	case models.StatusFailed:
+		return exitTaskFailed
+	case models.StatusCancelled:
+		return exitTaskCancelled
+	}
+	return exitOK
+}
+
+func runCreate(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	description := set.String("d", "", "task description")
+	taskType := set.String("t", "", "task type (default type if empty)")
+	input := set.String("input", "", "task input as JSON")
+	ttl := set.Duration("ttl", 0, "how long to keep the task after it finishes (0 - server retention policy)")
+	wait := set.Bool("wait", false, "wait for the task to finish and exit with its outcome")
+	timeout := set.Duration("timeout", 0, "maximum time to wait with --wait")
+	interval := set.Duration("interval", 2*time.Second, "poll interval with --wait")
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 0, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	if *ttl != 0 && *ttl < time.Second {
+		fmt.Fprintln(stderr, "taskctl create: -ttl must be 0 or at least 1s")
+		return exitUsage
+	}
+	// Сервер принимает целые секунды: дробную часть округляем вверх
+	ttlSeconds := int(*ttl / time.Second)
+	if *ttl%time.Second != 0 {
+		ttlSeconds++
+	}
+
+	req := client.CreateTaskRequest{Type: *taskType, Description: *description, TTLSeconds: ttlSeconds}
+	if *input != "" {
+		if !json.Valid([]byte(*input)) {
+			fmt.Fprintln(stderr, "taskctl create: -input is not valid JSON")
+			return exitUsage
+		}
+		req.Input = json.RawMessage(*input)
+	}
+	task, err := s.client.CreateTask(ctx, req)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if !*wait {
+		if err := s.out.task(task); err != nil {
+			return fail(stderr, err)
+		}
+		return exitOK
+	}
+
+	return waitAndPrint(ctx, s, task.ID, *timeout, *interval, stderr)
+}
+
+func runGet(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("get", flag.ContinueOnError)
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	task, err := s.client.GetTask(ctx, s.args[0])
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := s.out.task(task); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runList(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("list", flag.ContinueOnError)
+	status := set.String("status", "", "show only tasks with this status")
+	watch := set.Bool("watch", false, "refresh the list until interrupted")
+	interval := set.Duration("interval", 2*time.Second, "refresh interval with --watch")
+	pageSize := set.Int("page-size", 100, "number of tasks fetched per request")
+	allNamespaces := set.Bool("A", false, "list tasks of all namespaces (requires admin)")
+	selector := set.String("l", "", "label selector, e.g. env=prod,team in (a,b),!draft")
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 0, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	for {
+		var tasks []models.Task
+		for task, err := range s.client.ListAllTasks(ctx, client.ListOptions{
+			PageSize:      *pageSize,
+			AllNamespaces: *allNamespaces,
+			Selector:      *selector,
+		}) {
+			if err != nil {
+				if *watch && ctx.Err() != nil {
+					return exitOK
+				}
+				return fail(stderr, err)
+			}
+			if *status == "" || string(task.Status) == *status {
+				tasks = append(tasks, task)
+			}
+		}
+
+		if *watch {
+			s.out.clear()
+		}
+		if err := s.out.tasks(tasks); err != nil {
+			return fail(stderr, err)
+		}
+		if !*watch {
+			return exitOK
+		}
+
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-time.After(*interval):
+		}
+	}
+}
+
+func runCancel(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("cancel", flag.ContinueOnError)
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	task, err := s.client.CancelTask(ctx, s.args[0])
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := s.out.task(task); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runDelete(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("delete", flag.ContinueOnError)
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	if err := s.client.DeleteTask(ctx, s.args[0]); err != nil {
+		return fail(stderr, err)
+	}
+	if s.settings.Output == "table" {
+		fmt.Fprintf(stdout, "task %s deleted\n", s.args[0])
+	}
+	return exitOK
+}
+
+func runLogs(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("logs", flag.ContinueOnError)
+	tail := set.Int("tail", 0, "show only the last N lines (0 - all)")
+	since := set.String("since", "", "show lines newer than a duration (10m) or an RFC 3339 time")
+	follow := set.Bool("f", false, "stream new lines until the task finishes")
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	opts := client.LogOptions{Tail: *tail}
+	if *since != "" {
+		if d, err := time.ParseDuration(*since); err == nil {
+			opts.Since = time.Now().Add(-d)
+		} else if opts.Since, err = time.Parse(time.RFC3339, *since); err != nil {
+			fmt.Fprintf(stderr, "taskctl logs: invalid -since %q\n", *since)
+			return exitUsage
+		}
+	}
+
+	if !*follow {
+		entries, err := s.client.GetTaskLogs(ctx, s.args[0], opts)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		for _, entry := range entries {
+			if err := s.out.logEntry(entry); err != nil {
+				return fail(stderr, err)
+			}
+		}
+		return exitOK
+	}
+
+	err := s.client.FollowTaskLogs(ctx, s.args[0], opts, s.out.logEntry)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runArtifacts(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("artifacts", flag.ContinueOnError)
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	list, err := s.client.ListArtifacts(ctx, s.args[0])
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := s.out.artifacts(list); err != nil {
+		return fail(stderr, err)
+	}
+	return exitOK
+}
+
+func runDownload(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("download", flag.ContinueOnError)
+	output := set.String("O", "", `output file ("-" - stdout, default - the artifact name)`)
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 2, stdout, stderr)
+	if s == nil {
+		return code
+	}
+	id, name := s.args[0], s.args[1]
+
+	if *output == "-" {
+		if _, err := s.client.DownloadArtifact(ctx, id, name, stdout); err != nil {
+			return fail(stderr, err)
+		}
+		return exitOK
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Base(name)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	n, err := s.client.DownloadArtifact(ctx, id, name, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Не оставляем недокачанный файл
+		os.Remove(path)
+		return fail(stderr, err)
+	}
+	if s.settings.Output == "table" {
+		fmt.Fprintf(stdout, "saved %s (%d bytes)\n", path, n)
+	}
+	return exitOK
+}
+
+func runWait(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	set := flag.NewFlagSet("wait", flag.ContinueOnError)
+	timeout := set.Duration("timeout", 0, "maximum time to wait (0 - no limit)")
+	interval := set.Duration("interval", 2*time.Second, "poll interval")
+
+	var common commonFlags
+	s, code := setup(set, &common, args, 1, stdout, stderr)
+	if s == nil {
+		return code
+	}
+
+	return waitAndPrint(ctx, s, s.args[0], *timeout, *interval, stderr)
+}
+
+func waitAndPrint(ctx context.Context, s *session, id string, timeout, interval time.Duration, stderr io.Writer) int {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	c, err := s.newClient(client.WithPollInterval(interval))
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	task, err := c.WaitForCompletion(ctx, id)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	if err := s.out.task(task); err != nil {
+		return fail(stderr, err)
+	}
+	return outcomeCode(task)
+}
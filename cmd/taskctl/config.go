@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// settings - итоговые параметры подключения.
+// Приоритет: флаги, затем переменные окружения, затем файл конфигурации.
+type settings struct {
+	Server    string        `yaml:"server"`
+	Token     string        `yaml:"token"`
+	Namespace string        `yaml:"namespace"`
+	Output    string        `yaml:"output"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// commonFlags регистрирует флаги, общие для всех команд
+type commonFlags struct {
+	config    string
+	server    string
+	token     string
+	namespace string
+	output    string
+	timeout   time.Duration
+}
+
+func (f *commonFlags) register(set *flag.FlagSet) {
+	set.StringVar(&f.config, "config", "", "path to config file (env TASKCTL_CONFIG)")
+	set.StringVar(&f.server, "server", "", "task service address (env TASKCTL_SERVER)")
+	set.StringVar(&f.token, "token", "", "API token (env TASKCTL_TOKEN)")
+	set.StringVar(&f.namespace, "n", "", "task namespace (env TASKCTL_NAMESPACE)")
+	set.StringVar(&f.output, "o", "", "output format: table, json or yaml")
+	set.DurationVar(&f.timeout, "request-timeout", 0, "timeout of a single HTTP request")
+}
+
+func (f *commonFlags) resolve() (settings, error) {
+	s := settings{
+		Server:  defaultServer,
+		Output:  "table",
+		Timeout: 30 * time.Second,
+	}
+
+	path := firstNonEmpty(f.config, os.Getenv("TASKCTL_CONFIG"))
+	explicit := path != ""
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "taskctl", "config.yaml")
+		}
+	}
+	if path != "" {
+		if err := loadSettingsFile(path, &s); err != nil {
+			if explicit || !errors.Is(err, fs.ErrNotExist) {
+				return s, err
+			}
+		}
+	}
+
+	s.Server = firstNonEmpty(f.server, os.Getenv("TASKCTL_SERVER"), s.Server)
+	s.Token = firstNonEmpty(f.token, os.Getenv("TASKCTL_TOKEN"), s.Token)
+	s.Namespace = firstNonEmpty(f.namespace, os.Getenv("TASKCTL_NAMESPACE"), s.Namespace)
+	s.Output = firstNonEmpty(f.output, os.Getenv("TASKCTL_OUTPUT"), s.Output)
+	if f.timeout > 0 {
+		s.Timeout = f.timeout
+	}
+
+	switch s.Output {
+	case "table", "json", "yaml":
+	default:
+		return s, fmt.Errorf("unknown output format %q", s.Output)
+	}
+	return s, nil
+}
+
+func loadSettingsFile(path string, s *settings) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+// Команда taskctl - консольный клиент HTTP API задач.
+//
+//	taskctl create -d "описание"
+//	taskctl create -t resize -input '{"width": 640}' -d "превью"
+//	taskctl get <id>
+//	taskctl list --status processing --watch
+//	taskctl cancel <id>
+//	taskctl delete <id>
+//	taskctl wait <id>
+//	taskctl download -O report.csv <id> report.csv
+//
+// Адрес сервера и токен берутся из флагов --server/--token, переменных
+// окружения TASKCTL_SERVER/TASKCTL_TOKEN или конфигурационного файла
+// (--config, TASKCTL_CONFIG, по умолчанию ~/.config/taskctl/config.yaml).
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Коды завершения, по которым скрипты и CI различают исход задачи
+const (
+	exitOK            = 0
+	exitError         = 1
+	exitUsage         = 2
+	exitTaskFailed    = 3
+	exitTaskCancelled = 4
+	exitTimeout       = 5
+)
+
+const usage = `Usage: taskctl <command> [flags] [args]
+
+Commands:
+  create    create a task
+  get       show a task
+  list      list tasks
+  cancel    cancel a task
+  delete    delete a task
+  wait      wait until a task finishes
+  logs      show task logs
+  artifacts list task artifacts
+  download  download a task artifact
+
+Run "taskctl <command> -h" for command flags.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	commands := map[string]func(context.Context, []string, io.Writer, io.Writer) int{
+		"create":    runCreate,
+		"get":       runGet,
+		"list":      runList,
+		"cancel":    runCancel,
+		"delete":    runDelete,
+		"wait":      runWait,
+		"logs":      runLogs,
+		"artifacts": runArtifacts,
+		"download":  runDownload,
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "taskctl: unknown command %q\n\n%s", name, usage)
+		return exitUsage
+	}
+	return cmd(ctx, args[1:], stdout, stderr)
+}
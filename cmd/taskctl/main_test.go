@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"http_api/internal/artifacts"
+	"http_api/internal/services"
+	"http_api/internal/testsupport"
+	"http_api/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestTaskctl(t *testing.T) {
+	server := testsupport.NewServer(t)
+	t.Setenv("TASKCTL_CONFIG", "")
+	t.Setenv("TASKCTL_SERVER", server.URL)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var taskID string
+
+	t.Run("create", func(t *testing.T) {
+		code, stdout, stderr := runCLI("create", "-d", "cli task", "-o", "json")
+		require.Equal(t, exitOK, code, stderr)
+
+		var task models.Task
+		require.NoError(t, json.Unmarshal([]byte(stdout), &task))
+		assert.Equal(t, "cli task", task.Description)
+		taskID = task.ID
+	})
+
+	t.Run("create rounds ttl up to whole seconds", func(t *testing.T) {
+		code, stdout, stderr := runCLI("create", "-d", "short lived", "-ttl", "1500ms", "-o", "json")
+		require.Equal(t, exitOK, code, stderr)
+
+		var task models.Task
+		require.NoError(t, json.Unmarshal([]byte(stdout), &task))
+		assert.Equal(t, 2, task.TTLSeconds)
+	})
+
+	t.Run("create with invalid input", func(t *testing.T) {
+		code, _, stderr := runCLI("create", "-d", "bad", "-input", "{")
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "-input")
+
+		code, _, stderr = runCLI("create", "-d", "bad", "-ttl", "500ms")
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "-ttl")
+
+		code, _, stderr = runCLI("create", "-t", "missing", "-d", "bad")
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, "unknown task type")
+	})
+
+	t.Run("get as table", func(t *testing.T) {
+		code, stdout, _ := runCLI("get", taskID)
+		assert.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, taskID)
+		assert.Contains(t, stdout, "cli task")
+	})
+
+	t.Run("get as yaml", func(t *testing.T) {
+		code, stdout, _ := runCLI("get", "-o", "yaml", taskID)
+		assert.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "id: "+taskID)
+		assert.Contains(t, stdout, "description: cli task")
+	})
+
+	t.Run("list filtered by status", func(t *testing.T) {
+		code, stdout, _ := runCLI("list", "--status", "cancelled", "-o", "json")
+		assert.Equal(t, exitOK, code)
+		assert.JSONEq(t, "[]", stdout)
+
+		code, stdout, _ = runCLI("list")
+		assert.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "ID"))
+		assert.Contains(t, stdout, taskID)
+	})
+
+	t.Run("cancel and wait", func(t *testing.T) {
+		code, _, _ := runCLI("cancel", taskID)
+		assert.Equal(t, exitOK, code)
+
+		code, stdout, _ := runCLI("wait", "--interval", "10ms", "-o", "json", taskID)
+		assert.Equal(t, exitTaskCancelled, code)
+		assert.Contains(t, stdout, string(models.StatusCancelled))
+	})
+
+	t.Run("logs", func(t *testing.T) {
+		code, stdout, stderr := runCLI("logs", "-tail", "1", taskID)
+		require.Equal(t, exitOK, code, stderr)
+		assert.Contains(t, stdout, "system attempt 1 started")
+
+		// Задача уже отменена, поэтому поток сразу завершается
+		code, stdout, stderr = runCLI("logs", "-f", "-o", "json", taskID)
+		require.Equal(t, exitOK, code, stderr)
+		var entry models.TaskLogEntry
+		require.NoError(t, json.Unmarshal([]byte(stdout), &entry))
+		assert.Equal(t, int64(1), entry.Seq)
+
+		code, _, stderr = runCLI("logs", "-since", "yesterday", taskID)
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "invalid -since")
+	})
+
+	t.Run("wait timeout", func(t *testing.T) {
+		code, stdout, _ := runCLI("create", "-d", "long", "-o", "json")
+		require.Equal(t, exitOK, code)
+
+		var task models.Task
+		require.NoError(t, json.Unmarshal([]byte(stdout), &task))
+
+		code, _, _ = runCLI("wait", "--timeout", "50ms", "--interval", "10ms", task.ID)
+		assert.Equal(t, exitTimeout, code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		code, _, _ := runCLI("delete", taskID)
+		assert.Equal(t, exitOK, code)
+
+		code, _, stderr := runCLI("get", taskID)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, "not found")
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		code, _, _ := runCLI()
+		assert.Equal(t, exitUsage, code)
+
+		code, _, _ = runCLI("frobnicate")
+		assert.Equal(t, exitUsage, code)
+
+		code, _, _ = runCLI("get")
+		assert.Equal(t, exitUsage, code)
+
+		code, _, _ = runCLI("list", "-o", "xml")
+		assert.Equal(t, exitUsage, code)
+	})
+}
+
+func TestTaskctlArtifacts(t *testing.T) {
+	store, err := artifacts.NewFSStore(t.TempDir())
+	require.NoError(t, err)
+	server := testsupport.NewServer(t, services.WithWorkers(0), services.WithArtifacts(store))
+	t.Setenv("TASKCTL_CONFIG", "")
+	t.Setenv("TASKCTL_SERVER", server.URL)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	code, stdout, stderr := runCLI("create", "-d", "export", "-o", "json")
+	require.Equal(t, exitOK, code, stderr)
+	var task models.Task
+	require.NoError(t, json.Unmarshal([]byte(stdout), &task))
+	_, err = store.Put(context.Background(), task.ID, "report.csv", "text/csv", strings.NewReader("a,b\n"))
+	require.NoError(t, err)
+
+	code, stdout, stderr = runCLI("artifacts", task.ID)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "report.csv")
+	assert.Contains(t, stdout, "text/csv")
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	code, stdout, stderr = runCLI("download", "-O", path, task.ID, "report.csv")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "4 bytes")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n", string(data))
+
+	code, stdout, _ = runCLI("download", "-O", "-", task.ID, "report.csv")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "a,b\n", stdout)
+
+	missing := filepath.Join(t.TempDir(), "missing.csv")
+	code, _, stderr = runCLI("download", "-O", missing, task.ID, "missing.csv")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "not found")
+	assert.NoFileExists(t, missing)
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server: http://file:1\ntoken: file-token\noutput: yaml\n"), 0o600))
+
+	t.Setenv("TASKCTL_CONFIG", path)
+	t.Setenv("TASKCTL_SERVER", "http://env:2")
+	t.Setenv("TASKCTL_TOKEN", "")
+	t.Setenv("TASKCTL_OUTPUT", "")
+
+	s, err := (&commonFlags{}).resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "http://env:2", s.Server)
+	assert.Equal(t, "file-token", s.Token)
+	assert.Equal(t, "yaml", s.Output)
+
+	s, err = (&commonFlags{server: "http://flag:3", output: "json"}).resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "http://flag:3", s.Server)
+	assert.Equal(t, "json", s.Output)
+
+	_, err = (&commonFlags{config: filepath.Join(t.TempDir(), "missing.yaml")}).resolve()
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"http_api/models"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p *printer) task(task *models.Task) error {
+	switch p.format {
+	case "json":
+		return p.json(task)
+	case "yaml":
+		return p.yaml(task)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", task.ID)
+	fmt.Fprintf(tw, "Status:\t%s\n", task.Status)
+	fmt.Fprintf(tw, "Description:\t%s\n", task.Description)
+	fmt.Fprintf(tw, "Created:\t%s\n", formatTime(&task.CreatedAt))
+	fmt.Fprintf(tw, "Started:\t%s\n", formatTime(task.StartedAt))
+	fmt.Fprintf(tw, "Completed:\t%s\n", formatTime(task.CompletedAt))
+	if task.CancelledAt != nil {
+		fmt.Fprintf(tw, "Cancelled:\t%s\n", formatTime(task.CancelledAt))
+	}
+	fmt.Fprintf(tw, "Duration:\t%s\n", formatDuration(task.Duration))
+	if len(task.Result) > 0 {
+		fmt.Fprintf(tw, "Result:\t%s\n", task.Result)
+	}
+	if task.ErrorInfo != nil {
+		fmt.Fprintf(tw, "Error:\t%s: %s\n", task.ErrorInfo.Code, task.ErrorInfo.Message)
+	} else if task.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", task.Error)
+	}
+	return tw.Flush()
+}
+
+func (p *printer) tasks(tasks []models.Task) error {
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+
+	switch p.format {
+	case "json":
+		return p.json(tasks)
+	case "yaml":
+		return p.yaml(tasks)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tCREATED\tDURATION\tDESCRIPTION")
+	for _, task := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			task.ID, task.Status, formatTime(&task.CreatedAt), formatDuration(task.Duration), task.Description)
+	}
+	return tw.Flush()
+}
+
+// logEntry выводит строку журнала задачи; в форматах json и yaml - по документу на строку
+func (p *printer) logEntry(entry models.TaskLogEntry) error {
+	switch p.format {
+	case "json":
+		return json.NewEncoder(p.w).Encode(entry)
+	case "yaml":
+		return p.yaml([]models.TaskLogEntry{entry})
+	}
+	_, err := fmt.Fprintf(p.w, "%s %-6s %s\n", entry.Time.Local().Format(time.RFC3339), entry.Stream, entry.Line)
+	return err
+}
+
+func (p *printer) artifacts(list []models.Artifact) error {
+	if list == nil {
+		list = []models.Artifact{}
+	}
+
+	switch p.format {
+	case "json":
+		return p.json(list)
+	case "yaml":
+		return p.yaml(list)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSIZE\tTYPE\tCREATED\tCHECKSUM")
+	for _, a := range list {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", a.Name, a.Size, a.ContentType, formatTime(&a.CreatedAt), a.Checksum)
+	}
+	return tw.Flush()
+}
+
+// clear очищает экран перед очередным обновлением в режиме --watch
+func (p *printer) clear() {
+	if f, ok := p.w.(*os.File); ok && p.format == "table" {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(p.w, "\033[H\033[2J")
+		}
+	}
+}
+
+func (p *printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// yaml выводит значение с теми же именами полей, что и в JSON API:
+// JSON разбирается в yaml.Node, что сохраняет порядок полей.
+func (p *printer) yaml(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func formatDuration(seconds float64) string {
+	if seconds == 0 {
+		return "-"
+	}
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"http_api/internal/handlers"
-	"http_api/internal/models"
 	"http_api/internal/services"
 	"http_api/internal/storage"
+	"http_api/models"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,7 +18,7 @@ import (
 func TestTaskAPI(t *testing.T) {
 	// Setup
 	storage := storage.NewInMemoryTaskStorage()
-	service := services.NewTaskService(storage)
+	service := services.NewTaskService(storage, services.WithProcessingTime(10*time.Millisecond, 20*time.Millisecond))
 	handler := handlers.NewTaskHandler(service)
 
 	t.Run("Full task lifecycle", func(t *testing.T) {
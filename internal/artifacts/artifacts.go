@@ -0,0 +1,70 @@
+// Package artifacts хранит файлы, которые исполнители создают при выполнении
+// задач (отчеты, выгрузки), отдельно от записи задачи.
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"http_api/models"
+	"io"
+	"regexp"
+)
+
+var (
+	ErrNotFound = errors.New("artifact not found")
+	// ErrTooLarge - артефакт превысил допустимый размер
+	ErrTooLarge = errors.New("artifact too large")
+	// ErrUnavailable - у задачи нет хранилища артефактов
+	ErrUnavailable = errors.New("artifact store is not configured")
+)
+
+// File - содержимое артефакта с произвольным доступом для ответов на Range-запросы
+type File interface {
+	io.ReadSeekCloser
+}
+
+// Store хранит артефакты задач
+type Store interface {
+	// Put сохраняет артефакт, заменяя артефакт задачи с тем же именем.
+	// Пустой contentType определяется по имени и содержимому.
+	Put(ctx context.Context, taskID, name, contentType string, r io.Reader) (*models.Artifact, error)
+	// List возвращает артефакты задачи, упорядоченные по имени
+	List(taskID string) ([]models.Artifact, error)
+	// Open открывает артефакт для чтения
+	Open(taskID, name string) (File, *models.Artifact, error)
+	// Delete удаляет все артефакты задачи
+	Delete(taskID string) error
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,254}$`)
+
+// ValidateName проверяет имя артефакта: латинские буквы, цифры, '.', '_' и '-',
+// не больше 255 символов и без точки в начале
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid artifact name %q", name)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+type sink struct {
+	store  Store
+	taskID string
+}
+
+// WithTask привязывает к контексту хранилище артефактов задачи taskID
+func WithTask(ctx context.Context, store Store, taskID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, sink{store: store, taskID: taskID})
+}
+
+// Put сохраняет артефакт задачи, выполняемой в ctx
+func Put(ctx context.Context, name, contentType string, r io.Reader) (*models.Artifact, error) {
+	s, ok := ctx.Value(contextKey{}).(sink)
+	if !ok {
+		return nil, ErrUnavailable
+	}
+	return s.store.Put(ctx, s.taskID, name, contentType, r)
+}
@@ -0,0 +1,222 @@
+package artifacts
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"http_api/models"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FSStore хранит артефакты в локальном каталоге: <dir>/<task>/files/<name>
+// и описание рядом, в <dir>/<task>/meta/<name>.json
+type FSStore struct {
+	dir      string
+	maxBytes int64
+	now      func() time.Time
+}
+
+type FSOption func(*FSStore)
+
+// WithMaxBytes ограничивает размер одного артефакта (0 - без ограничения)
+func WithMaxBytes(n int64) FSOption {
+	return func(s *FSStore) {
+		s.maxBytes = n
+	}
+}
+
+// NewFSStore создает хранилище в каталоге dir
+func NewFSStore(dir string, opts ...FSOption) (*FSStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FSStore{dir: dir, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *FSStore) taskDir(taskID string) string {
+	return filepath.Join(s.dir, url.PathEscape(taskID))
+}
+
+func (s *FSStore) Put(ctx context.Context, taskID, name, contentType string, r io.Reader) (*models.Artifact, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	files := filepath.Join(s.taskDir(taskID), "files")
+	meta := filepath.Join(s.taskDir(taskID), "meta")
+	for _, dir := range []string{files, meta} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы читатели не видели неполный артефакт
+	tmp, err := os.CreateTemp(files, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := sha256.New()
+	head := bufio.NewReader(r)
+	sniff, _ := head.Peek(512)
+	if contentType == "" {
+		contentType = detectContentType(name, sniff)
+	}
+	size, err := s.copy(ctx, io.MultiWriter(tmp, sum), head)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	artifact := &models.Artifact{
+		Name:        name,
+		Size:        size,
+		ContentType: contentType,
+		Checksum:    checksum(sum),
+		CreatedAt:   s.now().UTC(),
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(files, name)); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(filepath.Join(meta, name+".json"), artifact); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// copy копирует содержимое, соблюдая лимит размера и отмену ctx
+func (s *FSStore) copy(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	if s.maxBytes > 0 {
+		r = io.LimitReader(r, s.maxBytes+1)
+	}
+	size, err := io.Copy(w, readerFunc(func(p []byte) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return r.Read(p)
+	}))
+	if err != nil {
+		return 0, err
+	}
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, s.maxBytes)
+	}
+	return size, nil
+}
+
+func (s *FSStore) List(taskID string) ([]models.Artifact, error) {
+	entries, err := os.ReadDir(filepath.Join(s.taskDir(taskID), "meta"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []models.Artifact
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || ValidateName(name) != nil {
+			continue
+		}
+		artifact, err := s.stat(taskID, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *artifact)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (s *FSStore) Open(taskID, name string) (File, *models.Artifact, error) {
+	if ValidateName(name) != nil {
+		return nil, nil, ErrNotFound
+	}
+	artifact, err := s.stat(taskID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filepath.Join(s.taskDir(taskID), "files", name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, artifact, nil
+}
+
+func (s *FSStore) Delete(taskID string) error {
+	return os.RemoveAll(s.taskDir(taskID))
+}
+
+// stat читает описание артефакта
+func (s *FSStore) stat(taskID, name string) (*models.Artifact, error) {
+	data, err := os.ReadFile(filepath.Join(s.taskDir(taskID), "meta", name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var artifact models.Artifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("artifact %q: corrupted metadata: %w", name, err)
+	}
+	return &artifact, nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// detectContentType определяет тип по расширению, иначе по первым байтам
+func detectContentType(name string, head []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+func checksum(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
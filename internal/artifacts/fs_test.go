@@ -0,0 +1,92 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"report.csv", "export_2024-05.tar.gz", "a"} {
+		assert.NoError(t, ValidateName(name), name)
+	}
+	for _, name := range []string{"", ".", "..", ".hidden", "dir/file", `dir\file`, "with space", strings.Repeat("a", 256)} {
+		assert.Error(t, ValidateName(name), name)
+	}
+}
+
+func TestFSStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFSStore(t.TempDir(), WithMaxBytes(16))
+	require.NoError(t, err)
+
+	artifact, err := store.Put(ctx, "t1", "report.csv", "", strings.NewReader("a,b\n1,2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), artifact.Size)
+	// Тип определяется по расширению или, если оно неизвестно системе, по содержимому
+	assert.True(t, strings.HasPrefix(artifact.ContentType, "text/"), artifact.ContentType)
+	assert.Equal(t, "sha256:492d5ea496056f1a6a6592241032fab764c321596317930b4fa0e1e8bc3b7470", artifact.Checksum)
+
+	_, err = store.Put(ctx, "t1", "blob", "", strings.NewReader("\x00\x01\x02"))
+	require.NoError(t, err)
+	_, err = store.Put(ctx, "t2", "other.txt", "text/plain", strings.NewReader("x"))
+	require.NoError(t, err)
+
+	// Повторная запись заменяет артефакт
+	_, err = store.Put(ctx, "t1", "report.csv", "text/csv", strings.NewReader("a,b\n"))
+	require.NoError(t, err)
+
+	list, err := store.List("t1")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "blob", list[0].Name)
+	assert.Equal(t, "application/octet-stream", list[0].ContentType)
+	assert.Equal(t, "report.csv", list[1].Name)
+	assert.Equal(t, int64(4), list[1].Size)
+
+	file, stored, err := store.Open("t1", "report.csv")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "a,b\n", string(data))
+	assert.Equal(t, list[1], *stored)
+
+	_, err = store.Put(ctx, "t1", "big.bin", "", strings.NewReader(strings.Repeat("x", 17)))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = store.Put(ctx, "t1", "../escape", "", strings.NewReader("x"))
+	assert.Error(t, err)
+	_, _, err = store.Open("t1", "big.bin")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = store.Open("t1", "../t2/files/other.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete("t1"))
+	list, err = store.List("t1")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	list, err = store.List("t2")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestPut(t *testing.T) {
+	_, err := Put(context.Background(), "a.txt", "", strings.NewReader("x"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	store, err := NewFSStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := WithTask(context.Background(), store, "t1")
+	_, err = Put(ctx, "a.txt", "", strings.NewReader("x"))
+	require.NoError(t, err)
+	list, _ := store.List("t1")
+	assert.Len(t, list, 1)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = Put(cancelled, "b.txt", "", strings.NewReader("x"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
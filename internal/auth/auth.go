@@ -0,0 +1,147 @@
+// Package auth проверяет учетные данные запросов и хранит в context
+// субъекта (principal) с его правами.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Scope - право на группу операций
+type Scope string
+
+const (
+	ScopeRead   Scope = "tasks:read"
+	ScopeWrite  Scope = "tasks:write"
+	ScopeCancel Scope = "tasks:cancel"
+	ScopeDelete Scope = "tasks:delete"
+	// ScopeAdmin включает все остальные права
+	ScopeAdmin Scope = "admin"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeRead:   true,
+	ScopeWrite:  true,
+	ScopeCancel: true,
+	ScopeDelete: true,
+	ScopeAdmin:  true,
+}
+
+// ParseScopes проверяет, что все права известны
+func ParseScopes(values []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(values))
+	for _, v := range values {
+		scope := Scope(v)
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", v)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal - аутентифицированный клиент
+type Principal struct {
+	// ID уникален среди всех способов аутентификации: "key:<имя>" для API-ключа,
+	// "jwt:<iss>/<sub>" для токена
+	ID     string
+	Scopes []Scope
+	// Namespace - пространство имен клиента; пустое значение означает пространство по умолчанию
+	Namespace string
+}
+
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъекта, установленного Middleware
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Authenticator сопоставляет bearer-токен с субъектом.
+// Для неизвестного токена возвращает ErrInvalidCredentials.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// Middleware требует заголовок Authorization: Bearer и кладет субъекта в context.
+// Без корректных учетных данных отвечает 401.
+func Middleware(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Identify(a)(Require(next))
+	}
+}
+
+type failureKey struct{}
+
+// Identify кладет в context субъекта, если учетные данные верны, и в любом
+// случае передает запрос дальше. Между Identify и Require можно разместить
+// обработку, которой нужно знать, аутентифицирован ли запрос.
+func Identify(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := bearerToken(r)
+			if err == nil {
+				var p *Principal
+				if p, err = a.Authenticate(r.Context(), token); err == nil {
+					next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+					return
+				}
+			}
+			slog.DebugContext(r.Context(), "authentication failed", "error", err)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), failureKey{}, err)))
+		})
+	}
+}
+
+// Require отвечает 401 на запросы, для которых Identify не нашел субъекта
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		err, _ := r.Context().Value(failureKey{}).(error)
+		if err == nil || errors.Is(err, ErrMissingCredentials) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tasks"`)
+			http.Error(w, "Missing credentials", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tasks", error="invalid_token"`)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	})
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrMissingCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrInvalidCredentials
+	}
+	return strings.TrimSpace(token), nil
+}
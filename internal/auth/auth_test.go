@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStore(t *testing.T) {
+	store, err := NewKeyStore([]Key{
+		{Name: "ci", Hash: HashKey("secret-ci"), Scopes: []string{"tasks:read", "tasks:write"}},
+		{Name: "ops", Hash: HashKey("secret-ops"), Scopes: []string{"admin"}},
+	})
+	require.NoError(t, err)
+
+	t.Run("Known key", func(t *testing.T) {
+		p, err := store.Authenticate(t.Context(), "secret-ci")
+		require.NoError(t, err)
+		assert.Equal(t, "key:ci", p.ID)
+		assert.True(t, p.HasScope(ScopeWrite))
+		assert.False(t, p.HasScope(ScopeDelete))
+	})
+
+	t.Run("Admin has every scope", func(t *testing.T) {
+		p, err := store.Authenticate(t.Context(), "secret-ops")
+		require.NoError(t, err)
+		assert.True(t, p.HasScope(ScopeDelete))
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		_, err := store.Authenticate(t.Context(), "guess")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		_, err := NewKeyStore([]Key{{Name: "a", Hash: "plain", Scopes: nil}})
+		assert.Error(t, err)
+		_, err = NewKeyStore([]Key{{Name: "a", Hash: HashKey("x"), Scopes: []string{"tasks:everything"}}})
+		assert.Error(t, err)
+		_, err = NewKeyStore([]Key{{Name: "a", Hash: HashKey("x")}, {Name: "a", Hash: HashKey("y")}})
+		assert.Error(t, err)
+	})
+}
+
+func TestReadKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	content := "keys:\n  - name: ci\n    hash: " + HashKey("k") + "\n    scopes: [tasks:read]\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keys, err := ReadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Key{{Name: "ci", Hash: HashKey("k"), Scopes: []string{"tasks:read"}}}, keys)
+}
+
+func TestMiddleware(t *testing.T) {
+	store, err := NewKeyStore([]Key{{Name: "ci", Hash: HashKey("secret"), Scopes: []string{"tasks:read"}}})
+	require.NoError(t, err)
+
+	var seen *Principal
+	handler := Middleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"Valid key", "Bearer secret", http.StatusOK},
+		{"Missing header", "", http.StatusUnauthorized},
+		{"Wrong scheme", "Basic secret", http.StatusUnauthorized},
+		{"Wrong key", "Bearer other", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest("GET", "/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				require.NotNil(t, seen)
+				assert.Equal(t, "key:ci", seen.ID)
+			} else {
+				assert.Nil(t, seen)
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// verificationKey - ключ проверки подписи JWT: []byte для HS256,
+// *rsa.PublicKey для RS256, *ecdsa.PublicKey (P-256) для ES256
+type verificationKey struct {
+	id  string
+	key interface{}
+}
+
+// KeySet - набор ключей проверки подписи
+type KeySet struct {
+	keys []verificationKey
+}
+
+// HMACKey добавляет общий секрет для HS256
+func (s *KeySet) HMACKey(id string, secret []byte) {
+	s.keys = append(s.keys, verificationKey{id: id, key: append([]byte(nil), secret...)})
+}
+
+func (s *KeySet) Len() int {
+	return len(s.keys)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ReadJWKS читает ключи из локального JWKS-файла (RFC 7517).
+// Поддерживаются ключи RSA, EC P-256 и oct; ключи шифрования (use=enc) пропускаются.
+func ReadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+
+	set := &KeySet{}
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d (kid %q): %w", i, k.Kid, err)
+		}
+		set.keys = append(set.keys, verificationKey{id: k.Kid, key: key})
+	}
+	return set, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid e")
+		}
+		if n.BitLen() < 2048 {
+			return nil, errors.New("rsa key must be at least 2048 bits")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("invalid k")
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
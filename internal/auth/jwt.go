@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWTVerifier аутентифицирует запросы по JWT, подписанным HS256, RS256 или ES256
+type JWTVerifier struct {
+	keys           *KeySet
+	issuer         string
+	audience       string
+	leeway         time.Duration
+	namespaceClaim string
+	now            func() time.Time
+}
+
+type JWTOption func(*JWTVerifier)
+
+// WithIssuer требует совпадения claim iss
+func WithIssuer(issuer string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience требует, чтобы claim aud содержал audience
+func WithAudience(audience string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway допускает расхождение часов при проверке exp и nbf
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(v *JWTVerifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithNamespaceClaim задает claim, из которого берется пространство имен клиента
+func WithNamespaceClaim(claim string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.namespaceClaim = claim
+	}
+}
+
+func NewJWTVerifier(keys *KeySet, opts ...JWTOption) *JWTVerifier {
+	v := &JWTVerifier{keys: keys, namespaceClaim: "namespace", now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scp       []string `json:"scp"`
+	// extra - все claims, включая нестандартные
+	extra map[string]interface{}
+}
+
+// audience принимает aud как строку или массив строк (RFC 7519, 4.1.3)
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings")
+	}
+	*a = list
+	return nil
+}
+
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	claims, err := v.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	// Права берутся из scope (строка через пробел) или scp (массив).
+	// Незнакомые права провайдера игнорируются.
+	values := claims.Scp
+	if claims.Scope != "" {
+		values = append(values, strings.Fields(claims.Scope)...)
+	}
+	var scopes []Scope
+	for _, value := range values {
+		if knownScopes[Scope(value)] {
+			scopes = append(scopes, Scope(value))
+		}
+	}
+	namespace, _ := claims.extra[v.namespaceClaim].(string)
+	// Субъект уникален только в пределах издателя и не должен совпасть с именем ключа
+	return &Principal{ID: "jwt:" + claims.Issuer + "/" + claims.Subject, Scopes: scopes, Namespace: namespace}, nil
+}
+
+func (v *JWTVerifier) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding")
+	}
+	if !v.verifySignature(header, parts[0]+"."+parts[1], signature) {
+		return nil, fmt.Errorf("signature verification failed")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if err := decodeSegment(parts[1], &claims.extra); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// verifySignature подбирает ключ по kid (или перебирает все) с учетом alg:
+// тип ключа должен соответствовать алгоритму, иначе подпись не принимается
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	for _, k := range v.keys.keys {
+		if header.Kid != "" && k.id != "" && k.id != header.Kid {
+			continue
+		}
+
+		switch key := k.key.(type) {
+		case []byte:
+			if header.Alg != "HS256" {
+				continue
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(signed))
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if header.Alg != "RS256" {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if header.Alg != "ES256" || len(signature) != 64 {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *JWTVerifier) validateClaims(c *jwtClaims) error {
+	now := v.now()
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("exp claim is required")
+	}
+	if now.After(time.Unix(*c.ExpiresAt, 0).Add(v.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if c.NotBefore != nil && now.Add(v.leeway).Before(time.Unix(*c.NotBefore, 0)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if v.audience != "" && !c.Audience.contains(v.audience) {
+		return fmt.Errorf("token is not intended for audience %q", v.audience)
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("sub claim is required")
+	}
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Chain пробует аутентификаторы по очереди, пока один из них не примет токен
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	err := ErrInvalidCredentials
+	for _, a := range c {
+		var p *Principal
+		if p, err = a.Authenticate(ctx, token); err == nil {
+			return p, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken собирает JWT с заданными заголовком и claims
+func signToken(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := b64(h) + "." + b64(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa-1","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec-1","crv":"P-256","x":%q,"y":%q},
+		{"kty":"oct","kid":"hs-1","k":%q},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}
+	]}`,
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()),
+		b64(secret))
+	keys, err := ParseJWKS([]byte(jwks))
+	require.NoError(t, err)
+	assert.Equal(t, 3, keys.Len())
+
+	now := time.Unix(1_700_000_000, 0)
+	verifier := NewJWTVerifier(keys, WithIssuer("https://idp.example"), WithAudience("tasks"), WithLeeway(time.Minute))
+	verifier.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "https://idp.example",
+			"aud":   []string{"tasks", "other"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "tasks:read tasks:write openid",
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	t.Run("Supported algorithms", func(t *testing.T) {
+		for _, tc := range []struct {
+			alg, kid string
+			key      interface{}
+		}{
+			{"RS256", "rsa-1", rsaKey},
+			{"ES256", "ec-1", ecKey},
+			{"HS256", "hs-1", secret},
+		} {
+			token := signToken(t, map[string]interface{}{"alg": tc.alg, "kid": tc.kid}, claims(nil), tc.key)
+			p, err := verifier.Authenticate(t.Context(), token)
+			require.NoError(t, err, tc.alg)
+			assert.Equal(t, "jwt:https://idp.example/alice", p.ID)
+			assert.Equal(t, []Scope{ScopeRead, ScopeWrite}, p.Scopes)
+		}
+	})
+
+	t.Run("scp and namespace claims", func(t *testing.T) {
+		token := signToken(t, map[string]interface{}{"alg": "RS256"}, claims(map[string]interface{}{
+			"scope": "", "scp": []string{"admin"}, "aud": "tasks", "namespace": "team-a",
+		}), rsaKey)
+		p, err := verifier.Authenticate(t.Context(), token)
+		require.NoError(t, err)
+		assert.True(t, p.HasScope(ScopeDelete))
+		assert.Equal(t, "team-a", p.Namespace)
+	})
+
+	rejected := []struct {
+		name   string
+		header map[string]interface{}
+		claims map[string]interface{}
+		key    interface{}
+	}{
+		{"Expired", nil, map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}, rsaKey},
+		{"Missing exp", nil, map[string]interface{}{"exp": nil}, rsaKey},
+		{"Not yet valid", nil, map[string]interface{}{"nbf": now.Add(2 * time.Minute).Unix()}, rsaKey},
+		{"Wrong issuer", nil, map[string]interface{}{"iss": "https://evil.example"}, rsaKey},
+		{"Wrong audience", nil, map[string]interface{}{"aud": "billing"}, rsaKey},
+		{"Unknown signer", nil, nil, mustRSAKey(t)},
+		{"Algorithm none", map[string]interface{}{"alg": "none"}, nil, []byte{}},
+		{"HS256 signed with RSA modulus", map[string]interface{}{"alg": "HS256", "kid": "rsa-1"}, nil, rsaKey.N.Bytes()},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			header := tc.header
+			if header == nil {
+				header = map[string]interface{}{"alg": "RS256", "kid": "rsa-1"}
+			}
+			token := signToken(t, header, claims(tc.claims), tc.key)
+			_, err := verifier.Authenticate(t.Context(), token)
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+
+	t.Run("Malformed token", func(t *testing.T) {
+		_, err := verifier.Authenticate(t.Context(), "not-a-jwt")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestChain(t *testing.T) {
+	store, err := NewKeyStore([]Key{{Name: "ci", Hash: HashKey("api-key"), Scopes: []string{"tasks:read"}}})
+	require.NoError(t, err)
+	keys := &KeySet{}
+	keys.HMACKey("", []byte("secret"))
+	chain := Chain{store, NewJWTVerifier(keys)}
+
+	p, err := chain.Authenticate(t.Context(), "api-key")
+	require.NoError(t, err)
+	assert.Equal(t, "key:ci", p.ID)
+
+	// Субъект токена, совпадающий с именем ключа, остается другим клиентом
+	token := signToken(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{
+		"sub": "ci", "exp": time.Now().Add(time.Hour).Unix(),
+	}, []byte("secret"))
+	p, err = chain.Authenticate(t.Context(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "jwt:/ci", p.ID)
+
+	_, err = chain.Authenticate(t.Context(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const hashPrefix = "sha256:"
+
+// Key - статический API-ключ. Сам ключ не хранится, только его хеш
+// в виде "sha256:<hex>".
+type Key struct {
+	Name      string   `yaml:"name"`
+	Hash      string   `yaml:"hash"`
+	Scopes    []string `yaml:"scopes"`
+	Namespace string   `yaml:"namespace"`
+}
+
+// HashKey возвращает хеш ключа в формате, ожидаемом KeyStore
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// ReadKeyFile читает ключи из YAML-файла вида {keys: [{name, hash, scopes}]}
+func ReadKeyFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file struct {
+		Keys []Key `yaml:"keys"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return file.Keys, nil
+}
+
+type storedKey struct {
+	hash      []byte
+	principal Principal
+}
+
+// KeyStore аутентифицирует запросы по статическим API-ключам
+type KeyStore struct {
+	keys []storedKey
+}
+
+func NewKeyStore(keys []Key) (*KeyStore, error) {
+	s := &KeyStore{}
+	names := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k.Name == "" {
+			return nil, errors.New("api key name must not be empty")
+		}
+		if names[k.Name] {
+			return nil, fmt.Errorf("duplicate api key %q", k.Name)
+		}
+		names[k.Name] = true
+
+		hash, err := decodeHash(k.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		scopes, err := ParseScopes(k.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		s.keys = append(s.keys, storedKey{hash: hash, principal: Principal{ID: "key:" + k.Name, Scopes: scopes, Namespace: k.Namespace}})
+	}
+	return s, nil
+}
+
+func decodeHash(value string) ([]byte, error) {
+	hexHash, ok := strings.CutPrefix(value, hashPrefix)
+	if !ok {
+		return nil, fmt.Errorf("hash must start with %q", hashPrefix)
+	}
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, errors.New("hash must be 64 hex characters")
+	}
+	return hash, nil
+}
+
+// Authenticate сравнивает хеш токена со всеми ключами за постоянное время
+func (s *KeyStore) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(token))
+
+	var found *storedKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].hash) == 1 {
+			found = &s.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	p := found.principal
+	p.Scopes = append([]Scope(nil), p.Scopes...)
+	return &p, nil
+}
@@ -0,0 +1,616 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix - префикс переменных окружения сервиса, например TASKS_SERVER_ADDR
+const EnvPrefix = "TASKS_"
+
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Workers    WorkersConfig    `yaml:"workers"`
+	Queue      QueueConfig      `yaml:"queue"`
+	Tasks      TasksConfig      `yaml:"tasks"`
+	TaskLogs   TaskLogsConfig   `yaml:"task_logs"`
+	Artifacts  ArtifactsConfig  `yaml:"artifacts"`
+	API        APIConfig        `yaml:"api"`
+	Retention  RetentionConfig  `yaml:"retention"`
+	Logging    LoggingConfig    `yaml:"logging"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Quotas     QuotasConfig     `yaml:"quotas"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Validation ValidationConfig `yaml:"validation"`
+	Executors  ExecutorsConfig  `yaml:"executors"`
+}
+
+type ServerConfig struct {
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout ограничивает ожидание завершения HTTP-запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	Path    string `yaml:"path"`
+	// FlushInterval - как часто backend file сохраняет накопленные изменения (0 - после каждого)
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+type WorkersConfig struct {
+	Count int `yaml:"count"`
+	// DrainTimeout - сколько ждать выполняющиеся задачи при остановке,
+	// прежде чем прервать их и сохранить для следующего запуска
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+}
+
+type QueueConfig struct {
+	MaxPending int `yaml:"max_pending"`
+}
+
+type TasksConfig struct {
+	MinDuration time.Duration `yaml:"min_duration"`
+	MaxDuration time.Duration `yaml:"max_duration"`
+	// StatsWindow - скользящее окно статистики GET /task-types/{type}/stats
+	StatsWindow time.Duration `yaml:"stats_window"`
+}
+
+// TaskLogsConfig - журналы выполнения задач
+type TaskLogsConfig struct {
+	// Dir - каталог файлов журналов; пусто - журналы хранятся только в памяти
+	Dir string `yaml:"dir"`
+	// MaxLines - сколько последних строк журнала одной задачи держать в памяти
+	MaxLines int `yaml:"max_lines"`
+}
+
+// ArtifactsConfig - файлы, которые исполнители сохраняют при выполнении задач
+type ArtifactsConfig struct {
+	// Dir - каталог артефактов; пусто - исполнители не могут сохранять артефакты
+	Dir string `yaml:"dir"`
+	// MaxBytes ограничивает размер одного артефакта (0 - без ограничения)
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+type APIConfig struct {
+	DefaultPageSize int `yaml:"default_page_size"`
+	MaxPageSize     int `yaml:"max_page_size"`
+}
+
+// RetentionConfig задает время хранения завершенных задач по статусам (0 - бессрочно)
+type RetentionConfig struct {
+	Completed time.Duration `yaml:"completed"`
+	Failed    time.Duration `yaml:"failed"`
+	Cancelled time.Duration `yaml:"cancelled"`
+	// MaxTasks - предел общего числа задач, сверх которого удаляются
+	// самые давно завершенные (0 - без ограничения)
+	MaxTasks int `yaml:"max_tasks"`
+	// Interval - период проверки сроков хранения
+	Interval time.Duration `yaml:"interval"`
+	// Archive - файл JSON Lines, в который задачи дописываются перед удалением
+	Archive string `yaml:"archive"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// AuthConfig включает аутентификацию по API-ключам. Ключи задаются
+// списком keys и/или файлом key_file того же формата.
+type AuthConfig struct {
+	Enabled bool           `yaml:"enabled"`
+	KeyFile string         `yaml:"key_file"`
+	Keys    []APIKeyConfig `yaml:"keys"`
+	JWT     JWTConfig      `yaml:"jwt"`
+}
+
+// JWTConfig включает прием JWT. Подпись проверяется ключами из локального
+// JWKS-файла и/или общим секретом HS256.
+type JWTConfig struct {
+	JWKSFile   string        `yaml:"jwks_file"`
+	HMACSecret Secret        `yaml:"hmac_secret"`
+	Issuer     string        `yaml:"issuer"`
+	Audience   string        `yaml:"audience"`
+	Leeway     time.Duration `yaml:"leeway"`
+	// NamespaceClaim - claim с пространством имен клиента
+	NamespaceClaim string `yaml:"namespace_claim"`
+}
+
+// Enabled сообщает, задан ли хотя бы один ключ проверки подписи
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSFile != "" || c.HMACSecret != ""
+}
+
+type APIKeyConfig struct {
+	Name string `yaml:"name"`
+	// Hash - SHA-256 ключа в виде "sha256:<hex>"
+	Hash   Secret   `yaml:"hash"`
+	Scopes []string `yaml:"scopes"`
+	// Namespace ограничивает ключ одним пространством имен (кроме ключей с правом admin)
+	Namespace string `yaml:"namespace"`
+}
+
+// QuotasConfig ограничивает пространства имен: default действует для всех,
+// namespaces переопределяет квоту целиком для отдельных пространств
+type QuotasConfig struct {
+	Default    QuotaConfig            `yaml:"default"`
+	Namespaces map[string]QuotaConfig `yaml:"namespaces"`
+}
+
+// QuotaConfig - лимиты пространства имен (0 - без ограничения)
+type QuotaConfig struct {
+	MaxPending      int `yaml:"max_pending"`
+	MaxProcessing   int `yaml:"max_processing"`
+	CreatePerMinute int `yaml:"create_per_minute"`
+}
+
+// RateLimitConfig ограничивает частоту запросов одного клиента (API-ключа или IP)
+// отдельно для чтения, создания, изменения и удаления задач
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxClients ограничивает число отслеживаемых клиентов на класс маршрутов
+	MaxClients int           `yaml:"max_clients"`
+	Read       RateLimitRule `yaml:"read"`
+	Create     RateLimitRule `yaml:"create"`
+	Write      RateLimitRule `yaml:"write"`
+	Delete     RateLimitRule `yaml:"delete"`
+}
+
+// ValidationConfig - правила проверки запросов на создание и изменение задач
+type ValidationConfig struct {
+	RequireDescription   bool `yaml:"require_description"`
+	MaxDescriptionLength int  `yaml:"max_description_length"`
+	// DescriptionPattern - регулярное выражение, которому должно целиком соответствовать описание
+	DescriptionPattern string `yaml:"description_pattern"`
+	MaxLabels          int    `yaml:"max_labels"`
+	MaxMetadataBytes   int    `yaml:"max_metadata_bytes"`
+	MaxBodyBytes       int    `yaml:"max_body_bytes"`
+	// DisallowUnknownFields отклоняет запросы с полями, которых нет в API
+	DisallowUnknownFields bool `yaml:"disallow_unknown_fields"`
+}
+
+// ExecutorsConfig настраивает встроенные типы задач. Задается только в файле конфигурации.
+type ExecutorsConfig struct {
+	Exec ExecConfig `yaml:"exec"`
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// ExecConfig включает тип задач "exec", если задана хотя бы одна команда
+type ExecConfig struct {
+	Commands       map[string]ExecCommandConfig `yaml:"commands"`
+	MaxConcurrency int                          `yaml:"max_concurrency"`
+	// KillGrace - пауза между SIGTERM и SIGKILL при остановке команды
+	KillGrace time.Duration `yaml:"kill_grace"`
+	Retry     RetryConfig   `yaml:"retry"`
+}
+
+// ExecCommandConfig - разрешенная команда и лимиты ее запуска (0 - без ограничения)
+type ExecCommandConfig struct {
+	Path     string            `yaml:"path"`
+	Args     []string          `yaml:"args"`
+	WorkDir  string            `yaml:"work_dir"`
+	Env      map[string]string `yaml:"env"`
+	AllowEnv []string          `yaml:"allow_env"`
+	Timeout  time.Duration     `yaml:"timeout"`
+	// MaxOutputBytes ограничивает суммарный объем stdout и stderr
+	MaxOutputBytes     int64 `yaml:"max_output_bytes"`
+	RetryableExitCodes []int `yaml:"retryable_exit_codes"`
+}
+
+// HTTPConfig включает тип задач "http" - запросы к внутренним сервисам
+type HTTPConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowedHosts - разрешенные хосты, "*.example.com" разрешает поддомены; обязателен
+	AllowedHosts []string `yaml:"allowed_hosts"`
+	// Headers - заголовки запросов к хостам, в том числе учетные данные,
+	// которые нельзя передавать во входе задачи
+	Headers        map[string]map[string]Secret `yaml:"headers"`
+	RequestTimeout time.Duration                `yaml:"request_timeout"`
+	// MaxResponseBytes ограничивает тело ответа, сохраняемое в результате
+	MaxResponseBytes int64 `yaml:"max_response_bytes"`
+	// Retry - повторы запроса при ответах 5xx и 429 и сетевых ошибках
+	Retry        RetryConfig   `yaml:"retry"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Timeout ограничивает задачу целиком, вместе с повторами и опросом (0 - без ограничения)
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxConcurrency int           `yaml:"max_concurrency"`
+}
+
+// RetryConfig - повторы задачи после ошибок, допускающих повтор
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+}
+
+// RateLimitRule - средняя частота и допустимый всплеск запросов (0 - без ограничения)
+type RateLimitRule struct {
+	PerMinute int `yaml:"per_minute"`
+	Burst     int `yaml:"burst"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Storage: StorageConfig{Backend: "memory", FlushInterval: time.Second},
+		Workers: WorkersConfig{
+			Count:        16,
+			DrainTimeout: 30 * time.Second,
+		},
+		Queue: QueueConfig{MaxPending: 1000},
+		Tasks: TasksConfig{
+			MinDuration: 3 * time.Minute,
+			MaxDuration: 5 * time.Minute,
+			StatsWindow: time.Hour,
+		},
+		TaskLogs:  TaskLogsConfig{MaxLines: 10000},
+		Retention: RetentionConfig{Interval: time.Minute},
+		Artifacts: ArtifactsConfig{MaxBytes: 1 << 30},
+		API: APIConfig{
+			DefaultPageSize: 10,
+			MaxPageSize:     100,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		RateLimit: RateLimitConfig{
+			MaxClients: 10000,
+			Read:       RateLimitRule{PerMinute: 600, Burst: 100},
+			Create:     RateLimitRule{PerMinute: 60, Burst: 20},
+			Write:      RateLimitRule{PerMinute: 120, Burst: 20},
+			Delete:     RateLimitRule{PerMinute: 60, Burst: 10},
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{Leeway: 30 * time.Second, NamespaceClaim: "namespace"},
+		},
+		Validation: ValidationConfig{
+			RequireDescription:    true,
+			MaxDescriptionLength:  1024,
+			MaxLabels:             64,
+			MaxMetadataBytes:      16 << 10,
+			MaxBodyBytes:          1 << 20,
+			DisallowUnknownFields: true,
+		},
+		Executors: ExecutorsConfig{
+			Exec: ExecConfig{KillGrace: 5 * time.Second},
+			HTTP: HTTPConfig{
+				RequestTimeout:   30 * time.Second,
+				MaxResponseBytes: 1 << 20,
+				Retry:            RetryConfig{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second},
+				PollInterval:     5 * time.Second,
+				Timeout:          time.Hour,
+			},
+		},
+	}
+}
+
+// field связывает параметр конфигурации с флагом и переменной окружения
+type field struct {
+	flag  string
+	env   string
+	usage string
+	ptr   interface{}
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		{"addr", "SERVER_ADDR", "HTTP listen address", &c.Server.Addr},
+		{"read-timeout", "SERVER_READ_TIMEOUT", "HTTP read timeout", &c.Server.ReadTimeout},
+		{"write-timeout", "SERVER_WRITE_TIMEOUT", "HTTP write timeout", &c.Server.WriteTimeout},
+		{"idle-timeout", "SERVER_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", &c.Server.IdleTimeout},
+		{"shutdown-timeout", "SERVER_SHUTDOWN_TIMEOUT", "how long to wait for HTTP requests on shutdown", &c.Server.ShutdownTimeout},
+		{"storage-backend", "STORAGE_BACKEND", "storage backend: memory or file", &c.Storage.Backend},
+		{"storage-path", "STORAGE_PATH", "storage file path for the file backend", &c.Storage.Path},
+		{"storage-flush-interval", "STORAGE_FLUSH_INTERVAL", "how often the file backend saves changes (0 - after every change)", &c.Storage.FlushInterval},
+		{"workers", "WORKERS_COUNT", "number of concurrently processed tasks", &c.Workers.Count},
+		{"drain-timeout", "WORKERS_DRAIN_TIMEOUT", "how long to wait for running tasks on shutdown", &c.Workers.DrainTimeout},
+		{"queue-max-pending", "QUEUE_MAX_PENDING", "maximum number of queued tasks (0 - unlimited)", &c.Queue.MaxPending},
+		{"task-min-duration", "TASKS_MIN_DURATION", "minimum simulated task duration", &c.Tasks.MinDuration},
+		{"task-max-duration", "TASKS_MAX_DURATION", "maximum simulated task duration", &c.Tasks.MaxDuration},
+		{"task-stats-window", "TASKS_STATS_WINDOW", "rolling window of per-type task statistics", &c.Tasks.StatsWindow},
+		{"task-logs-dir", "TASK_LOGS_DIR", "directory for task log files (empty - in memory only)", &c.TaskLogs.Dir},
+		{"task-logs-max-lines", "TASK_LOGS_MAX_LINES", "task log lines kept in memory per task", &c.TaskLogs.MaxLines},
+		{"artifacts-dir", "ARTIFACTS_DIR", "directory for task artifacts (empty - artifacts disabled)", &c.Artifacts.Dir},
+		{"artifacts-max-bytes", "ARTIFACTS_MAX_BYTES", "maximum size of a single artifact in bytes (0 - unlimited)", &c.Artifacts.MaxBytes},
+		{"default-page-size", "API_DEFAULT_PAGE_SIZE", "default page size of GET /tasks", &c.API.DefaultPageSize},
+		{"max-page-size", "API_MAX_PAGE_SIZE", "maximum page size of GET /tasks", &c.API.MaxPageSize},
+		{"retention-completed", "RETENTION_COMPLETED", "how long completed tasks are kept (0 - forever)", &c.Retention.Completed},
+		{"retention-failed", "RETENTION_FAILED", "how long failed tasks are kept (0 - forever)", &c.Retention.Failed},
+		{"retention-cancelled", "RETENTION_CANCELLED", "how long cancelled tasks are kept (0 - forever)", &c.Retention.Cancelled},
+		{"retention-max-tasks", "RETENTION_MAX_TASKS", "maximum number of tasks before the oldest finished ones are removed (0 - unlimited)", &c.Retention.MaxTasks},
+		{"retention-interval", "RETENTION_INTERVAL", "how often expired tasks are removed", &c.Retention.Interval},
+		{"retention-archive", "RETENTION_ARCHIVE", "JSON Lines file that removed tasks are appended to (empty - no archive)", &c.Retention.Archive},
+		{"log-level", "LOG_LEVEL", "log level: debug, info, warn, error", &c.Logging.Level},
+		{"log-format", "LOG_FORMAT", "log format: text or json", &c.Logging.Format},
+		{"quota-max-pending", "QUOTAS_DEFAULT_MAX_PENDING", "default per-namespace limit of queued tasks (0 - unlimited)", &c.Quotas.Default.MaxPending},
+		{"quota-max-processing", "QUOTAS_DEFAULT_MAX_PROCESSING", "default per-namespace limit of running tasks (0 - unlimited)", &c.Quotas.Default.MaxProcessing},
+		{"quota-create-per-minute", "QUOTAS_DEFAULT_CREATE_PER_MINUTE", "default per-namespace task creation rate (0 - unlimited)", &c.Quotas.Default.CreatePerMinute},
+		{"rate-limit-enabled", "RATE_LIMIT_ENABLED", "limit request rate per client (true or false)", &c.RateLimit.Enabled},
+		{"rate-limit-max-clients", "RATE_LIMIT_MAX_CLIENTS", "maximum number of tracked clients", &c.RateLimit.MaxClients},
+		{"validation-require-description", "VALIDATION_REQUIRE_DESCRIPTION", "reject tasks without description (true or false)", &c.Validation.RequireDescription},
+		{"validation-max-description-length", "VALIDATION_MAX_DESCRIPTION_LENGTH", "maximum task description length in characters (0 - unlimited)", &c.Validation.MaxDescriptionLength},
+		{"validation-description-pattern", "VALIDATION_DESCRIPTION_PATTERN", "regular expression the whole description must match", &c.Validation.DescriptionPattern},
+		{"validation-max-body-bytes", "VALIDATION_MAX_BODY_BYTES", "maximum request body size in bytes (0 - unlimited)", &c.Validation.MaxBodyBytes},
+		{"validation-disallow-unknown-fields", "VALIDATION_DISALLOW_UNKNOWN_FIELDS", "reject requests with unknown JSON fields (true or false)", &c.Validation.DisallowUnknownFields},
+		{"auth-enabled", "AUTH_ENABLED", "require API keys for /tasks endpoints (true or false)", &c.Auth.Enabled},
+		{"auth-key-file", "AUTH_KEY_FILE", "path to YAML file with API keys", &c.Auth.KeyFile},
+		{"jwt-jwks-file", "AUTH_JWT_JWKS_FILE", "path to JWKS file with JWT verification keys", &c.Auth.JWT.JWKSFile},
+		{"jwt-hmac-secret", "AUTH_JWT_HMAC_SECRET", "shared secret for HS256 JWTs", &c.Auth.JWT.HMACSecret},
+		{"jwt-issuer", "AUTH_JWT_ISSUER", "required JWT iss claim", &c.Auth.JWT.Issuer},
+		{"jwt-audience", "AUTH_JWT_AUDIENCE", "required JWT aud claim", &c.Auth.JWT.Audience},
+	}
+}
+
+// Load собирает конфигурацию. Приоритет источников по возрастанию:
+// значения по умолчанию, файл (-config или TASKS_CONFIG), переменные окружения, флаги.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("http_api", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	configPath := fs.String("config", getenv(EnvPrefix+"CONFIG"), "path to YAML or JSON config file")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.flag] = fs.String(f.flag, "", f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if value := getenv(EnvPrefix + f.env); value != "" {
+			if err := setValue(f.ptr, value); err != nil {
+				return nil, fmt.Errorf("invalid %s%s: %w", EnvPrefix, f.env, err)
+			}
+		}
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+	for _, f := range fields {
+		if !set[f.flag] {
+			continue
+		}
+		if err := setValue(f.ptr, *flagValues[f.flag]); err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", f.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile читает YAML; JSON является подмножеством YAML и читается тем же парсером
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func setValue(ptr interface{}, value string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = value
+	case *Secret:
+		*p = Secret(value)
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported config field type %T", ptr)
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must not be empty")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.ShutdownTimeout >= 0, "server.shutdown_timeout must not be negative")
+	switch c.Storage.Backend {
+	case "memory":
+	case "file":
+		check(c.Storage.Path != "", "storage.path is required for the file backend")
+		check(c.Storage.FlushInterval >= 0, "storage.flush_interval must not be negative")
+	default:
+		check(false, "storage.backend %q is not one of memory, file", c.Storage.Backend)
+	}
+	check(c.Workers.Count >= 1, "workers.count must be at least 1")
+	check(c.Workers.DrainTimeout >= 0, "workers.drain_timeout must not be negative")
+	check(c.Queue.MaxPending >= 0, "queue.max_pending must not be negative")
+	check(c.Tasks.MinDuration >= 0, "tasks.min_duration must not be negative")
+	check(c.Tasks.MaxDuration >= c.Tasks.MinDuration, "tasks.max_duration must not be less than tasks.min_duration")
+	check(c.Tasks.StatsWindow > 0, "tasks.stats_window must be positive")
+	check(c.TaskLogs.MaxLines >= 1, "task_logs.max_lines must be at least 1")
+	check(c.Artifacts.MaxBytes >= 0, "artifacts.max_bytes must not be negative")
+	check(c.API.DefaultPageSize >= 1, "api.default_page_size must be at least 1")
+	check(c.API.MaxPageSize >= c.API.DefaultPageSize, "api.max_page_size must not be less than api.default_page_size")
+	check(c.Retention.Completed >= 0, "retention.completed must not be negative")
+	check(c.Retention.Failed >= 0, "retention.failed must not be negative")
+	check(c.Retention.Cancelled >= 0, "retention.cancelled must not be negative")
+	check(c.Retention.MaxTasks >= 0, "retention.max_tasks must not be negative")
+	check(c.Retention.Interval > 0, "retention.interval must be positive")
+
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "logging.level %q is not one of debug, info, warn, error", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "text", "json":
+	default:
+		check(false, "logging.format %q is not one of text, json", c.Logging.Format)
+	}
+
+	checkQuota := func(name string, q QuotaConfig) {
+		check(q.MaxPending >= 0, "%s.max_pending must not be negative", name)
+		check(q.MaxProcessing >= 0, "%s.max_processing must not be negative", name)
+		check(q.CreatePerMinute >= 0, "%s.create_per_minute must not be negative", name)
+	}
+	checkQuota("quotas.default", c.Quotas.Default)
+	for namespace, q := range c.Quotas.Namespaces {
+		checkQuota("quotas.namespaces."+namespace, q)
+	}
+
+	check(c.RateLimit.MaxClients >= 0, "rate_limit.max_clients must not be negative")
+	for name, rule := range map[string]RateLimitRule{
+		"read": c.RateLimit.Read, "create": c.RateLimit.Create,
+		"write": c.RateLimit.Write, "delete": c.RateLimit.Delete,
+	} {
+		check(rule.PerMinute >= 0, "rate_limit.%s.per_minute must not be negative", name)
+		check(rule.PerMinute == 0 || rule.Burst >= 1, "rate_limit.%s.burst must be at least 1", name)
+	}
+
+	check(c.Validation.MaxDescriptionLength >= 0, "validation.max_description_length must not be negative")
+	check(c.Validation.MaxLabels >= 0, "validation.max_labels must not be negative")
+	check(c.Validation.MaxMetadataBytes >= 0, "validation.max_metadata_bytes must not be negative")
+	check(c.Validation.MaxBodyBytes >= 0, "validation.max_body_bytes must not be negative")
+	if c.Validation.DescriptionPattern != "" {
+		_, err := regexp.Compile(c.Validation.DescriptionPattern)
+		check(err == nil, "validation.description_pattern is not a valid regular expression: %v", err)
+	}
+
+	exec := c.Executors.Exec
+	check(exec.MaxConcurrency >= 0, "executors.exec.max_concurrency must not be negative")
+	check(exec.KillGrace >= 0, "executors.exec.kill_grace must not be negative")
+	check(exec.Retry.MaxAttempts >= 0, "executors.exec.retry.max_attempts must not be negative")
+	check(exec.Retry.Backoff >= 0, "executors.exec.retry.backoff must not be negative")
+	check(exec.Retry.MaxBackoff >= 0, "executors.exec.retry.max_backoff must not be negative")
+	for name, command := range exec.Commands {
+		prefix := "executors.exec.commands." + name
+		check(filepath.IsAbs(command.Path), "%s.path must be an absolute path", prefix)
+		check(command.Timeout >= 0, "%s.timeout must not be negative", prefix)
+		check(command.MaxOutputBytes >= 0, "%s.max_output_bytes must not be negative", prefix)
+	}
+
+	callout := c.Executors.HTTP
+	check(callout.RequestTimeout >= 0, "executors.http.request_timeout must not be negative")
+	check(callout.MaxResponseBytes >= 0, "executors.http.max_response_bytes must not be negative")
+	check(callout.Retry.MaxAttempts >= 0, "executors.http.retry.max_attempts must not be negative")
+	check(callout.Retry.Backoff >= 0, "executors.http.retry.backoff must not be negative")
+	check(callout.Retry.MaxBackoff >= 0, "executors.http.retry.max_backoff must not be negative")
+	check(callout.PollInterval > 0, "executors.http.poll_interval must be positive")
+	check(callout.Timeout >= 0, "executors.http.timeout must not be negative")
+	check(callout.MaxConcurrency >= 0, "executors.http.max_concurrency must not be negative")
+	check(!callout.Enabled || len(callout.AllowedHosts) > 0,
+		"executors.http.allowed_hosts is required when the http executor is enabled")
+	for _, host := range callout.AllowedHosts {
+		check(strings.TrimPrefix(host, "*.") != "" && !strings.ContainsAny(host, "/:"),
+			"executors.http.allowed_hosts: %q is not a host name", host)
+	}
+	for host := range callout.Headers {
+		check(strings.TrimPrefix(host, "*.") != "" && !strings.ContainsAny(host, "/:"),
+			"executors.http.headers: %q is not a host name", host)
+	}
+
+	if c.Auth.Enabled {
+		check(len(c.Auth.Keys) > 0 || c.Auth.KeyFile != "" || c.Auth.JWT.Enabled(),
+			"auth.keys, auth.key_file or auth.jwt is required when auth is enabled")
+	}
+	check(c.Auth.JWT.Leeway >= 0, "auth.jwt.leeway must not be negative")
+	for i, key := range c.Auth.Keys {
+		check(key.Name != "", "auth.keys[%d].name must not be empty", i)
+		check(key.Hash != "", "auth.keys[%d].hash must not be empty", i)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// String возвращает конфигурацию в YAML со скрытыми секретами
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("<invalid config: %v>", err)
+	}
+	return string(data)
+}
+
+// Secret - строковый параметр, который не выводится в логах и дампах конфигурации
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func envFrom(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load(nil, envFrom(nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, Default(), cfg)
+	})
+
+	t.Run("YAML file", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9090"
+workers:
+  count: 4
+tasks:
+  min_duration: 1s
+  max_duration: 2s
+`)
+		cfg, err := Load([]string{"-config", path}, envFrom(nil))
+
+		require.NoError(t, err)
+		assert.Equal(t, ":9090", cfg.Server.Addr)
+		assert.Equal(t, 4, cfg.Workers.Count)
+		assert.Equal(t, time.Second, cfg.Tasks.MinDuration)
+		assert.Equal(t, 1000, cfg.Queue.MaxPending)
+	})
+
+	t.Run("JSON file", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"queue": {"max_pending": 5}, "logging": {"format": "json"}}`)
+
+		cfg, err := Load(nil, envFrom(map[string]string{"TASKS_CONFIG": path}))
+
+		require.NoError(t, err)
+		assert.Equal(t, 5, cfg.Queue.MaxPending)
+		assert.Equal(t, "json", cfg.Logging.Format)
+	})
+
+	t.Run("Precedence: flags over env over file", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "server:\n  addr: \":1111\"\nworkers:\n  count: 2\nqueue:\n  max_pending: 3\n")
+		env := envFrom(map[string]string{
+			"TASKS_SERVER_ADDR":   ":2222",
+			"TASKS_WORKERS_COUNT": "5",
+		})
+
+		cfg, err := Load([]string{"-config", path, "-addr", ":3333"}, env)
+
+		require.NoError(t, err)
+		assert.Equal(t, ":3333", cfg.Server.Addr)
+		assert.Equal(t, 5, cfg.Workers.Count)
+		assert.Equal(t, 3, cfg.Queue.MaxPending)
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		_, err := Load(nil, envFrom(map[string]string{"TASKS_WORKERS_COUNT": "many"}))
+		assert.Error(t, err)
+
+		_, err = Load([]string{"-task-min-duration", "soon"}, envFrom(nil))
+		assert.Error(t, err)
+
+		path := writeFile(t, "config.yaml", "server:\n  adress: \":1\"\n")
+		_, err = Load([]string{"-config", path}, envFrom(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("Auth keys", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+auth:
+  keys:
+    - name: ci
+      hash: "sha256:abc"
+      scopes: [tasks:read]
+`)
+		cfg, err := Load([]string{"-config", path}, envFrom(map[string]string{"TASKS_AUTH_ENABLED": "true"}))
+
+		require.NoError(t, err)
+		assert.True(t, cfg.Auth.Enabled)
+		require.Len(t, cfg.Auth.Keys, 1)
+		assert.Equal(t, Secret("sha256:abc"), cfg.Auth.Keys[0].Hash)
+		assert.NotContains(t, cfg.String(), "sha256:abc")
+
+		_, err = Load([]string{"-auth-enabled", "true"}, envFrom(nil))
+		assert.ErrorContains(t, err, "auth.keys, auth.key_file or auth.jwt")
+	})
+
+	t.Run("Quotas", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+quotas:
+  default:
+    max_processing: 4
+  namespaces:
+    team-a:
+      max_pending: 10
+      create_per_minute: -1
+`)
+		_, err := Load([]string{"-config", path}, envFrom(nil))
+		assert.ErrorContains(t, err, "quotas.namespaces.team-a.create_per_minute")
+
+		cfg, err := Load([]string{"-quota-max-pending", "50"}, envFrom(nil))
+		require.NoError(t, err)
+		assert.Equal(t, 50, cfg.Quotas.Default.MaxPending)
+	})
+
+	t.Run("Task logs", func(t *testing.T) {
+		cfg, err := Load([]string{"-task-logs-max-lines", "500"}, envFrom(map[string]string{"TASKS_TASK_LOGS_DIR": "/var/lib/tasks/logs"}))
+		require.NoError(t, err)
+		assert.Equal(t, "/var/lib/tasks/logs", cfg.TaskLogs.Dir)
+		assert.Equal(t, 500, cfg.TaskLogs.MaxLines)
+
+		_, err = Load([]string{"-task-logs-max-lines", "0"}, envFrom(nil))
+		assert.ErrorContains(t, err, "task_logs.max_lines must be at least 1")
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+retention:
+  completed: 24h
+  failed: 168h
+  max_tasks: 100000
+`)
+		cfg, err := Load([]string{"-config", path, "-retention-interval", "30s"}, envFrom(map[string]string{"TASKS_RETENTION_ARCHIVE": "/var/lib/tasks/archive.jsonl"}))
+		require.NoError(t, err)
+		assert.Equal(t, 24*time.Hour, cfg.Retention.Completed)
+		assert.Equal(t, 168*time.Hour, cfg.Retention.Failed)
+		assert.Equal(t, 100000, cfg.Retention.MaxTasks)
+		assert.Equal(t, 30*time.Second, cfg.Retention.Interval)
+		assert.Equal(t, "/var/lib/tasks/archive.jsonl", cfg.Retention.Archive)
+
+		_, err = Load([]string{"-retention-interval", "0s", "-retention-max-tasks", "-1"}, envFrom(nil))
+		assert.ErrorContains(t, err, "retention.max_tasks must not be negative")
+		assert.ErrorContains(t, err, "retention.interval must be positive")
+	})
+
+	t.Run("Artifacts", func(t *testing.T) {
+		cfg, err := Load([]string{"-artifacts-max-bytes", "1048576"}, envFrom(map[string]string{"TASKS_ARTIFACTS_DIR": "/var/lib/tasks/artifacts"}))
+		require.NoError(t, err)
+		assert.Equal(t, "/var/lib/tasks/artifacts", cfg.Artifacts.Dir)
+		assert.Equal(t, int64(1<<20), cfg.Artifacts.MaxBytes)
+
+		_, err = Load([]string{"-artifacts-max-bytes", "-1"}, envFrom(nil))
+		assert.ErrorContains(t, err, "artifacts.max_bytes must not be negative")
+	})
+
+	t.Run("Exec commands", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+executors:
+  exec:
+    max_concurrency: 2
+    commands:
+      backup:
+        path: /usr/local/bin/backup
+        args: [--quiet]
+        allow_env: [TARGET]
+        timeout: 10m
+        retryable_exit_codes: [75]
+`)
+		cfg, err := Load([]string{"-config", path}, envFrom(nil))
+		require.NoError(t, err)
+		command := cfg.Executors.Exec.Commands["backup"]
+		assert.Equal(t, []string{"--quiet"}, command.Args)
+		assert.Equal(t, 10*time.Minute, command.Timeout)
+		assert.Equal(t, []int{75}, command.RetryableExitCodes)
+		assert.Equal(t, 5*time.Second, cfg.Executors.Exec.KillGrace)
+
+		path = writeFile(t, "relative.yaml", `
+executors:
+  exec:
+    commands:
+      backup:
+        path: bin/backup
+`)
+		_, err = Load([]string{"-config", path}, envFrom(nil))
+		assert.ErrorContains(t, err, "executors.exec.commands.backup.path must be an absolute path")
+	})
+
+	t.Run("HTTP executor", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+executors:
+  http:
+    enabled: true
+    allowed_hosts: [billing.internal, "*.svc.cluster.local"]
+    headers:
+      billing.internal: {Authorization: Bearer secret}
+    retry:
+      max_attempts: 5
+`)
+		cfg, err := Load([]string{"-config", path}, envFrom(nil))
+		require.NoError(t, err)
+		assert.True(t, cfg.Executors.HTTP.Enabled)
+		assert.Equal(t, Secret("Bearer secret"), cfg.Executors.HTTP.Headers["billing.internal"]["Authorization"])
+		assert.NotContains(t, cfg.String(), "Bearer secret")
+		assert.Equal(t, 5, cfg.Executors.HTTP.Retry.MaxAttempts)
+		assert.Equal(t, time.Second, cfg.Executors.HTTP.Retry.Backoff)
+		assert.Equal(t, int64(1<<20), cfg.Executors.HTTP.MaxResponseBytes)
+
+		path = writeFile(t, "invalid.yaml", `
+executors:
+  http:
+    allowed_hosts: ["http://billing.internal"]
+`)
+		_, err = Load([]string{"-config", path}, envFrom(nil))
+		assert.ErrorContains(t, err, `executors.http.allowed_hosts: "http://billing.internal" is not a host name`)
+
+		path = writeFile(t, "open.yaml", `
+executors:
+  http:
+    enabled: true
+`)
+		_, err = Load([]string{"-config", path}, envFrom(nil))
+		assert.ErrorContains(t, err, "executors.http.allowed_hosts is required when the http executor is enabled")
+	})
+
+	t.Run("Request validation rules", func(t *testing.T) {
+		cfg, err := Load(nil, envFrom(map[string]string{
+			"TASKS_VALIDATION_MAX_BODY_BYTES":          "4096",
+			"TASKS_VALIDATION_DISALLOW_UNKNOWN_FIELDS": "false",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, 4096, cfg.Validation.MaxBodyBytes)
+		assert.False(t, cfg.Validation.DisallowUnknownFields)
+		assert.True(t, cfg.Validation.RequireDescription)
+
+		_, err = Load([]string{"-validation-description-pattern", "[a-z"}, envFrom(nil))
+		assert.ErrorContains(t, err, "validation.description_pattern")
+	})
+
+	t.Run("Validation reports every problem", func(t *testing.T) {
+		_, err := Load([]string{
+			"-workers", "0",
+			"-task-min-duration", "5m",
+			"-task-max-duration", "1m",
+			"-log-level", "verbose",
+		}, envFrom(nil))
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "workers.count")
+		assert.Contains(t, err.Error(), "tasks.max_duration")
+		assert.Contains(t, err.Error(), "logging.level")
+	})
+}
+
+func TestSecretRedaction(t *testing.T) {
+	assert.Equal(t, "[REDACTED]", Secret("hunter2").String())
+	assert.Equal(t, "", Secret("").String())
+
+	out, err := yaml.Marshal(struct {
+		Token Secret `yaml:"token"`
+	}{Token: "hunter2"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "hunter2")
+	assert.Contains(t, string(out), "[REDACTED]")
+
+	dump := Default().String()
+	assert.Contains(t, dump, "addr: :8080")
+	assert.Contains(t, dump, "max_pending: 1000")
+}
@@ -0,0 +1,349 @@
+// Package command реализует тип задач "exec": запуск разрешенных в конфигурации
+// команд с аргументами из входа задачи. Вывод команды пишется в журнал задачи,
+// код завершения определяет исход, а при отмене или превышении лимитов
+// завершается вся группа процессов команды.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypeName - имя типа задач, выполняющих команды
+const TypeName = "exec"
+
+// Коды ошибок исполнителя
+const (
+	// ErrorCodeExitStatus - команда завершилась с ненулевым кодом
+	ErrorCodeExitStatus = "exit_status"
+	// ErrorCodeOutputLimit - команда вывела больше разрешенного
+	ErrorCodeOutputLimit = "output_limit_exceeded"
+	// ErrorCodeStartFailed - команду не удалось запустить
+	ErrorCodeStartFailed = "start_failed"
+)
+
+const defaultKillGrace = 5 * time.Second
+
+// maxTimeout - наибольший таймаут, представимый в time.Duration
+const maxTimeout = time.Duration(math.MaxInt64)
+
+// Command - разрешенная команда и лимиты ее запуска
+type Command struct {
+	// Path - абсолютный путь к исполняемому файлу
+	Path string
+	// Args передаются перед аргументами из входа задачи
+	Args []string
+	// WorkDir - рабочий каталог ("" - каталог сервиса)
+	WorkDir string
+	// Env - переменные окружения команды; окружение сервиса не наследуется
+	Env map[string]string
+	// AllowEnv - переменные, которые может задать вход задачи
+	AllowEnv []string
+	// Timeout ограничивает время работы команды (0 - без ограничения)
+	Timeout time.Duration
+	// MaxOutputBytes ограничивает суммарный объем stdout и stderr (0 - без ограничения)
+	MaxOutputBytes int64
+	// RetryableExitCodes - коды завершения, после которых задачу можно повторить
+	RetryableExitCodes []int
+}
+
+// Input - вход задачи типа exec
+type Input struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// TimeoutSeconds и MaxOutputBytes ужесточают лимиты команды для одной
+	// задачи; ослабить лимиты из конфигурации задача не может
+	TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+	MaxOutputBytes int64   `json:"max_output_bytes,omitempty"`
+}
+
+// Output - результат задачи типа exec
+type Output struct {
+	ExitCode    int   `json:"exit_code"`
+	OutputBytes int64 `json:"output_bytes"`
+}
+
+// Executor запускает разрешенные команды
+type Executor struct {
+	commands map[string]Command
+	// killGrace - сколько ждать завершения группы после SIGTERM перед SIGKILL
+	killGrace time.Duration
+}
+
+type Option func(*Executor)
+
+// WithKillGrace задает паузу между SIGTERM и SIGKILL при остановке команды
+func WithKillGrace(d time.Duration) Option {
+	return func(e *Executor) {
+		e.killGrace = d
+	}
+}
+
+// New проверяет список разрешенных команд и создает исполнитель
+func New(commands map[string]Command, opts ...Option) (*Executor, error) {
+	if len(commands) == 0 {
+		return nil, errors.New("no commands are allowed")
+	}
+	for name, c := range commands {
+		if name == "" {
+			return nil, errors.New("command name must not be empty")
+		}
+		if !filepath.IsAbs(c.Path) {
+			return nil, fmt.Errorf("command %q: path must be absolute", name)
+		}
+		if c.Timeout < 0 || c.MaxOutputBytes < 0 {
+			return nil, fmt.Errorf("command %q: limits must not be negative", name)
+		}
+	}
+
+	e := &Executor{commands: commands, killGrace: defaultKillGrace}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// TaskType описывает тип exec со схемой входа, перечисляющей разрешенные команды
+func (e *Executor) TaskType() tasktypes.TaskType {
+	names := make([]string, 0, len(e.commands))
+	for name := range e.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	enum, _ := json.Marshal(names)
+	return tasktypes.TaskType{
+		Name:        TypeName,
+		Description: "Runs an allow-listed command: " + strings.Join(names, ", "),
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"required": ["command"],
+			"properties": {
+				"command": {"enum": ` + string(enum) + `},
+				"args": {"type": "array", "items": {"type": "string"}},
+				"env": {"type": "object", "additionalProperties": {"type": "string"}},
+				"timeout_seconds": {"type": "number", "exclusiveMinimum": 0},
+				"max_output_bytes": {"type": "integer", "minimum": 1}
+			},
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"required": ["exit_code", "output_bytes"],
+			"properties": {
+				"exit_code": {"type": "integer"},
+				"output_bytes": {"type": "integer", "minimum": 0}
+			}
+		}`),
+		Executor: e,
+	}
+}
+
+func (e *Executor) Execute(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+	var in Input
+	if err := json.Unmarshal(task.Input, &in); err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+	c, ok := e.commands[in.Command]
+	if !ok {
+		return nil, tasktypes.InvalidInput(fmt.Errorf("command %q is not allowed", in.Command))
+	}
+	c, err := c.limited(in)
+	if err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+	env, err := c.environ(in.Env)
+	if err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if c.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, c.Timeout)
+		defer cancel()
+	}
+
+	stdout := tasklogs.Writer(ctx, tasklogs.Stdout)
+	stderr := tasklogs.Writer(ctx, tasklogs.Stderr)
+	defer stdout.Close()
+	defer stderr.Close()
+	output := &outputLimit{limit: c.MaxOutputBytes, exceeded: cancel}
+
+	cmd := exec.CommandContext(runCtx, c.Path, append(append([]string(nil), c.Args...), in.Args...)...)
+	cmd.Dir = c.WorkDir
+	cmd.Env = env
+	cmd.Stdout = output.wrap(stdout)
+	cmd.Stderr = output.wrap(stderr)
+	setProcessGroup(cmd)
+
+	// При отмене группа получает SIGTERM, а через killGrace - SIGKILL
+	var killTimer *time.Timer
+	var killMu sync.Mutex
+	cmd.Cancel = func() error {
+		killMu.Lock()
+		defer killMu.Unlock()
+		killTimer = time.AfterFunc(e.killGrace, func() { signalGroup(cmd, true) })
+		return signalGroup(cmd, false)
+	}
+	// Wait не ждет бесконечно процессы, унаследовавшие stdout/stderr
+	cmd.WaitDelay = e.killGrace + time.Second
+
+	if err := cmd.Start(); err != nil {
+		return nil, tasktypes.Permanent(ErrorCodeStartFailed, err)
+	}
+	waitErr := cmd.Wait()
+	killMu.Lock()
+	if killTimer != nil {
+		killTimer.Stop()
+	}
+	killMu.Unlock()
+
+	switch {
+	case output.over.Load():
+		return nil, &tasktypes.Error{
+			Code:    ErrorCodeOutputLimit,
+			Message: fmt.Sprintf("command output exceeded %d bytes", c.MaxOutputBytes),
+			Details: map[string]interface{}{"max_output_bytes": c.MaxOutputBytes},
+		}
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return nil, &tasktypes.Error{
+			Code:      models.ErrorCodeTimeout,
+			Message:   fmt.Sprintf("command timed out after %s", c.Timeout),
+			Retryable: true,
+			Details:   map[string]interface{}{"timeout_seconds": c.Timeout.Seconds()},
+		}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		code := exitErr.ExitCode()
+		message := fmt.Sprintf("command exited with status %d", code)
+		if code < 0 {
+			message = "command terminated by " + exitErr.String()
+		}
+		return nil, &tasktypes.Error{
+			Code:      ErrorCodeExitStatus,
+			Message:   message,
+			Retryable: c.retryable(code),
+			Details:   map[string]interface{}{"exit_code": code},
+			Err:       waitErr,
+		}
+	}
+	if waitErr != nil {
+		return nil, waitErr
+	}
+	return json.Marshal(Output{ExitCode: 0, OutputBytes: output.total.Load()})
+}
+
+// limited возвращает команду с лимитами, ужесточенными входом задачи.
+// Лимит задачи выше лимита команды - ошибка, а не молчаливое урезание.
+func (c Command) limited(in Input) (Command, error) {
+	if in.TimeoutSeconds < 0 || in.MaxOutputBytes < 0 {
+		return c, errors.New("limits must not be negative")
+	}
+	if in.TimeoutSeconds > 0 {
+		if c.Timeout > 0 && in.TimeoutSeconds > c.Timeout.Seconds() {
+			return c, fmt.Errorf("timeout_seconds exceeds the command limit of %g", c.Timeout.Seconds())
+		}
+		if in.TimeoutSeconds > maxTimeout.Seconds() {
+			return c, errors.New("timeout_seconds is too large")
+		}
+		c.Timeout = time.Duration(in.TimeoutSeconds * float64(time.Second))
+	}
+	if in.MaxOutputBytes > 0 {
+		if c.MaxOutputBytes > 0 && in.MaxOutputBytes > c.MaxOutputBytes {
+			return c, fmt.Errorf("max_output_bytes exceeds the command limit of %d", c.MaxOutputBytes)
+		}
+		c.MaxOutputBytes = in.MaxOutputBytes
+	}
+	return c, nil
+}
+
+// environ собирает окружение команды из настроек и разрешенных переменных входа
+func (c Command) environ(overrides map[string]string) ([]string, error) {
+	vars := make(map[string]string, len(c.Env)+len(overrides))
+	for key, value := range c.Env {
+		vars[key] = value
+	}
+	for key, value := range overrides {
+		if !contains(c.AllowEnv, key) {
+			return nil, fmt.Errorf("environment variable %q is not allowed", key)
+		}
+		vars[key] = value
+	}
+
+	env := make([]string, 0, len(vars))
+	for key, value := range vars {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+func (c Command) retryable(exitCode int) bool {
+	for _, code := range c.RetryableExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// outputLimit считает общий объем вывода и останавливает команду при превышении
+type outputLimit struct {
+	limit    int64
+	total    atomic.Int64
+	over     atomic.Bool
+	exceeded context.CancelFunc
+}
+
+func (o *outputLimit) wrap(w interface{ Write([]byte) (int, error) }) *limitedWriter {
+	return &limitedWriter{limit: o, w: w}
+}
+
+type limitedWriter struct {
+	limit *outputLimit
+	w     interface{ Write([]byte) (int, error) }
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	o := w.limit
+	total := o.total.Add(int64(len(p)))
+	if o.limit > 0 && total > o.limit {
+		// Записываем то, что помещается в лимит, и останавливаем команду
+		if allowed := int64(len(p)) - (total - o.limit); allowed > 0 {
+			w.w.Write(p[:allowed])
+		}
+		if !o.over.Swap(true) {
+			o.exceeded()
+		}
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExecutor разрешает команду "sh", выполняющую скрипт из аргументов
+func newExecutor(t *testing.T, c Command) *Executor {
+	t.Helper()
+	c.Path = "/bin/sh"
+	c.Args = []string{"-c"}
+	e, err := New(map[string]Command{"sh": c}, WithKillGrace(100*time.Millisecond))
+	require.NoError(t, err)
+	return e
+}
+
+func run(e *Executor, store *tasklogs.Store, input string) (json.RawMessage, error) {
+	task := &models.Task{ID: "t1", Type: TypeName, Input: json.RawMessage(input)}
+	return e.Execute(tasklogs.WithTask(context.Background(), store, task.ID), task)
+}
+
+func logLines(store *tasklogs.Store) []string {
+	var result []string
+	for _, e := range store.Entries("t1") {
+		result = append(result, e.Stream+": "+e.Line)
+	}
+	return result
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	assert.Error(t, err)
+
+	_, err = New(map[string]Command{"ls": {Path: "ls"}})
+	assert.ErrorContains(t, err, "path must be absolute")
+
+	e, err := New(map[string]Command{"b": {Path: "/bin/b"}, "a": {Path: "/bin/a"}})
+	require.NoError(t, err)
+	taskType := e.TaskType()
+	assert.Equal(t, "exec", taskType.Name)
+	assert.Contains(t, string(taskType.InputSchema), `["a","b"]`)
+
+	registry := tasktypes.NewRegistry()
+	require.NoError(t, registry.Register(taskType))
+	registered, _ := registry.Get(TypeName)
+	assert.Empty(t, registered.ValidateInput(json.RawMessage(`{"command": "a", "args": ["-l"]}`)))
+	assert.NotEmpty(t, registered.ValidateInput(json.RawMessage(`{"command": "rm"}`)))
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("Output goes to task logs", func(t *testing.T) {
+		store := tasklogs.NewStore(0)
+		e := newExecutor(t, Command{Env: map[string]string{"GREETING": "hello"}})
+
+		result, err := run(e, store, `{"command": "sh", "args": ["echo $GREETING $0; echo oops >&2", "world"]}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"exit_code": 0, "output_bytes": 17}`, string(result))
+		assert.ElementsMatch(t, []string{"stdout: hello world", "stderr: oops"}, logLines(store))
+	})
+
+	t.Run("Exit code", func(t *testing.T) {
+		e := newExecutor(t, Command{RetryableExitCodes: []int{75}})
+
+		_, err := run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["exit 3"]}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeExitStatus, failure.Code)
+		assert.Equal(t, "command exited with status 3", failure.Message)
+		assert.Equal(t, 3, failure.Details["exit_code"])
+		assert.False(t, failure.Retryable)
+
+		_, err = run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["exit 75"]}`)
+		assert.True(t, tasktypes.Describe(err).Retryable)
+	})
+
+	t.Run("Environment whitelist", func(t *testing.T) {
+		store := tasklogs.NewStore(0)
+		e := newExecutor(t, Command{AllowEnv: []string{"NAME"}})
+		t.Setenv("SECRET", "leaked")
+
+		_, err := run(e, store, `{"command": "sh", "args": ["echo $NAME${SECRET}"], "env": {"NAME": "x"}}`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"stdout: x"}, logLines(store))
+
+		_, err = run(e, store, `{"command": "sh", "args": ["true"], "env": {"PATH": "/tmp"}}`)
+		assert.Equal(t, models.ErrorCodeInvalidInput, tasktypes.Describe(err).Code)
+	})
+
+	t.Run("Working directory", func(t *testing.T) {
+		store := tasklogs.NewStore(0)
+		dir := t.TempDir()
+		e := newExecutor(t, Command{WorkDir: dir})
+
+		_, err := run(e, store, `{"command": "sh", "args": ["pwd"]}`)
+		require.NoError(t, err)
+		resolved, _ := filepath.EvalSymlinks(dir)
+		assert.Equal(t, []string{"stdout: " + resolved}, logLines(store))
+	})
+
+	t.Run("Timeout kills the process group", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "marker")
+		e := newExecutor(t, Command{Timeout: 200 * time.Millisecond})
+
+		// Дочерний процесс игнорирует SIGTERM и переживет команду, если группу не убить
+		start := time.Now()
+		_, err := run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["(trap '' TERM; sleep 1; touch `+marker+`) & wait"]}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, models.ErrorCodeTimeout, failure.Code)
+		assert.True(t, failure.Retryable)
+		assert.Less(t, time.Since(start), time.Second)
+
+		time.Sleep(1200 * time.Millisecond)
+		_, statErr := os.Stat(marker)
+		assert.True(t, os.IsNotExist(statErr), "child process survived")
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		e := newExecutor(t, Command{})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		task := &models.Task{ID: "t1", Input: json.RawMessage(`{"command": "sh", "args": ["sleep 5"]}`)}
+		_, err := e.Execute(ctx, task)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("Output limit", func(t *testing.T) {
+		store := tasklogs.NewStore(0)
+		e := newExecutor(t, Command{MaxOutputBytes: 10})
+
+		_, err := run(e, store, `{"command": "sh", "args": ["while true; do echo 0123456789; done"]}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeOutputLimit, failure.Code)
+		assert.False(t, failure.Retryable)
+		assert.Equal(t, []string{"stdout: 0123456789"}, logLines(store))
+	})
+
+	t.Run("Task tightens command limits", func(t *testing.T) {
+		e := newExecutor(t, Command{Timeout: time.Minute, MaxOutputBytes: 100})
+
+		_, err := run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["sleep 5"], "timeout_seconds": 0.2}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, models.ErrorCodeTimeout, failure.Code)
+		assert.Equal(t, 0.2, failure.Details["timeout_seconds"])
+
+		_, err = run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["echo 0123456789"], "max_output_bytes": 5}`)
+		assert.Equal(t, ErrorCodeOutputLimit, tasktypes.Describe(err).Code)
+
+		// Ослабить лимиты команды задача не может
+		_, err = run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["true"], "timeout_seconds": 120}`)
+		failure = tasktypes.Describe(err)
+		assert.Equal(t, models.ErrorCodeInvalidInput, failure.Code)
+		assert.Contains(t, failure.Message, "exceeds the command limit of 60")
+
+		_, err = run(e, tasklogs.NewStore(0), `{"command": "sh", "args": ["true"], "max_output_bytes": 1000}`)
+		assert.Equal(t, models.ErrorCodeInvalidInput, tasktypes.Describe(err).Code)
+	})
+
+	t.Run("Start failure", func(t *testing.T) {
+		e, err := New(map[string]Command{"missing": {Path: "/nonexistent/command"}})
+		require.NoError(t, err)
+
+		_, err = run(e, tasklogs.NewStore(0), `{"command": "missing"}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeStartFailed, failure.Code)
+		assert.True(t, strings.Contains(failure.Message, "no such file"))
+	})
+}
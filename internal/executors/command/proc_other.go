@@ -0,0 +1,12 @@
+//go:build !unix
+
+package command
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup без групп процессов завершает только саму команду
+func signalGroup(cmd *exec.Cmd, kill bool) error {
+	return cmd.Process.Kill()
+}
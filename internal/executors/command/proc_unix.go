@@ -0,0 +1,23 @@
+//go:build unix
+
+package command
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup запускает команду в собственной группе процессов,
+// чтобы остановить ее вместе с дочерними процессами
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup отправляет группе процессов команды SIGTERM или, если kill, SIGKILL
+func signalGroup(cmd *exec.Cmd, kill bool) error {
+	sig := syscall.SIGTERM
+	if kill {
+		sig = syscall.SIGKILL
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
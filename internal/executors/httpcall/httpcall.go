@@ -0,0 +1,582 @@
+// Package httpcall реализует тип задач "http": HTTP-запрос к внутреннему сервису
+// с повторами при ответах 5xx и, при необходимости, опросом адреса статуса
+// до завершения операции. Статус, заголовки и тело ответа сохраняются в результате задачи.
+package httpcall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/internal/schema"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeName - имя типа задач, выполняющих HTTP-запросы
+const TypeName = "http"
+
+// Коды ошибок исполнителя
+const (
+	// ErrorCodeHTTPStatus - сервис ответил статусом 4xx или 5xx
+	ErrorCodeHTTPStatus = "http_status"
+	// ErrorCodeRequestFailed - запрос не удалось выполнить (сеть, DNS, разрыв соединения)
+	ErrorCodeRequestFailed = "request_failed"
+	// ErrorCodePollFailed - адрес статуса сообщил о неудаче операции
+	ErrorCodePollFailed = "poll_failed"
+)
+
+// ErrHostNotAllowed - хост запроса или перенаправления не входит в список разрешенных
+var ErrHostNotAllowed = errors.New("host is not allowed")
+
+// sensitiveHeaders не принимаются во входе задачи, как и заголовки из Config.Headers
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// maxDetailsBody ограничивает тело ответа в подробностях ошибки
+const maxDetailsBody = 1 << 10
+
+// Config - ограничения исполнителя
+type Config struct {
+	// AllowedHosts - разрешенные хосты; "*.example.com" разрешает поддомены. Список обязателен
+	AllowedHosts []string
+	// Headers - заголовки запросов к хостам, ключи в том же формате, что AllowedHosts.
+	// Учетные данные задаются здесь: вход задачи виден всем, кто читает задачи
+	Headers map[string]map[string]string
+	// RequestTimeout ограничивает один запрос вместе с чтением тела
+	RequestTimeout time.Duration
+	// MaxResponseBytes ограничивает сохраняемое тело ответа
+	MaxResponseBytes int64
+	// Retry - повторы запроса при ответах 5xx и 429 и сетевых ошибках
+	Retry tasktypes.RetryPolicy
+	// PollInterval - интервал опроса, если задача его не задала
+	PollInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RequestTimeout:   30 * time.Second,
+		MaxResponseBytes: 1 << 20,
+		Retry:            tasktypes.RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second},
+		PollInterval:     5 * time.Second,
+	}
+}
+
+// Input - вход задачи типа http
+type Input struct {
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body - строка отправляется как есть, прочие значения - как JSON
+	Body json.RawMessage `json:"body,omitempty"`
+	Poll *Poll           `json:"poll,omitempty"`
+}
+
+// Poll описывает опрос адреса статуса после основного запроса. Без Field
+// опрос продолжается, пока адрес отвечает 202 Accepted; с Field - пока значение
+// по указателю не попадет в Done или Failed.
+type Poll struct {
+	// URL по умолчанию берется из заголовка Location ответа на основной запрос
+	URL             string            `json:"url,omitempty"`
+	IntervalSeconds float64           `json:"interval_seconds,omitempty"`
+	Field           string            `json:"field,omitempty"`
+	Done            []json.RawMessage `json:"done,omitempty"`
+	Failed          []json.RawMessage `json:"failed,omitempty"`
+}
+
+// Output - результат задачи типа http: последний полученный ответ
+type Output struct {
+	StatusCode    int         `json:"status_code"`
+	Headers       http.Header `json:"headers"`
+	Body          string      `json:"body"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+}
+
+// Executor выполняет HTTP-запросы задач
+type Executor struct {
+	cfg    Config
+	client *http.Client
+}
+
+type Option func(*Executor)
+
+// WithTransport задает транспорт HTTP-клиента
+func WithTransport(rt http.RoundTripper) Option {
+	return func(e *Executor) {
+		e.client.Transport = rt
+	}
+}
+
+func New(cfg Config, opts ...Option) (*Executor, error) {
+	if cfg.RequestTimeout < 0 || cfg.MaxResponseBytes < 0 || cfg.PollInterval < 0 {
+		return nil, errors.New("limits must not be negative")
+	}
+	if len(cfg.AllowedHosts) == 0 {
+		return nil, errors.New("allowed hosts are required")
+	}
+	for _, host := range cfg.AllowedHosts {
+		if !validHost(host) {
+			return nil, fmt.Errorf("invalid allowed host %q", host)
+		}
+	}
+	for host := range cfg.Headers {
+		if !validHost(host) {
+			return nil, fmt.Errorf("invalid headers host %q", host)
+		}
+	}
+
+	e := &Executor{cfg: cfg}
+	e.client = &http.Client{
+		// Перенаправления тоже ограничены списком разрешенных хостов,
+		// а заголовки из конфигурации заменяются заголовками нового хоста
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if err := e.checkURL(req.URL); err != nil {
+				return err
+			}
+			for _, headers := range e.cfg.Headers {
+				for name := range headers {
+					req.Header.Del(name)
+				}
+			}
+			e.setHostHeaders(req)
+			return nil
+		},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// TaskType описывает тип http со схемами входа и результата
+func (e *Executor) TaskType() tasktypes.TaskType {
+	return tasktypes.TaskType{
+		Name:        TypeName,
+		Description: "Performs an HTTP request and optionally polls a status URL until the operation is done",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"required": ["url"],
+			"properties": {
+				"method": {"enum": ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"]},
+				"url": {"type": "string", "pattern": "^https?://"},
+				"headers": {"type": "object", "additionalProperties": {"type": "string"}},
+				"body": {},
+				"poll": {
+					"type": "object",
+					"properties": {
+						"url": {"type": "string", "pattern": "^https?://"},
+						"interval_seconds": {"type": "number", "exclusiveMinimum": 0},
+						"field": {"type": "string", "pattern": "^(/.*)?$"},
+						"done": {"type": "array"},
+						"failed": {"type": "array"}
+					},
+					"additionalProperties": false
+				}
+			},
+			"additionalProperties": false
+		}`),
+		CheckInput: e.checkInput,
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"required": ["status_code", "headers", "body"],
+			"properties": {
+				"status_code": {"type": "integer"},
+				"headers": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}},
+				"body": {"type": "string"},
+				"body_truncated": {"type": "boolean"}
+			}
+		}`),
+		Executor: e,
+	}
+}
+
+// request - подготовленный запрос, который можно отправлять повторно
+type request struct {
+	method  string
+	url     *url.URL
+	headers http.Header
+	body    []byte
+}
+
+func (r request) String() string {
+	return r.method + " " + r.url.Redacted()
+}
+
+// response - ответ с телом, прочитанным не больше лимита
+type response struct {
+	status    int
+	headers   http.Header
+	body      []byte
+	truncated bool
+}
+
+func (e *Executor) Execute(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+	var in Input
+	if err := json.Unmarshal(task.Input, &in); err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+	req, err := e.newRequest(in)
+	if err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+
+	log := tasklogs.Writer(ctx, tasklogs.System)
+	defer log.Close()
+
+	resp, err := e.send(ctx, log, req)
+	if err == nil && in.Poll != nil {
+		resp, err = e.poll(ctx, log, in, req, resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Output{
+		StatusCode:    resp.status,
+		Headers:       resp.headers,
+		Body:          string(resp.body),
+		BodyTruncated: resp.truncated,
+	})
+}
+
+func (e *Executor) newRequest(in Input) (request, error) {
+	req := request{method: in.Method, headers: make(http.Header)}
+	if req.method == "" {
+		req.method = http.MethodGet
+	}
+	var err error
+	if req.url, err = e.parseURL(in.URL); err != nil {
+		return req, err
+	}
+	for name, value := range in.Headers {
+		if e.sensitive(name) {
+			return req, fmt.Errorf("header %q must be configured for the host, not passed in task input", name)
+		}
+		req.headers.Set(name, value)
+	}
+
+	if len(in.Body) > 0 && string(in.Body) != "null" {
+		var text string
+		if json.Unmarshal(in.Body, &text) == nil {
+			req.body = []byte(text)
+		} else {
+			req.body = in.Body
+			if req.headers.Get("Content-Type") == "" {
+				req.headers.Set("Content-Type", "application/json")
+			}
+		}
+	}
+	return req, nil
+}
+
+func (e *Executor) parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url scheme must be http or https")
+	}
+	return u, e.checkURL(u)
+}
+
+// checkURL проверяет хост по списку разрешенных
+func (e *Executor) checkURL(u *url.URL) error {
+	for _, allowed := range e.cfg.AllowedHosts {
+		if matchHost(allowed, u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostNotAllowed, strings.ToLower(u.Hostname()))
+}
+
+// checkInput отклоняет учетные данные во входе задачи: вход возвращается API
+func (e *Executor) checkInput(input json.RawMessage) []schema.Error {
+	var in Input
+	if json.Unmarshal(input, &in) != nil {
+		return nil
+	}
+	var errs []schema.Error
+	for name := range in.Headers {
+		if e.sensitive(name) {
+			errs = append(errs, schema.Error{
+				Pointer: "/headers/" + pointerEscaper.Replace(name),
+				Message: "must be configured for the host, not passed in task input",
+			})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Pointer < errs[j].Pointer })
+	return errs
+}
+
+// sensitive сообщает, что заголовок нельзя передавать во входе задачи
+func (e *Executor) sensitive(name string) bool {
+	for _, header := range sensitiveHeaders {
+		if strings.EqualFold(name, header) {
+			return true
+		}
+	}
+	for _, headers := range e.cfg.Headers {
+		for header := range headers {
+			if strings.EqualFold(name, header) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setHostHeaders добавляет заголовки из конфигурации для хоста запроса;
+// заголовки точного хоста важнее заголовков шаблона "*."
+func (e *Executor) setHostHeaders(req *http.Request) {
+	for _, exact := range []bool{false, true} {
+		for pattern, headers := range e.cfg.Headers {
+			if strings.HasPrefix(pattern, "*.") == exact || !matchHost(pattern, req.URL.Hostname()) {
+				continue
+			}
+			for name, value := range headers {
+				req.Header.Set(name, value)
+			}
+		}
+	}
+}
+
+// matchHost сравнивает хост с шаблоном вида "example.com" или "*.example.com"
+func matchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	return host == pattern || strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])
+}
+
+func validHost(host string) bool {
+	return strings.TrimPrefix(host, "*.") != "" && !strings.ContainsAny(host, "/:")
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// send выполняет запрос, повторяя его при ответах 5xx и 429 и сетевых ошибках.
+// Ответы 4xx и 5xx после исчерпания повторов возвращаются как ошибка.
+func (e *Executor) send(ctx context.Context, log io.Writer, req request) (*response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := e.do(ctx, req)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		retryable := !errors.Is(err, ErrHostNotAllowed)
+		if err == nil {
+			retryable = resp.status >= 500 || resp.status == http.StatusTooManyRequests
+		}
+		if retryable && attempt < e.cfg.Retry.MaxAttempts {
+			// Delay уже ограничена MaxBackoff; Retry-After сервиса важнее этого
+			// ограничения, а общее время задачи ограничивает таймаут типа
+			delay := e.cfg.Retry.Delay(attempt)
+			if resp != nil {
+				delay = max(delay, retryAfter(resp.headers))
+			}
+			fmt.Fprintf(log, "%s: %s, retrying in %s\n", req, outcome(resp, err), delay)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fmt.Fprintf(log, "%s: %s\n", req, outcome(resp, err))
+		switch {
+		case errors.Is(err, ErrHostNotAllowed):
+			return nil, tasktypes.Permanent(ErrorCodeRequestFailed, err)
+		case err != nil:
+			code := ErrorCodeRequestFailed
+			if errors.Is(err, context.DeadlineExceeded) {
+				code = models.ErrorCodeTimeout
+			}
+			return nil, tasktypes.Retryable(code, err)
+		case resp.status >= 400:
+			return nil, &tasktypes.Error{
+				Code:      ErrorCodeHTTPStatus,
+				Message:   fmt.Sprintf("%s returned %d %s", req, resp.status, http.StatusText(resp.status)),
+				Retryable: retryable,
+				Details:   map[string]interface{}{"status_code": resp.status, "body": snippet(resp.body)},
+			}
+		}
+		return resp, nil
+	}
+}
+
+func (e *Executor) do(ctx context.Context, req request) (*response, error) {
+	if e.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.RequestTimeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = req.headers.Clone()
+	e.setHostHeaders(httpReq)
+
+	httpResp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	reader := io.Reader(httpResp.Body)
+	if e.cfg.MaxResponseBytes > 0 {
+		reader = io.LimitReader(reader, e.cfg.MaxResponseBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	resp := &response{status: httpResp.StatusCode, headers: httpResp.Header, body: data}
+	if e.cfg.MaxResponseBytes > 0 && int64(len(data)) > e.cfg.MaxResponseBytes {
+		resp.body, resp.truncated = data[:e.cfg.MaxResponseBytes], true
+	}
+	return resp, nil
+}
+
+// poll опрашивает адрес статуса, пока операция не завершится
+func (e *Executor) poll(ctx context.Context, log io.Writer, in Input, req request, resp *response) (*response, error) {
+	target := in.Poll.URL
+	if target == "" {
+		location := resp.headers.Get("Location")
+		if location == "" {
+			return nil, tasktypes.Permanent(ErrorCodePollFailed, errors.New("no poll url and no Location header in the response"))
+		}
+		ref, err := req.url.Parse(location)
+		if err != nil {
+			return nil, tasktypes.Permanent(ErrorCodePollFailed, fmt.Errorf("invalid Location header: %w", err))
+		}
+		target = ref.String()
+	}
+	statusURL, err := e.parseURL(target)
+	if err != nil {
+		return nil, tasktypes.InvalidInput(err)
+	}
+
+	interval := e.cfg.PollInterval
+	if in.Poll.IntervalSeconds > 0 {
+		interval = time.Duration(in.Poll.IntervalSeconds * float64(time.Second))
+	}
+	status := request{method: http.MethodGet, url: statusURL, headers: req.headers.Clone()}
+	status.headers.Del("Content-Type")
+
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+		if resp, err = e.send(ctx, log, status); err != nil {
+			return nil, err
+		}
+
+		if in.Poll.Field == "" {
+			if resp.status != http.StatusAccepted {
+				return resp, nil
+			}
+			continue
+		}
+		var doc interface{}
+		if err := json.Unmarshal(resp.body, &doc); err != nil {
+			return nil, tasktypes.Permanent(ErrorCodePollFailed, fmt.Errorf("status response is not JSON: %w", err))
+		}
+		value, ok := lookup(doc, in.Poll.Field)
+		switch {
+		case ok && oneOf(value, in.Poll.Done):
+			return resp, nil
+		case ok && oneOf(value, in.Poll.Failed):
+			return nil, &tasktypes.Error{
+				Code:    ErrorCodePollFailed,
+				Message: fmt.Sprintf("operation failed: %s is %v", in.Poll.Field, value),
+				Details: map[string]interface{}{"field": in.Poll.Field, "value": value, "body": snippet(resp.body)},
+			}
+		}
+	}
+}
+
+// lookup возвращает значение документа по JSON Pointer
+func lookup(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func oneOf(value interface{}, candidates []json.RawMessage) bool {
+	for _, raw := range candidates {
+		var candidate interface{}
+		if json.Unmarshal(raw, &candidate) == nil && reflect.DeepEqual(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func snippet(body []byte) string {
+	if len(body) > maxDetailsBody {
+		body = body[:maxDetailsBody]
+	}
+	return string(body)
+}
+
+func outcome(resp *response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return strconv.Itoa(resp.status) + " " + http.StatusText(resp.status)
+}
+
+// retryAfter разбирает заголовок Retry-After в секундах
+func retryAfter(headers http.Header) time.Duration {
+	seconds, err := strconv.Atoi(headers.Get("Retry-After"))
+	if err != nil || seconds < 0 || seconds > math.MaxInt64/int(time.Second) {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
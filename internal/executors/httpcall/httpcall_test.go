@@ -0,0 +1,276 @@
+package httpcall
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.AllowedHosts = []string{"127.0.0.1"}
+	cfg.Retry = tasktypes.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	cfg.PollInterval = time.Millisecond
+	return cfg
+}
+
+func newExecutor(t *testing.T, cfg Config) *Executor {
+	t.Helper()
+	e, err := New(cfg)
+	require.NoError(t, err)
+	return e
+}
+
+func run(e *Executor, store *tasklogs.Store, input string) (Output, error) {
+	task := &models.Task{ID: "t1", Type: TypeName, Input: json.RawMessage(input)}
+	result, err := e.Execute(tasklogs.WithTask(context.Background(), store, task.ID), task)
+	var out Output
+	if err == nil {
+		json.Unmarshal(result, &out)
+	}
+	return out, err
+}
+
+func TestTaskType(t *testing.T) {
+	e := newExecutor(t, testConfig())
+	registry := tasktypes.NewRegistry()
+	require.NoError(t, registry.Register(e.TaskType()))
+
+	taskType, _ := registry.Get(TypeName)
+	assert.Empty(t, taskType.ValidateInput(json.RawMessage(`{"url": "http://svc/run", "method": "POST", "body": {"a": 1}, "poll": {"field": "/state", "done": ["ok"]}}`)))
+	assert.NotEmpty(t, taskType.ValidateInput(json.RawMessage(`{"url": "ftp://svc/run"}`)))
+	assert.NotEmpty(t, taskType.ValidateInput(json.RawMessage(`{"url": "http://svc", "method": "TRACE"}`)))
+
+	_, err := New(Config{AllowedHosts: []string{"svc:8080"}})
+	assert.ErrorContains(t, err, "invalid allowed host")
+	_, err = New(Config{})
+	assert.ErrorContains(t, err, "allowed hosts are required")
+}
+
+func TestExecute(t *testing.T) {
+	t.Run("Request and result", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Echo", r.Header.Get("X-Token"))
+			fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+		}))
+		defer server.Close()
+
+		store := tasklogs.NewStore(0)
+		out, err := run(newExecutor(t, testConfig()), store,
+			`{"method": "POST", "url": "`+server.URL+`/run", "headers": {"X-Token": "abc"}, "body": {"n": 1}}`)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, out.StatusCode)
+		assert.Equal(t, `POST application/json {"n": 1}`, out.Body)
+		assert.Equal(t, "abc", out.Headers.Get("X-Echo"))
+		assert.Equal(t, "POST "+server.URL+"/run: 200 OK", store.Entries("t1")[0].Line)
+
+		out, err = run(newExecutor(t, testConfig()), store, `{"method": "PUT", "url": "`+server.URL+`", "body": "plain"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "PUT  plain", out.Body)
+	})
+
+	t.Run("Retries on 5xx", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, "ok")
+		}))
+		defer server.Close()
+
+		store := tasklogs.NewStore(0)
+		out, err := run(newExecutor(t, testConfig()), store, `{"url": "`+server.URL+`"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", out.Body)
+		assert.Equal(t, int32(3), calls.Load())
+		assert.Contains(t, store.Entries("t1")[0].Line, "503 Service Unavailable, retrying in 1ms")
+
+		calls.Store(-10)
+		_, err = run(newExecutor(t, testConfig()), store, `{"url": "`+server.URL+`"}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeHTTPStatus, failure.Code)
+		assert.True(t, failure.Retryable)
+		assert.Equal(t, http.StatusServiceUnavailable, failure.Details["status_code"])
+		assert.Equal(t, int32(-7), calls.Load())
+	})
+
+	t.Run("Retry-After is not clamped by max backoff", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			io.WriteString(w, "ok")
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.Retry.MaxBackoff = time.Millisecond
+		store := tasklogs.NewStore(0)
+		start := time.Now()
+		_, err := run(newExecutor(t, cfg), store, `{"url": "`+server.URL+`"}`)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+		assert.Contains(t, store.Entries("t1")[0].Line, "429 Too Many Requests, retrying in 1s")
+	})
+
+	t.Run("4xx is not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Error(w, "bad input", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := run(newExecutor(t, testConfig()), tasklogs.NewStore(0), `{"url": "`+server.URL+`"}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeHTTPStatus, failure.Code)
+		assert.False(t, failure.Retryable)
+		assert.Equal(t, "bad input\n", failure.Details["body"])
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Poll Location until not 202", func(t *testing.T) {
+		var polls atomic.Int32
+		mux := http.NewServeMux()
+		mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/jobs/1")
+			w.WriteHeader(http.StatusAccepted)
+		})
+		mux.HandleFunc("/jobs/1", func(w http.ResponseWriter, r *http.Request) {
+			if polls.Add(1) < 3 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			io.WriteString(w, "finished")
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		out, err := run(newExecutor(t, testConfig()), tasklogs.NewStore(0), `{"method": "POST", "url": "`+server.URL+`/jobs", "poll": {}}`)
+		require.NoError(t, err)
+		assert.Equal(t, "finished", out.Body)
+		assert.Equal(t, int32(3), polls.Load())
+	})
+
+	t.Run("Poll field", func(t *testing.T) {
+		var state atomic.Value
+		state.Store("running")
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"job": {"state": %q}}`, state.Load())
+		}))
+		defer server.Close()
+
+		time.AfterFunc(20*time.Millisecond, func() { state.Store("done") })
+		input := `{"url": "` + server.URL + `", "poll": {"url": "` + server.URL + `/status", "field": "/job/state", "done": ["done"], "failed": ["error"]}}`
+		out, err := run(newExecutor(t, testConfig()), tasklogs.NewStore(0), input)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"job": {"state": "done"}}`, out.Body)
+
+		state.Store("error")
+		_, err = run(newExecutor(t, testConfig()), tasklogs.NewStore(0), input)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodePollFailed, failure.Code)
+		assert.Equal(t, "operation failed: /job/state is error", failure.Message)
+		assert.False(t, failure.Retryable)
+	})
+
+	t.Run("Response size limit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, strings.Repeat("x", 100))
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.MaxResponseBytes = 10
+		out, err := run(newExecutor(t, cfg), tasklogs.NewStore(0), `{"url": "`+server.URL+`"}`)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat("x", 10), out.Body)
+		assert.True(t, out.BodyTruncated)
+	})
+
+	t.Run("Allowed hosts", func(t *testing.T) {
+		server := httptest.NewServer(http.RedirectHandler("http://example.com/", http.StatusFound))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.AllowedHosts = []string{"127.0.0.1", "*.internal"}
+		e := newExecutor(t, cfg)
+
+		_, err := run(e, tasklogs.NewStore(0), `{"url": "http://example.com"}`)
+		assert.Equal(t, models.ErrorCodeInvalidInput, tasktypes.Describe(err).Code)
+		assert.NoError(t, e.checkURL(mustParse(t, "http://billing.internal:8080/")))
+
+		_, err = run(e, tasklogs.NewStore(0), `{"url": "`+server.URL+`"}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, ErrorCodeRequestFailed, failure.Code)
+		assert.False(t, failure.Retryable)
+		assert.ErrorIs(t, err, ErrHostNotAllowed)
+	})
+
+	t.Run("Credentials come from configuration", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s|%s", r.Header.Get("Authorization"), r.Header.Get("X-Api-Key"))
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.Headers = map[string]map[string]string{"127.0.0.1": {"Authorization": "Bearer secret", "X-Api-Key": "key"}}
+		e := newExecutor(t, cfg)
+		out, err := run(e, tasklogs.NewStore(0), `{"url": "`+server.URL+`"}`)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer secret|key", out.Body)
+
+		registry := tasktypes.NewRegistry()
+		require.NoError(t, registry.Register(e.TaskType()))
+		taskType, _ := registry.Get(TypeName)
+		errs := taskType.ValidateInput(json.RawMessage(`{"url": "http://svc", "headers": {"authorization": "Bearer x", "x-api-key": "k", "X-Trace": "1"}}`))
+		require.Len(t, errs, 2)
+		assert.Equal(t, "/headers/authorization", errs[0].Pointer)
+		assert.Equal(t, "/headers/x-api-key", errs[1].Pointer)
+
+		// Задачи, созданные до ограничения, тоже не отправляют учетные данные из входа
+		_, err = run(e, tasklogs.NewStore(0), `{"url": "`+server.URL+`", "headers": {"Cookie": "a=b"}}`)
+		assert.Equal(t, models.ErrorCodeInvalidInput, tasktypes.Describe(err).Code)
+	})
+
+	t.Run("Request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		cfg := testConfig()
+		cfg.RequestTimeout = 20 * time.Millisecond
+		cfg.Retry.MaxAttempts = 1
+		_, err := run(newExecutor(t, cfg), tasklogs.NewStore(0), `{"url": "`+server.URL+`"}`)
+		failure := tasktypes.Describe(err)
+		assert.Equal(t, models.ErrorCodeTimeout, failure.Code)
+		assert.True(t, failure.Retryable)
+	})
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"http_api/internal/services"
+	"http_api/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag - сильный валидатор задачи, построенный по ее версии
+func etag(task *models.Task) string {
+	return `"` + strconv.FormatInt(task.Version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, task *models.Task) {
+	w.Header().Set("ETag", etag(task))
+}
+
+// withIfMatch переносит заголовок If-Match в условие сервиса, которое проверяется
+// атомарно с изменением задачи. Слабые теги (W/) по RFC 9110 никогда не совпадают.
+func withIfMatch(r *http.Request) *http.Request {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return r
+	}
+	tags := parseETags(header)
+	precondition := func(version int64) bool {
+		current := `"` + strconv.FormatInt(version, 10) + `"`
+		for _, tag := range tags {
+			if tag == "*" || tag == current {
+				return true
+			}
+		}
+		return false
+	}
+	return r.WithContext(services.WithPrecondition(r.Context(), precondition))
+}
+
+// notModified проверяет If-None-Match слабым сравнением (RFC 9110, 13.1.2)
+func notModified(r *http.Request, task *models.Task) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(task)
+	for _, tag := range parseETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
+
+func parseETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
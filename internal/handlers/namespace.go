@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"http_api/internal/auth"
+	"http_api/internal/services"
+	"http_api/models"
+	"net/http"
+	"regexp"
+)
+
+// NamespaceHeader выбирает пространство имен, если оно не задано субъектом
+const NamespaceHeader = "X-Namespace"
+
+// Имена как у меток DNS: строчные буквы, цифры и дефис, до 63 символов
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// withNamespace определяет пространство имен запроса и кладет его в context.
+// Субъект с собственным пространством имен не может выйти за его пределы;
+// администратор выбирает любое через X-Namespace, а список всех задач
+// получает через ?all_namespaces=true.
+func (h *TaskHandler) withNamespace(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	requested := r.Header.Get(NamespaceHeader)
+	if requested != "" && !namespacePattern.MatchString(requested) {
+		http.Error(w, "Invalid namespace", http.StatusBadRequest)
+		return nil, false
+	}
+
+	principal, authenticated := auth.FromContext(r.Context())
+	if h.requireScopes && !authenticated {
+		// Ответ 401 вернет authorize
+		return r, true
+	}
+	admin := !h.requireScopes || (authenticated && principal.HasScope(auth.ScopeAdmin))
+
+	namespace := requested
+	if authenticated && !admin {
+		own := principal.Namespace
+		if own == "" {
+			own = models.DefaultNamespace
+		}
+		if requested != "" && requested != own {
+			http.Error(w, "Access to namespace "+requested+" is forbidden", http.StatusForbidden)
+			return nil, false
+		}
+		namespace = own
+	} else if namespace == "" && authenticated {
+		namespace = principal.Namespace
+	}
+
+	if r.URL.Query().Get("all_namespaces") == "true" {
+		if !admin {
+			http.Error(w, "Listing all namespaces requires scope "+string(auth.ScopeAdmin), http.StatusForbidden)
+			return nil, false
+		}
+		namespace = services.AllNamespaces
+	}
+
+	return r.WithContext(services.WithNamespace(r.Context(), namespace)), true
+}
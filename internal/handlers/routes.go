@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Классы маршрутов для ограничения частоты запросов
+const (
+	ClassRead   = "read"
+	ClassCreate = "create"
+	ClassWrite  = "write"
+	ClassDelete = "delete"
+)
+
+// RouteName возвращает шаблон маршрута API без конкретных ID,
+// пригодный для меток метрик и логов. Для чужих путей возвращает "".
+func RouteName(path string) string {
+	if path == "/tasks" || path == "/quotas" || path == "/task-types" {
+		return path
+	}
+	if strings.HasPrefix(path, "/task-types/") {
+		if strings.HasSuffix(path, "/stats") {
+			return "/task-types/{type}/stats"
+		}
+		return "/task-types/{type}"
+	}
+	if !strings.HasPrefix(path, "/tasks/") {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/tasks/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "cancel":
+		return "/tasks/cancel"
+	case len(parts) == 1:
+		return "/tasks/{id}"
+	case len(parts) == 2 && parts[1] == "cancel":
+		return "/tasks/{id}/cancel"
+	case len(parts) == 2 && parts[1] == "logs":
+		return "/tasks/{id}/logs"
+	case len(parts) == 2 && parts[1] == "artifacts":
+		return "/tasks/{id}/artifacts"
+	case len(parts) == 3 && parts[1] == "artifacts":
+		return "/tasks/{id}/artifacts/{name}"
+	}
+	return "/tasks/other"
+}
+
+// RouteClass относит запрос к классу: чтение, создание задачи,
+// изменение (PUT, PATCH, отмена) или удаление
+func RouteClass(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return ClassRead
+	case r.Method == http.MethodDelete:
+		return ClassDelete
+	case r.Method == http.MethodPost && r.URL.Path == "/tasks":
+		return ClassCreate
+	}
+	return ClassWrite
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"http_api/internal/artifacts"
+	"http_api/internal/storage"
+	"http_api/models"
+	"net/http"
+	"strings"
+)
+
+// taskArtifacts отдает список артефактов задачи (GET /tasks/{id}/artifacts)
+func (h *TaskHandler) taskArtifacts(w http.ResponseWriter, r *http.Request, id string) {
+	list, err := h.service.Artifacts(r.Context(), id)
+	if err != nil {
+		artifactError(w, r, err)
+		return
+	}
+	if list == nil {
+		list = []models.Artifact{}
+	}
+	respondWithJSON(w, http.StatusOK, models.ArtifactList{Artifacts: list, Total: len(list)})
+}
+
+// downloadArtifact отдает содержимое артефакта (GET /tasks/{id}/artifacts/{name}).
+// Поддерживаются Range и условные запросы; ETag строится по контрольной сумме.
+func (h *TaskHandler) downloadArtifact(w http.ResponseWriter, r *http.Request, id, name string) {
+	file, artifact, err := h.service.OpenArtifact(r.Context(), id, name)
+	if err != nil {
+		artifactError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+artifact.Name+`"`)
+	w.Header().Set("ETag", `"`+strings.TrimPrefix(artifact.Checksum, "sha256:")+`"`)
+	http.ServeContent(w, r, artifact.Name, artifact.CreatedAt, file)
+}
+
+func artifactError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTaskNotFound):
+		http.Error(w, "Task not found", http.StatusNotFound)
+	case errors.Is(err, artifacts.ErrNotFound):
+		http.Error(w, "Artifact not found", http.StatusNotFound)
+	default:
+		internalError(w, r, err)
+	}
+}
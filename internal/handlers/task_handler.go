@@ -1,30 +1,85 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"http_api/internal/models"
+	"http_api/internal/auth"
+	"http_api/internal/labels"
 	"http_api/internal/services"
 	"http_api/internal/storage"
+	"http_api/internal/validation"
+	"http_api/models"
+	"log/slog"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+
+	mergePatchType = "application/merge-patch+json"
 )
 
 type TaskHandler struct {
-	service *services.TaskService
+	service         *services.TaskService
+	defaultPageSize int
+	maxPageSize     int
+	requireScopes   bool
 }
 
-func NewTaskHandler(service *services.TaskService) *TaskHandler {
-	return &TaskHandler{service: service}
+type Option func(*TaskHandler)
+
+// WithPageSize задает размер страницы по умолчанию и максимально допустимый
+func WithPageSize(defaultSize, maxSize int) Option {
+	return func(h *TaskHandler) {
+		h.defaultPageSize = defaultSize
+		h.maxPageSize = maxSize
+	}
+}
+
+// RequireScopes включает проверку прав субъекта, установленного auth.Middleware
+func RequireScopes() Option {
+	return func(h *TaskHandler) {
+		h.requireScopes = true
+	}
+}
+
+func NewTaskHandler(service *services.TaskService, opts ...Option) *TaskHandler {
+	h := &TaskHandler{
+		service:         service,
+		defaultPageSize: defaultPageSize,
+		maxPageSize:     maxPageSize,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *TaskHandler) HandleTasks(w http.ResponseWriter, r *http.Request) {
+	r, ok := h.withNamespace(w, r)
+	if !ok {
+		return
+	}
+
 	switch r.Method {
 	case http.MethodPost:
-		h.createTask(w, r)
+		if h.authorize(w, r, auth.ScopeWrite) {
+			h.createTask(w, r)
+		}
 	case http.MethodGet:
-		h.listTasks(w, r)
+		if h.authorize(w, r, auth.ScopeRead) {
+			h.listTasks(w, r)
+		}
+	case http.MethodDelete:
+		if h.authorize(w, r, auth.ScopeDelete) {
+			h.bulk(w, r, h.service.DeleteTasks)
+		}
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -38,16 +93,67 @@ func (h *TaskHandler) HandleTaskByID(w http.ResponseWriter, r *http.Request) {
 	}
 	id := parts[2]
 
+	r, ok := h.withNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	// Массовая отмена по селектору: POST /tasks/cancel?selector=...
+	if r.URL.Path == "/tasks/cancel" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		} else if h.authorize(w, r, auth.ScopeCancel) {
+			h.bulk(w, r, h.service.CancelTasks)
+		}
+		return
+	}
+
+	if len(parts) == 4 && parts[3] == "logs" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		} else if h.authorize(w, r, auth.ScopeRead) {
+			h.taskLogs(w, r, id)
+		}
+		return
+	}
+
+	if len(parts) >= 4 && parts[3] == "artifacts" {
+		switch {
+		case len(parts) > 5:
+			http.Error(w, "Not found", http.StatusNotFound)
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		case !h.authorize(w, r, auth.ScopeRead):
+		case len(parts) == 5:
+			h.downloadArtifact(w, r, id, parts[4])
+		default:
+			h.taskArtifacts(w, r, id)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		h.getTask(w, r, id)
+		if h.authorize(w, r, auth.ScopeRead) {
+			h.getTask(w, r, id)
+		}
 	case http.MethodPut:
-		h.updateTask(w, r, id)
+		if h.authorize(w, r, auth.ScopeWrite) {
+			h.updateTask(w, withIfMatch(r), id)
+		}
+	case http.MethodPatch:
+		if h.authorize(w, r, auth.ScopeWrite) {
+			h.patchTask(w, withIfMatch(r), id)
+		}
 	case http.MethodDelete:
-		h.deleteTask(w, r, id)
+		if h.authorize(w, r, auth.ScopeDelete) {
+			h.deleteTask(w, withIfMatch(r), id)
+		}
 	case http.MethodPost:
 		if strings.HasSuffix(r.URL.Path, "/cancel") {
-			h.cancelTask(w, r, id)
+			if h.authorize(w, r, auth.ScopeCancel) {
+				h.cancelTask(w, withIfMatch(r), id)
+			}
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -56,22 +162,52 @@ func (h *TaskHandler) HandleTaskByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		Description string `json:"description"`
+// authorize отвечает 401/403 и возвращает false, если у субъекта нет права scope
+func (h *TaskHandler) authorize(w http.ResponseWriter, r *http.Request, scope auth.Scope) bool {
+	if !h.requireScopes {
+		return true
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Missing credentials", http.StatusUnauthorized)
+		return false
+	}
+	if !principal.HasScope(scope) {
+		http.Error(w, "Missing scope "+string(scope), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
+	var request models.TaskCreate
+	if err := h.service.ValidationRules().DecodeCreate(r.Body, &request); err != nil {
+		decodeError(w, err)
 		return
 	}
 
-	task, err := h.service.CreateTask(r.Context(), request.Description)
+	task, err := h.service.CreateTask(r.Context(), request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		var quotaErr *services.QuotaError
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			respondWithJSON(w, http.StatusBadRequest, validationErr)
+		} else if errors.As(err, &quotaErr) {
+			w.Header().Set("Retry-After", retryAfter(quotaErr.RetryAfter))
+			http.Error(w, quotaErr.Error(), http.StatusTooManyRequests)
+		} else if errors.Is(err, services.ErrQueueFull) {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Task queue is full", http.StatusServiceUnavailable)
+		} else if errors.Is(err, services.ErrShuttingDown) {
+			http.Error(w, "Service is shutting down", http.StatusServiceUnavailable)
+		} else {
+			internalError(w, r, err)
+		}
 		return
 	}
 
+	setETag(w, task)
 	respondWithJSON(w, http.StatusCreated, task)
 }
 
@@ -81,11 +217,16 @@ func (h *TaskHandler) getTask(w http.ResponseWriter, r *http.Request, id string)
 		if errors.Is(err, storage.ErrTaskNotFound) {
 			http.Error(w, "Task not found", http.StatusNotFound)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalError(w, r, err)
 		}
 		return
 	}
 
+	setETag(w, task)
+	if notModified(r, task) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	respondWithJSON(w, http.StatusOK, task)
 }
 
@@ -97,13 +238,18 @@ func (h *TaskHandler) listTasks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if pageSize < 1 || pageSize > h.maxPageSize {
+		pageSize = h.defaultPageSize
 	}
 
-	tasks, err := h.service.ListTasks(r.Context())
+	selector, ok := parseSelector(w, r)
+	if !ok {
+		return
+	}
+
+	tasks, err := h.service.ListTasks(r.Context(), selector)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalError(w, r, err)
 		return
 	}
 
@@ -125,23 +271,54 @@ func (h *TaskHandler) listTasks(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, paginatedTasks)
 }
 
+// updateTask заменяет изменяемые поля задачи целиком (PUT)
 func (h *TaskHandler) updateTask(w http.ResponseWriter, r *http.Request, id string) {
 	var update models.TaskUpdate
-	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := h.service.ValidationRules().DecodeUpdate(r.Body, &update); err != nil {
+		decodeError(w, err)
 		return
 	}
 
 	task, err := h.service.UpdateTask(r.Context(), id, update)
+	h.respondWithUpdate(w, r, task, err)
+}
+
+// patchTask применяет JSON Merge Patch к изменяемым полям задачи (PATCH)
+func (h *TaskHandler) patchTask(w http.ResponseWriter, r *http.Request, id string) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != mergePatchType {
+		w.Header().Set("Accept-Patch", mergePatchType)
+		http.Error(w, "Unsupported media type, expected "+mergePatchType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := h.service.ValidationRules().DecodePatch(r.Body)
+	if err != nil {
+		decodeError(w, err)
+		return
+	}
+
+	task, err := h.service.PatchTask(r.Context(), id, patch)
+	h.respondWithUpdate(w, r, task, err)
+}
+
+func (h *TaskHandler) respondWithUpdate(w http.ResponseWriter, r *http.Request, task *models.Task, err error) {
 	if err != nil {
+		var validationErr *validation.Error
 		if errors.Is(err, storage.ErrTaskNotFound) {
 			http.Error(w, "Task not found", http.StatusNotFound)
+		} else if errors.Is(err, services.ErrPreconditionFailed) {
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		} else if errors.As(err, &validationErr) {
+			respondWithJSON(w, http.StatusBadRequest, validationErr)
+		} else if errors.Is(err, storage.ErrInvalidState) {
+			http.Error(w, "Priority can only be changed while task is pending", http.StatusConflict)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalError(w, r, err)
 		}
 		return
 	}
 
+	setETag(w, task)
 	respondWithJSON(w, http.StatusOK, task)
 }
 
@@ -150,14 +327,17 @@ func (h *TaskHandler) cancelTask(w http.ResponseWriter, r *http.Request, id stri
 	if err != nil {
 		if errors.Is(err, storage.ErrTaskNotFound) {
 			http.Error(w, "Task not found", http.StatusNotFound)
+		} else if errors.Is(err, services.ErrPreconditionFailed) {
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
 		} else if errors.Is(err, storage.ErrInvalidState) {
 			http.Error(w, "Task cannot be cancelled in its current state", http.StatusBadRequest)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalError(w, r, err)
 		}
 		return
 	}
 
+	setETag(w, task)
 	respondWithJSON(w, http.StatusOK, task)
 }
 
@@ -165,8 +345,10 @@ func (h *TaskHandler) deleteTask(w http.ResponseWriter, r *http.Request, id stri
 	if err := h.service.DeleteTask(r.Context(), id); err != nil {
 		if errors.Is(err, storage.ErrTaskNotFound) {
 			http.Error(w, "Task not found", http.StatusNotFound)
+		} else if errors.Is(err, services.ErrPreconditionFailed) {
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalError(w, r, err)
 		}
 		return
 	}
@@ -174,8 +356,87 @@ func (h *TaskHandler) deleteTask(w http.ResponseWriter, r *http.Request, id stri
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// bulk применяет массовую операцию к задачам, выбранным параметром selector
+func (h *TaskHandler) bulk(w http.ResponseWriter, r *http.Request, op func(context.Context, labels.Selector) (*models.BulkResult, error)) {
+	selector, ok := parseSelector(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := op(r.Context(), selector)
+	if err != nil {
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			respondWithJSON(w, http.StatusBadRequest, validationErr)
+		} else {
+			internalError(w, r, err)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// parseSelector разбирает селектор меток из параметра selector
+func parseSelector(w http.ResponseWriter, r *http.Request) (labels.Selector, bool) {
+	selector, err := labels.Parse(r.URL.Query().Get("selector"))
+	if err != nil {
+		respondWithJSON(w, http.StatusBadRequest, &validation.Error{Errors: []validation.FieldError{
+			{Parameter: "selector", Message: err.Error()},
+		}})
+		return nil, false
+	}
+	return selector, true
+}
+
+// decodeError отвечает на ошибку разбора тела запроса
+func decodeError(w http.ResponseWriter, err error) {
+	var validationErr *validation.Error
+	switch {
+	case errors.Is(err, validation.ErrBodyTooLarge):
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+	case errors.As(err, &validationErr):
+		respondWithJSON(w, http.StatusBadRequest, validationErr)
+	default:
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	}
+}
+
+// HandleQuotas отдает загрузку квот пространства имен запроса
+// (всех пространств - администратору с ?all_namespaces=true)
+func (h *TaskHandler) HandleQuotas(w http.ResponseWriter, r *http.Request) {
+	r, ok := h.withNamespace(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeRead) {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.service.Quotas(r.Context()))
+}
+
+// retryAfter округляет задержку вверх до целых секунд, но не меньше одной
+func retryAfter(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
 func respondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 }
+
+// internalError логирует непредвиденную ошибку вместе с request_id и отвечает 500
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -2,12 +2,20 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"http_api/internal/models"
+	"http_api/internal/artifacts"
+	"http_api/internal/auth"
 	"http_api/internal/services"
 	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/internal/validation"
+	"http_api/models"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -176,3 +184,581 @@ func TestTaskHandlers(t *testing.T) {
 		}
 	})
 }
+
+func TestTaskHandlerScopes(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0))
+	handler := NewTaskHandler(service, RequireScopes())
+	store.Create(&models.Task{ID: "t1", Status: models.StatusPending})
+
+	reader := &auth.Principal{ID: "reader", Scopes: []auth.Scope{auth.ScopeRead}}
+	admin := &auth.Principal{ID: "admin", Scopes: []auth.Scope{auth.ScopeAdmin}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		url       string
+		want      int
+	}{
+		{"No principal", nil, "GET", "/tasks/t1", http.StatusUnauthorized},
+		{"Read allowed", reader, "GET", "/tasks/t1", http.StatusOK},
+		{"List allowed", reader, "GET", "/tasks", http.StatusOK},
+		{"Create forbidden", reader, "POST", "/tasks", http.StatusForbidden},
+		{"Cancel forbidden", reader, "POST", "/tasks/t1/cancel", http.StatusForbidden},
+		{"Delete forbidden", reader, "DELETE", "/tasks/t1", http.StatusForbidden},
+		{"Admin may delete", admin, "DELETE", "/tasks/t1", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(`{"description":"x"}`))
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			rec := httptest.NewRecorder()
+
+			if tt.url == "/tasks" {
+				handler.HandleTasks(rec, req)
+			} else {
+				handler.HandleTaskByID(rec, req)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestTaskHandlerNamespaces(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0))
+	handler := NewTaskHandler(service, RequireScopes())
+
+	teamA := &auth.Principal{ID: "a", Scopes: []auth.Scope{auth.ScopeRead, auth.ScopeWrite}, Namespace: "team-a"}
+	teamB := &auth.Principal{ID: "b", Scopes: []auth.Scope{auth.ScopeRead, auth.ScopeWrite, auth.ScopeCancel, auth.ScopeDelete}, Namespace: "team-b"}
+	admin := &auth.Principal{ID: "root", Scopes: []auth.Scope{auth.ScopeAdmin}}
+
+	do := func(p *auth.Principal, method, url, namespace, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		if namespace != "" {
+			req.Header.Set(NamespaceHeader, namespace)
+		}
+		rec := httptest.NewRecorder()
+		if strings.HasPrefix(url, "/tasks?") || url == "/tasks" {
+			handler.HandleTasks(rec, req)
+		} else {
+			handler.HandleTaskByID(rec, req)
+		}
+		return rec
+	}
+
+	rec := do(teamA, "POST", "/tasks", "", `{"description":"a"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", rec.Code)
+	}
+	var created models.Task
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Namespace != "team-a" {
+		t.Errorf("Expected namespace team-a, got %q", created.Namespace)
+	}
+	do(teamB, "POST", "/tasks", "", `{"description":"b"}`)
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		url       string
+		namespace string
+		want      int
+		wantTotal int
+	}{
+		{"Other tenant cannot get", teamB, "GET", "/tasks/" + created.ID, "", http.StatusNotFound, 0},
+		{"Other tenant cannot cancel", teamB, "POST", "/tasks/" + created.ID + "/cancel", "", http.StatusNotFound, 0},
+		{"Other tenant cannot delete", teamB, "DELETE", "/tasks/" + created.ID, "", http.StatusNotFound, 0},
+		{"Foreign namespace header", teamB, "GET", "/tasks", "team-a", http.StatusForbidden, 0},
+		{"Invalid namespace header", teamA, "GET", "/tasks", "Team A", http.StatusBadRequest, 0},
+		{"Tenant lists own tasks", teamA, "GET", "/tasks", "", http.StatusOK, 1},
+		{"Tenant cannot list all", teamA, "GET", "/tasks?all_namespaces=true", "", http.StatusForbidden, 0},
+		{"Admin lists namespace", admin, "GET", "/tasks", "team-b", http.StatusOK, 1},
+		{"Admin lists all", admin, "GET", "/tasks?all_namespaces=true", "", http.StatusOK, 2},
+		{"Admin default namespace is empty", admin, "GET", "/tasks", "", http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.principal, tt.method, tt.url, tt.namespace, "")
+			if rec.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if tt.method == "GET" && tt.want == http.StatusOK {
+				var list models.TaskList
+				json.NewDecoder(rec.Body).Decode(&list)
+				if list.Total != tt.wantTotal {
+					t.Errorf("Expected %d tasks, got %d", tt.wantTotal, list.Total)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskHandlerQuotas(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0), services.WithQuotas(services.Quotas{
+		Default: services.Quota{CreatePerMinute: 1},
+	}))
+	handler := NewTaskHandler(service)
+
+	create := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"description":"x"}`))
+		req.Header.Set(NamespaceHeader, "team-a")
+		rec := httptest.NewRecorder()
+		handler.HandleTasks(rec, req)
+		return rec
+	}
+
+	if rec := create(); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", rec.Code)
+	}
+	rec := create()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	req := httptest.NewRequest("GET", "/quotas", nil)
+	req.Header.Set(NamespaceHeader, "team-a")
+	rec = httptest.NewRecorder()
+	handler.HandleQuotas(rec, req)
+
+	var list models.QuotaList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Quotas) != 1 || list.Quotas[0].CreatedLastMinute != (models.Usage{Used: 1, Limit: 1}) {
+		t.Errorf("Unexpected quota usage: %+v", list.Quotas)
+	}
+}
+
+func TestTaskHandlerConditionalRequests(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0))
+	handler := NewTaskHandler(service)
+
+	task, _ := service.CreateTask(context.Background(), models.TaskCreate{Description: "conditional"})
+	path := "/tasks/" + task.ID
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.HandleTaskByID(rec, req)
+		return rec
+	}
+
+	rec := do("GET", path, "", nil)
+	if got := rec.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("Expected ETag \"1\", got %q", got)
+	}
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		if rec := do("GET", path, "", map[string]string{"If-None-Match": `W/"1"`}); rec.Code != http.StatusNotModified {
+			t.Errorf("Expected status 304, got %d", rec.Code)
+		}
+		if rec := do("GET", path, "", map[string]string{"If-None-Match": `"0", "7"`}); rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rec.Code)
+		}
+	})
+
+	t.Run("If-Match on PUT", func(t *testing.T) {
+		rec := do("PUT", path, `{"description":"stale"}`, map[string]string{"If-Match": `"5"`})
+		if rec.Code != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status 412, got %d", rec.Code)
+		}
+		rec = do("PUT", path, `{"description":"fresh"}`, map[string]string{"If-Match": `"1"`})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("ETag"); got != `"2"` {
+			t.Errorf("Expected ETag \"2\", got %q", got)
+		}
+		// Слабый тег не подходит для If-Match
+		if rec := do("PUT", path, `{"description":"weak"}`, map[string]string{"If-Match": `W/"2"`}); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412, got %d", rec.Code)
+		}
+	})
+
+	t.Run("If-Match on cancel and DELETE", func(t *testing.T) {
+		if rec := do("POST", path+"/cancel", "", map[string]string{"If-Match": `"1"`}); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412, got %d", rec.Code)
+		}
+		if rec := do("POST", path+"/cancel", "", map[string]string{"If-Match": "*"}); rec.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rec.Code)
+		}
+		if rec := do("DELETE", path, "", map[string]string{"If-Match": `"2"`}); rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("Expected status 412, got %d", rec.Code)
+		}
+		if rec := do("DELETE", path, "", map[string]string{"If-Match": `"3"`}); rec.Code != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", rec.Code)
+		}
+	})
+}
+
+func TestTaskHandlerPatch(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0))
+	handler := NewTaskHandler(service)
+
+	task, _ := service.CreateTask(context.Background(), models.TaskCreate{Description: "patch"})
+	path := "/tasks/" + task.ID
+
+	do := func(method, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.HandleTaskByID(rec, req)
+		return rec
+	}
+
+	rec := do("PATCH", "application/json", `{"priority":2}`)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected status 415, got %d", rec.Code)
+	}
+	if rec.Header().Get("Accept-Patch") != "application/merge-patch+json" {
+		t.Errorf("Expected Accept-Patch header, got %q", rec.Header().Get("Accept-Patch"))
+	}
+
+	rec = do("PATCH", "application/merge-patch+json", `{"priority":2,"labels":{"env":"prod"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var patched models.Task
+	json.NewDecoder(rec.Body).Decode(&patched)
+	if patched.Description != "patch" || patched.Priority != 2 || patched.Labels["env"] != "prod" {
+		t.Errorf("Unexpected task after PATCH: %+v", patched)
+	}
+
+	rec = do("PUT", "application/json", `{"description":"new","status":"completed","created_at":"2020-01-01T00:00:00Z"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "status: field is immutable") || !strings.Contains(body, "created_at: field is immutable") {
+		t.Errorf("Expected immutable fields in error, got %q", body)
+	}
+
+	rec = do("PUT", "application/json", `{"description":"new"}`)
+	var replaced models.Task
+	json.NewDecoder(rec.Body).Decode(&replaced)
+	if rec.Code != http.StatusOK || replaced.Priority != 0 || replaced.Labels != nil {
+		t.Errorf("Expected PUT to reset omitted fields, got %d %+v", rec.Code, replaced)
+	}
+}
+
+func TestTaskHandlerSelectors(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := services.NewTaskService(store, services.WithWorkers(0))
+	handler := NewTaskHandler(service)
+
+	for _, body := range []string{
+		`{"description":"a","labels":{"env":"prod","team":"a"}}`,
+		`{"description":"b","labels":{"env":"prod","team":"b"}}`,
+		`{"description":"c","labels":{"env":"dev"}}`,
+	} {
+		rec := httptest.NewRecorder()
+		handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"description":"x","labels":{"bad key":"v"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid label, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("GET", "/tasks?selector="+url.QueryEscape("env=prod,team in (b,c)"), nil))
+	var list models.TaskList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Tasks) != 1 || list.Tasks[0].Description != "b" {
+		t.Errorf("Expected only task b, got %+v", list.Tasks)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("GET", "/tasks?selector="+url.QueryEscape("env in (prod"), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid selector, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("POST", "/tasks/cancel?selector=env%3Dprod", nil))
+	var result models.BulkResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if rec.Code != http.StatusOK || result.Matched != 2 || len(result.TaskIDs) != 2 {
+		t.Errorf("Unexpected bulk cancel result: %d %+v", rec.Code, result)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("DELETE", "/tasks", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for bulk delete without selector, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("DELETE", "/tasks?selector=env", nil))
+	json.NewDecoder(rec.Body).Decode(&result)
+	if rec.Code != http.StatusOK || len(result.TaskIDs) != 3 {
+		t.Errorf("Unexpected bulk delete result: %d %+v", rec.Code, result)
+	}
+}
+
+func TestTaskHandlerValidation(t *testing.T) {
+	rules := validation.DefaultRules()
+	rules.MaxBodyBytes = 256
+	service := services.NewTaskService(storage.NewInMemoryTaskStorage(), services.WithWorkers(0), services.WithValidation(rules))
+	handler := NewTaskHandler(service)
+
+	rec := httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"descripton":"typo","labels":{"bad key":"v"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON error, got %q", ct)
+	}
+	var body struct {
+		Message string                  `json:"message"`
+		Errors  []validation.FieldError `json:"errors"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if len(body.Errors) != 1 || body.Errors[0].Pointer != "/descripton" || body.Message == "" {
+		t.Errorf("Expected unknown field error, got %+v", body)
+	}
+
+	// Поля проверяются после разбора: отсутствие описания и неверная метка - вместе
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"labels":{"bad key":"v"}}`)))
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusBadRequest || len(body.Errors) != 2 {
+		t.Errorf("Expected two field errors, got %d %+v", rec.Code, body)
+	}
+
+	rec = httptest.NewRecorder()
+	large := `{"description":"` + strings.Repeat("x", 300) + `"}`
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(large)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+}
+
+func TestTaskHandlerTaskTypes(t *testing.T) {
+	types := tasktypes.NewRegistry()
+	err := types.Register(tasktypes.TaskType{
+		Name:         "resize",
+		Description:  "Resize an image",
+		InputSchema:  json.RawMessage(`{"type":"object","required":["width"],"properties":{"width":{"type":"integer","minimum":1}}}`),
+		OutputSchema: json.RawMessage(`{"type":"object"}`),
+		Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+			return task.Input, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewTaskService(storage.NewInMemoryTaskStorage(), services.WithWorkers(0), services.WithTaskTypes(types))
+	handler := NewTaskHandler(service)
+
+	rec := httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"type":"resize","description":"bad","input":{"width":0}}`)))
+	var body struct {
+		Errors []validation.FieldError `json:"errors"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusBadRequest || len(body.Errors) != 1 || body.Errors[0].Pointer != "/input/width" {
+		t.Errorf("Expected input schema error, got %d %+v", rec.Code, body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTasks(rec, httptest.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"type":"resize","description":"good","input":{"width":640}}`)))
+	var task models.Task
+	json.NewDecoder(rec.Body).Decode(&task)
+	if rec.Code != http.StatusCreated || string(task.Input) != `{"width":640}` {
+		t.Errorf("Expected task with input, got %d %s", rec.Code, task.Input)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskTypes(rec, httptest.NewRequest("GET", "/task-types/resize", nil))
+	var info models.TaskTypeInfo
+	json.NewDecoder(rec.Body).Decode(&info)
+	if rec.Code != http.StatusOK || info.Description != "Resize an image" || string(info.OutputSchema) != `{"type":"object"}` {
+		t.Errorf("Unexpected task type: %d %+v", rec.Code, info)
+	}
+
+	if info.QueueDepth != 1 {
+		t.Errorf("Expected one queued task, got %d", info.QueueDepth)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskTypes(rec, httptest.NewRequest("GET", "/task-types", nil))
+	var list models.TaskTypeList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || list.Total != 2 || list.TaskTypes[1].Name != "resize" {
+		t.Errorf("Unexpected task type list: %d %+v", rec.Code, list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskTypes(rec, httptest.NewRequest("GET", "/task-types/resize/stats", nil))
+	var stats models.TaskTypeStats
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if rec.Code != http.StatusOK || stats.Type != "resize" || stats.SuccessRate != nil {
+		t.Errorf("Unexpected task type stats: %d %+v", rec.Code, stats)
+	}
+
+	for _, path := range []string{"/task-types/missing", "/task-types/missing/stats", "/task-types/resize/other"} {
+		rec = httptest.NewRecorder()
+		handler.HandleTaskTypes(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestTaskHandlerLogs(t *testing.T) {
+	logs := tasklogs.NewStore(0)
+	service := services.NewTaskService(storage.NewInMemoryTaskStorage(), services.WithWorkers(0), services.WithTaskLogs(logs))
+	handler := NewTaskHandler(service)
+
+	task, err := service.CreateTask(context.Background(), models.TaskCreate{Description: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one", "two", "three"} {
+		logs.Append(task.ID, tasklogs.Stdout, line)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/logs?tail=2", nil))
+	var list models.TaskLogList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || len(list.Entries) != 2 || list.Entries[0].Line != "two" || list.Entries[0].Seq != 2 {
+		t.Errorf("Expected last two lines, got %d %+v", rec.Code, list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/logs?since=yesterday&tail=-1", nil))
+	var body struct {
+		Errors []validation.FieldError `json:"errors"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusBadRequest || len(body.Errors) != 2 || body.Errors[1].Parameter != "since" {
+		t.Errorf("Expected parameter errors, got %d %+v", rec.Code, body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/logs?since=1h", nil))
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || len(list.Entries) != 3 {
+		t.Errorf("Expected all recent lines, got %d %+v", rec.Code, list)
+	}
+
+	// Поток завершенной задачи отдает журнал и закрывается
+	if _, err := service.CancelTask(context.Background(), task.ID); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/logs?follow=true&tail=1", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" || !strings.HasPrefix(rec.Body.String(), `{"seq":3,`) {
+		t.Errorf("Expected NDJSON stream with the last line, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/missing/logs?follow=true", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown task, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("DELETE", "/tasks/"+task.ID+"/logs", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+
+	if name := RouteName("/tasks/" + task.ID + "/logs"); name != "/tasks/{id}/logs" {
+		t.Errorf("Unexpected route name %q", name)
+	}
+}
+
+func TestTaskHandlerArtifacts(t *testing.T) {
+	store, err := artifacts.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewTaskService(storage.NewInMemoryTaskStorage(), services.WithWorkers(0), services.WithArtifacts(store))
+	handler := NewTaskHandler(service)
+
+	task, err := service.CreateTask(context.Background(), models.TaskCreate{Description: "export"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact, err := store.Put(context.Background(), task.ID, "report.txt", "text/plain", strings.NewReader("0123456789"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/artifacts", nil))
+	var list models.ArtifactList
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || list.Total != 1 || list.Artifacts[0].Checksum != artifact.Checksum || list.Artifacts[0].Size != 10 {
+		t.Errorf("Expected one artifact, got %d %+v", rec.Code, list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("GET", "/tasks/"+task.ID+"/artifacts/report.txt", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected artifact content, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="report.txt"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest("GET", "/tasks/"+task.ID+"/artifacts/report.txt", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" || rec.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Errorf("Expected partial content, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/tasks/"+task.ID+"/artifacts/report.txt", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	for _, path := range []string{"/tasks/" + task.ID + "/artifacts/missing.txt", "/tasks/missing/artifacts", "/tasks/" + task.ID + "/artifacts/report.txt/extra"} {
+		rec = httptest.NewRecorder()
+		handler.HandleTaskByID(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleTaskByID(rec, httptest.NewRequest("DELETE", "/tasks/"+task.ID+"/artifacts/report.txt", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+
+	if name := RouteName("/tasks/" + task.ID + "/artifacts/report.txt"); name != "/tasks/{id}/artifacts/{name}" {
+		t.Errorf("Unexpected route name %q", name)
+	}
+}
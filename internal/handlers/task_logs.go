@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/internal/validation"
+	"http_api/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// taskLogs отдает журнал задачи (GET /tasks/{id}/logs). С ?follow=true
+// строки передаются потоком NDJSON, пока задача не завершится.
+func (h *TaskHandler) taskLogs(w http.ResponseWriter, r *http.Request, id string) {
+	query, follow, ok := parseLogQuery(w, r)
+	if !ok {
+		return
+	}
+
+	if !follow {
+		entries, err := h.service.TaskLogs(r.Context(), id, query)
+		if err != nil {
+			logsError(w, r, err)
+			return
+		}
+		if entries == nil {
+			entries = []models.TaskLogEntry{}
+		}
+		respondWithJSON(w, http.StatusOK, models.TaskLogList{Entries: entries})
+		return
+	}
+
+	// Поток живет дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	started := false
+	start := func() {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	encoder := json.NewEncoder(w)
+	err := h.service.FollowTaskLogs(r.Context(), id, query, func(entries []models.TaskLogEntry) error {
+		start()
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return rc.Flush()
+	})
+	switch {
+	case err != nil && !started:
+		logsError(w, r, err)
+	case err != nil && !errors.Is(err, context.Canceled):
+		slog.WarnContext(r.Context(), "task log stream interrupted", "task_id", id, "error", err)
+	default:
+		start()
+	}
+}
+
+func logsError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrTaskNotFound) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	internalError(w, r, err)
+}
+
+// parseLogQuery разбирает параметры tail, since и follow. since принимает
+// момент времени в RFC 3339 или длительность, отсчитываемую от текущего момента.
+func parseLogQuery(w http.ResponseWriter, r *http.Request) (tasklogs.Query, bool, bool) {
+	var query tasklogs.Query
+	var follow bool
+	var errs []validation.FieldError
+	params := r.URL.Query()
+
+	if raw := params.Get("tail"); raw != "" {
+		tail, err := strconv.Atoi(raw)
+		if err != nil || tail < 0 {
+			errs = append(errs, validation.FieldError{Parameter: "tail", Message: "must be a non-negative integer"})
+		}
+		query.Tail = tail
+	}
+	if raw := params.Get("since"); raw != "" {
+		if since, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			query.Since = since
+		} else if d, err := time.ParseDuration(raw); err == nil && d >= 0 {
+			query.Since = time.Now().Add(-d)
+		} else {
+			errs = append(errs, validation.FieldError{Parameter: "since", Message: "must be an RFC 3339 timestamp or a non-negative duration"})
+		}
+	}
+	if raw := params.Get("follow"); raw != "" {
+		var err error
+		if follow, err = strconv.ParseBool(raw); err != nil {
+			errs = append(errs, validation.FieldError{Parameter: "follow", Message: "must be a boolean"})
+		}
+	}
+
+	if len(errs) > 0 {
+		respondWithJSON(w, http.StatusBadRequest, &validation.Error{Errors: errs})
+		return query, false, false
+	}
+	return query, follow, true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"http_api/internal/auth"
+	"http_api/internal/tasktypes"
+	"net/http"
+	"strings"
+)
+
+// HandleTaskTypes отдает каталог типов задач (/task-types), описание
+// одного типа (/task-types/{type}) и его статистику (/task-types/{type}/stats)
+func (h *TaskHandler) HandleTaskTypes(w http.ResponseWriter, r *http.Request) {
+	var name string
+	var stats bool
+	if r.URL.Path != "/task-types" {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/task-types/"), "/")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+		case len(parts) == 2 && parts[0] != "" && parts[1] == "stats":
+			stats = true
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		name = parts[0]
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeRead) {
+		return
+	}
+
+	if name == "" {
+		respondWithJSON(w, http.StatusOK, h.service.TaskTypes())
+		return
+	}
+
+	var result interface{}
+	var err error
+	if stats {
+		result, err = h.service.TaskTypeStats(name)
+	} else {
+		result, err = h.service.TaskType(name)
+	}
+	if errors.Is(err, tasktypes.ErrUnknownType) {
+		http.Error(w, "Task type not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(w, r, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
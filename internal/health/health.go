@@ -0,0 +1,143 @@
+// Package health реализует проверки живости (/healthz) и готовности (/readyz).
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Checker - проверка состояния компонента; nil означает, что компонент исправен
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc позволяет использовать функцию как Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	critical bool
+}
+
+type Health struct {
+	mu      sync.RWMutex
+	checks  []check
+	timeout time.Duration
+}
+
+func New() *Health {
+	return &Health{timeout: defaultTimeout}
+}
+
+// Register добавляет проверку готовности. Отказ критичной проверки
+// переводит /readyz в 503, некритичной - только отражается в ответе.
+func (h *Health) Register(name string, critical bool, checker Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, checker: checker, critical: critical})
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Run выполняет все проверки параллельно с общим таймаутом
+func (h *Health) Run(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if c.critical && results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c check) CheckResult {
+	result := CheckResult{Status: StatusOK, Critical: c.critical}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler отвечает 200, пока процесс способен обслуживать запросы
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+// ReadinessHandler отвечает 503, если не прошла хотя бы одна критичная проверка
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Run(r.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func TestHealth(t *testing.T) {
+	t.Run("Liveness", func(t *testing.T) {
+		h := New()
+		h.Register("broken", true, CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+
+		rec := httptest.NewRecorder()
+		h.LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Ready when all checks pass", func(t *testing.T) {
+		h := New()
+		h.Register("storage", true, CheckerFunc(ok))
+		h.Register("cache", false, CheckerFunc(ok))
+
+		rec := httptest.NewRecorder()
+		h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report Report
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Equal(t, StatusOK, report.Checks["storage"].Status)
+		assert.True(t, report.Checks["storage"].Critical)
+	})
+
+	t.Run("Non-critical failure keeps service ready", func(t *testing.T) {
+		h := New()
+		h.Register("storage", true, CheckerFunc(ok))
+		h.Register("cache", false, CheckerFunc(func(ctx context.Context) error { return errors.New("cold") }))
+
+		report := h.Run(context.Background())
+
+		assert.Equal(t, StatusOK, report.Status)
+		assert.Equal(t, StatusFail, report.Checks["cache"].Status)
+		assert.Equal(t, "cold", report.Checks["cache"].Error)
+	})
+
+	t.Run("Critical failure returns 503", func(t *testing.T) {
+		h := New()
+		h.Register("storage", true, CheckerFunc(func(ctx context.Context) error { return errors.New("read-only") }))
+
+		rec := httptest.NewRecorder()
+		h.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "read-only")
+	})
+
+	t.Run("Hanging check times out", func(t *testing.T) {
+		h := New()
+		h.timeout = 10 * time.Millisecond
+		h.Register("stuck", true, CheckerFunc(func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}))
+
+		report := h.Run(context.Background())
+
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Contains(t, report.Checks["stuck"].Error, "deadline")
+	})
+}
@@ -0,0 +1,172 @@
+// Package labels реализует метки задач и селекторы в стиле Kubernetes:
+// env=prod,tier!=cache,team in (a,b),release notin (beta),owner,!draft
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement - одно условие селектора
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+func (r Requirement) Matches(set map[string]string) bool {
+	value, ok := set[r.Key]
+	switch r.Operator {
+	case Equals, In:
+		return ok && r.hasValue(value)
+	case NotEquals, NotIn:
+		return !ok || !r.hasValue(value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+func (r Requirement) hasValue(value string) bool {
+	for _, v := range r.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector - набор условий, которые должны выполняться одновременно.
+// Пустой селектор выбирает все задачи.
+type Selector []Requirement
+
+func (s Selector) Matches(set map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// Parse разбирает строку селектора
+func Parse(selector string) (Selector, error) {
+	var s Selector
+	for _, term := range splitTerms(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return nil, fmt.Errorf("empty requirement in selector %q", selector)
+		}
+		r, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, r)
+	}
+	return s, nil
+}
+
+// splitTerms делит селектор по запятым вне скобок
+func splitTerms(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	var r Requirement
+	if m := setRequirement.FindStringSubmatch(term); m != nil {
+		r = Requirement{Key: m[1], Operator: Operator(m[2])}
+		for _, v := range strings.Split(m[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(v))
+		}
+	} else if strings.HasPrefix(term, "!") {
+		r = Requirement{Key: strings.TrimSpace(term[1:]), Operator: DoesNotExist}
+	} else if key, value, ok := strings.Cut(term, "!="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: NotEquals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, value, ok := strings.Cut(term, "=="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	} else if key, value, ok := strings.Cut(term, "="); ok {
+		r = Requirement{Key: strings.TrimSpace(key), Operator: Equals, Values: []string{strings.TrimSpace(value)}}
+	} else {
+		r = Requirement{Key: term, Operator: Exists}
+	}
+
+	if err := ValidateKey(r.Key); err != nil {
+		return r, fmt.Errorf("invalid requirement %q: %w", term, err)
+	}
+	for _, v := range r.Values {
+		if err := ValidateValue(v); err != nil {
+			return r, fmt.Errorf("invalid requirement %q: %w", term, err)
+		}
+	}
+	return r, nil
+}
+
+var (
+	namePattern  = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	prefixSyntax = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// ValidateKey проверяет ключ метки: необязательный префикс в виде DNS-имени
+// до 253 символов, "/" и имя до 63 символов из букв, цифр, "-", "_" и "."
+func ValidateKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) > 253 || !prefixSyntax.MatchString(prefix) {
+			return fmt.Errorf("invalid label key prefix %q", prefix)
+		}
+		name = rest
+	}
+	if len(name) > 63 || !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+// ValidateValue проверяет значение метки: пустое или до 63 символов того же алфавита, что и имя ключа
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > 63 || !namePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
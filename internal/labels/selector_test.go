@@ -0,0 +1,58 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse("env=prod, team in (a, b),!draft,tier!=cache,owner,release notin (beta),region==eu")
+	require.NoError(t, err)
+	assert.Equal(t, Selector{
+		{Key: "env", Operator: Equals, Values: []string{"prod"}},
+		{Key: "team", Operator: In, Values: []string{"a", "b"}},
+		{Key: "draft", Operator: DoesNotExist},
+		{Key: "tier", Operator: NotEquals, Values: []string{"cache"}},
+		{Key: "owner", Operator: Exists},
+		{Key: "release", Operator: NotIn, Values: []string{"beta"}},
+		{Key: "region", Operator: Equals, Values: []string{"eu"}},
+	}, s)
+
+	empty, err := Parse("  ")
+	require.NoError(t, err)
+	assert.True(t, empty.Empty())
+
+	for _, invalid := range []string{"env=prod,", "team in (a b)", "=prod", "env=bad value", "-env=prod", "Bad_Prefix/env=x"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	set := map[string]string{"env": "prod", "team": "a", "example.com/owner": "ci"}
+
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"missing!=x", true},
+		{"team in (a,b)", true},
+		{"team notin (a,b)", false},
+		{"missing notin (a)", true},
+		{"example.com/owner", true},
+		{"!draft", true},
+		{"!env", false},
+		{"env=prod,team in (b)", false},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.selector)
+		require.NoError(t, err, tt.selector)
+		assert.Equal(t, tt.matches, s.Matches(set), tt.selector)
+	}
+}
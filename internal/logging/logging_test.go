@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Adds request ID from context", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := New(&out, "info", "json")
+		require.NoError(t, err)
+
+		ctx := WithRequestID(context.Background(), "req-1")
+		logger.With("component", "test").InfoContext(ctx, "task created", "task_id", "abc")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		assert.Equal(t, "req-1", entry["request_id"])
+		assert.Equal(t, "abc", entry["task_id"])
+		assert.Equal(t, "test", entry["component"])
+	})
+
+	t.Run("Respects level", func(t *testing.T) {
+		var out bytes.Buffer
+		logger, err := New(&out, "warn", "text")
+		require.NoError(t, err)
+
+		logger.Info("hidden")
+		logger.Warn("shown")
+		assert.NotContains(t, out.String(), "hidden")
+		assert.Contains(t, out.String(), "shown")
+	})
+
+	t.Run("Rejects invalid settings", func(t *testing.T) {
+		_, err := New(&bytes.Buffer{}, "verbose", "json")
+		assert.Error(t, err)
+		_, err = New(&bytes.Buffer{}, "info", "xml")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,311 @@
+// Package metrics реализует счетчики, измерители и гистограммы
+// с выводом в текстовом формате Prometheus без внешних зависимостей.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// DefBuckets подходят для длительности HTTP-запросов в секундах
+	DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// TaskBuckets подходят для длительных задач: от секунды до часа
+	TaskBuckets = []float64{1, 5, 15, 30, 60, 120, 180, 240, 300, 600, 1800, 3600}
+)
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", name))
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo выводит все метрики в текстовом формате Prometheus
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	})
+}
+
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*Counter
+}
+
+type Counter struct {
+	labelValues []string
+	mu          sync.Mutex
+	value       float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*Counter)}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	checkLabels(c.name, c.labels, values)
+	key := strings.Join(values, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.values[key]
+	if !ok {
+		counter = &Counter{labelValues: append([]string(nil), values...)}
+		c.values[key] = counter
+	}
+	return counter
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	c.value += delta
+	c.mu.Unlock()
+}
+
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+
+	c.mu.Lock()
+	counters := make([]*Counter, 0, len(c.values))
+	for _, counter := range c.values {
+		counters = append(counters, counter)
+	}
+	c.mu.Unlock()
+	sortByLabels(counters, func(c *Counter) []string { return c.labelValues })
+
+	for _, counter := range counters {
+		writeSample(w, c.name, c.labels, counter.labelValues, "", "", counter.Value())
+	}
+}
+
+// GaugeFunc вычисляет значение в момент сбора метрик
+type GaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &GaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	writeSample(w, g.name, nil, nil, "", "", g.fn())
+}
+
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	values     map[string]*Histogram
+}
+
+type Histogram struct {
+	labelValues []string
+	buckets     []float64
+	mu          sync.Mutex
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*Histogram)}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	checkLabels(h.name, h.labels, values)
+	key := strings.Join(values, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	histogram, ok := h.values[key]
+	if !ok {
+		histogram = &Histogram{
+			labelValues: append([]string(nil), values...),
+			buckets:     h.buckets,
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = histogram
+	}
+	return histogram
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	h.mu.Lock()
+	histograms := make([]*Histogram, 0, len(h.values))
+	for _, histogram := range h.values {
+		histograms = append(histograms, histogram)
+	}
+	h.mu.Unlock()
+	sortByLabels(histograms, func(h *Histogram) []string { return h.labelValues })
+
+	for _, histogram := range histograms {
+		histogram.mu.Lock()
+		counts := append([]uint64(nil), histogram.counts...)
+		sum, count := histogram.sum, histogram.count
+		histogram.mu.Unlock()
+
+		for i, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, histogram.labelValues, "le", formatFloat(upper), float64(counts[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, histogram.labelValues, "le", "+Inf", float64(count))
+		writeSample(w, h.name+"_sum", h.labels, histogram.labelValues, "", "", sum)
+		writeSample(w, h.name+"_count", h.labels, histogram.labelValues, "", "", float64(count))
+	}
+}
+
+func checkLabels(name string, labels, values []string) {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", name, len(labels), len(values)))
+	}
+}
+
+func sortByLabels[T any](items []T, labels func(T) []string) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := labels(items[i]), labels(items[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabelValue(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
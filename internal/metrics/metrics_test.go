@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Text exposition format", func(t *testing.T) {
+		reg := NewRegistry()
+		counter := reg.NewCounterVec("jobs_total", "Processed jobs.", "type")
+		reg.NewGaugeFunc("queue_depth", "Queue depth.", func() float64 { return 3 })
+		histogram := reg.NewHistogramVec("job_seconds", "Job duration.", []float64{1, 5}, "type")
+
+		counter.WithLabelValues("b").Inc()
+		counter.WithLabelValues("a").Add(2)
+		histogram.WithLabelValues("a").Observe(0.5)
+		histogram.WithLabelValues("a").Observe(3)
+		histogram.WithLabelValues("a").Observe(10)
+
+		var out strings.Builder
+		_, err := reg.WriteTo(&out)
+		assert.NoError(t, err)
+
+		assert.Equal(t, `# HELP jobs_total Processed jobs.
+# TYPE jobs_total counter
+jobs_total{type="a"} 2
+jobs_total{type="b"} 1
+# HELP queue_depth Queue depth.
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP job_seconds Job duration.
+# TYPE job_seconds histogram
+job_seconds_bucket{type="a",le="1"} 1
+job_seconds_bucket{type="a",le="5"} 2
+job_seconds_bucket{type="a",le="+Inf"} 3
+job_seconds_sum{type="a"} 13.5
+job_seconds_count{type="a"} 3
+`, out.String())
+	})
+
+	t.Run("Label values are escaped", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.NewCounterVec("c_total", "C.", "v").WithLabelValues("a\"b\\c\nd").Inc()
+
+		var out strings.Builder
+		reg.WriteTo(&out)
+
+		assert.Contains(t, out.String(), `c_total{v="a\"b\\c\nd"} 1`)
+	})
+
+	t.Run("Duplicate and misused metrics panic", func(t *testing.T) {
+		reg := NewRegistry()
+		counter := reg.NewCounterVec("c_total", "C.", "v")
+
+		assert.Panics(t, func() { reg.NewCounterVec("c_total", "C.") })
+		assert.Panics(t, func() { counter.WithLabelValues() })
+		assert.Panics(t, func() { counter.WithLabelValues("x").Add(-1) })
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.NewGaugeFunc("up", "Up.", func() float64 { return 1 })
+
+		rec := httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, rec.Body.String(), "up 1")
+
+		rec = httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rec, httptest.NewRequest("POST", "/metrics", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccessLog пишет по строке на каждый запрос. Должен стоять после RequestID,
+// чтобы запись получила request_id.
+func AccessLog(logger *slog.Logger, route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			next.ServeHTTP(rec, r)
+
+			level := slog.LevelInfo
+			if rec.Status() >= 500 {
+				level = slog.LevelError
+			}
+			logger.Log(r.Context(), level, "http request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route(r),
+				"status", rec.Status(),
+				"bytes", rec.bytes,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
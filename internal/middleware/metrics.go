@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"http_api/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics считает HTTP-запросы и их длительность по маршрутам и кодам ответа.
+// route должен возвращать шаблон маршрута, а не исходный путь, чтобы число
+// временных рядов не зависело от ID задач.
+func Metrics(reg *metrics.Registry, route func(*http.Request) string) func(http.Handler) http.Handler {
+	requests := reg.NewCounterVec("taskapi_http_requests_total",
+		"Number of HTTP requests by route, method and status code.", "route", "method", "code")
+	duration := reg.NewHistogramVec("taskapi_http_request_duration_seconds",
+		"Duration of HTTP requests by route and method.", metrics.DefBuckets, "route", "method")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			next.ServeHTTP(rec, r)
+
+			name, method := route(r), methodLabel(r.Method)
+			requests.WithLabelValues(name, method, strconv.Itoa(rec.Status())).Inc()
+			duration.WithLabelValues(name, method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// methodLabel сводит нестандартные методы к "other": метод задает клиент,
+// и произвольные значения раздували бы число временных рядов
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"http_api/internal/logging"
+	"http_api/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	route := func(r *http.Request) string { return "/tasks/{id}" }
+	handler := Metrics(reg, route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tasks/missing" {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tasks/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tasks/2", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/tasks/missing", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOO", "/tasks/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BAR", "/tasks/1", nil))
+
+	var out strings.Builder
+	reg.WriteTo(&out)
+
+	assert.Contains(t, out.String(), `taskapi_http_requests_total{route="/tasks/{id}",method="GET",code="200"} 2`)
+	assert.Contains(t, out.String(), `taskapi_http_requests_total{route="/tasks/{id}",method="GET",code="404"} 1`)
+	assert.Contains(t, out.String(), `taskapi_http_request_duration_seconds_count{route="/tasks/{id}",method="GET"} 3`)
+	assert.Contains(t, out.String(), `taskapi_http_requests_total{route="/tasks/{id}",method="other",code="200"} 2`)
+	assert.NotContains(t, out.String(), `method="FOO"`)
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	t.Run("Propagates client ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks", nil)
+		req.Header.Set(RequestIDHeader, "client-42")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "client-42", seen)
+		assert.Equal(t, "client-42", rec.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Generates ID when missing or invalid", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks", nil)
+		req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Len(t, seen, 32)
+		assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := logging.New(&out, "info", "json")
+	require.NoError(t, err)
+
+	route := func(r *http.Request) string { return "/tasks" }
+	handler := RequestID(AccessLog(logger, route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})))
+
+	req := httptest.NewRequest("POST", "/tasks", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "http request", entry["msg"])
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.Equal(t, "/tasks", entry["route"])
+	assert.Equal(t, float64(500), entry["status"])
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewRateLimiter(map[string]Rule{"create": {PerMinute: 60, Burst: 2}}, 2)
+	limiter.now = func() time.Time { return now }
+
+	t.Run("Burst then refill", func(t *testing.T) {
+		d, _ := limiter.Allow("create", "alice")
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 1, d.Remaining)
+		d, _ = limiter.Allow("create", "alice")
+		assert.True(t, d.Allowed)
+
+		d, _ = limiter.Allow("create", "alice")
+		assert.False(t, d.Allowed)
+		assert.Equal(t, time.Second, d.RetryAfter)
+
+		// Другой клиент ограничивается независимо
+		d, _ = limiter.Allow("create", "bob")
+		assert.True(t, d.Allowed)
+
+		now = now.Add(time.Second)
+		d, _ = limiter.Allow("create", "alice")
+		assert.True(t, d.Allowed)
+	})
+
+	t.Run("Unlimited class", func(t *testing.T) {
+		_, limited := limiter.Allow("read", "alice")
+		assert.False(t, limited)
+	})
+
+	t.Run("Memory bounded", func(t *testing.T) {
+		for _, key := range []string{"c1", "c2", "c3", "c4"} {
+			limiter.Allow("create", key)
+		}
+		assert.Equal(t, 2, limiter.Len())
+	})
+
+	t.Run("Idle buckets evicted", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		limiter.Allow("create", "fresh")
+		assert.Equal(t, 1, limiter.Len())
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := NewRateLimiter(map[string]Rule{"create": {PerMinute: 1, Burst: 1}}, 10)
+	class := func(r *http.Request) string { return "create" }
+	handler := RateLimit(limiter, class, ClientIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest("POST", "/tasks", nil)
+	req.RemoteAddr = "10.0.0.1:5555"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// Тот же клиент с другого порта - тот же ключ
+	req.RemoteAddr = "10.0.0.1:6666"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// Запросы без ключа не ограничиваются
+	unkeyed := RateLimit(limiter, class, func(*http.Request) string { return "" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	rec = httptest.NewRecorder()
+	unkeyed.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"container/list"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rule - скорость пополнения корзины в запросах в минуту и ее емкость
+type Rule struct {
+	PerMinute int
+	Burst     int
+}
+
+func (r Rule) perSecond() float64 {
+	return float64(r.PerMinute) / 60
+}
+
+// fillTime - за сколько пустая корзина наполняется целиком
+func (r Rule) fillTime() time.Duration {
+	return time.Duration(float64(r.Burst) / r.perSecond() * float64(time.Second))
+}
+
+type bucket struct {
+	key    string
+	rule   Rule
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter хранит корзины маркеров (token bucket) по ключу клиента и классу
+// маршрута. Число корзин ограничено: давно не использованные вытесняются первыми,
+// а корзины, которые успели бы наполниться, удаляются без потери точности.
+type RateLimiter struct {
+	mu         sync.Mutex
+	rules      map[string]Rule
+	maxBuckets int
+	buckets    map[string]*list.Element
+	lru        *list.List // от недавно использованных к давно не использованным
+	now        func() time.Time
+}
+
+// NewRateLimiter создает ограничитель с правилами по классам маршрутов.
+// Для класса без правила запросы не ограничиваются. Всего хранится не больше
+// maxClients корзин на класс (0 - без ограничения).
+func NewRateLimiter(rules map[string]Rule, maxClients int) *RateLimiter {
+	return &RateLimiter{
+		rules:      rules,
+		maxBuckets: maxClients * len(rules),
+		buckets:    make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// Decision - результат проверки запроса
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - через сколько корзина снова будет полной
+	Reset time.Duration
+	// RetryAfter - через сколько появится маркер для отклоненного запроса
+	RetryAfter time.Duration
+}
+
+// Allow списывает маркер из корзины клиента key для класса class
+func (l *RateLimiter) Allow(class, key string) (Decision, bool) {
+	rule, ok := l.rules[class]
+	if !ok || rule.PerMinute <= 0 {
+		return Decision{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdle(now)
+
+	id := class + "\xff" + key
+	var b *bucket
+	if elem, ok := l.buckets[id]; ok {
+		l.lru.MoveToFront(elem)
+		b = elem.Value.(*bucket)
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rule.perSecond())
+		b.last = now
+	} else {
+		b = &bucket{key: id, rule: rule, tokens: float64(rule.Burst), last: now}
+		l.buckets[id] = l.lru.PushFront(b)
+		if l.maxBuckets > 0 && l.lru.Len() > l.maxBuckets {
+			l.removeElement(l.lru.Back())
+		}
+	}
+
+	d := Decision{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration((1 - b.tokens) / rule.perSecond() * float64(time.Second))
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = time.Duration((float64(rule.Burst) - b.tokens) / rule.perSecond() * float64(time.Second))
+	return d, true
+}
+
+// evictIdle удаляет с конца LRU корзины, которые уже наполнились бы целиком:
+// новая корзина для того же клиента ведет себя так же
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for elem := l.lru.Back(); elem != nil; elem = l.lru.Back() {
+		b := elem.Value.(*bucket)
+		if now.Sub(b.last) < b.rule.fillTime() {
+			return
+		}
+		l.removeElement(elem)
+	}
+}
+
+func (l *RateLimiter) removeElement(elem *list.Element) {
+	delete(l.buckets, elem.Value.(*bucket).key)
+	l.lru.Remove(elem)
+}
+
+// Len возвращает число хранимых корзин
+func (l *RateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
+
+// RateLimit ограничивает частоту запросов клиента для каждого класса маршрутов.
+// Все ответы получают заголовки RateLimit-Limit/Remaining/Reset, отклоненные -
+// 429 и Retry-After. Запросы с пустым ключом не ограничиваются.
+func RateLimit(limiter *RateLimiter, class, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := key(r)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			d, limited := limiter.Allow(class(r), id)
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(d.Reset))
+			if !d.Allowed {
+				h.Set("Retry-After", ceilSeconds(d.RetryAfter))
+				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// ClientIP возвращает адрес клиента из RemoteAddr без порта
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
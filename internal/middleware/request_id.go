@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"http_api/internal/logging"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID присваивает запросу идентификатор: берет корректный X-Request-ID
+// клиента или генерирует новый, возвращает его в ответе и кладет в context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID допускает только короткие печатные идентификаторы,
+// чтобы клиент не мог подмешать в логи произвольный текст
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
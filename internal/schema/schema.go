@@ -0,0 +1,431 @@
+// Package schema проверяет JSON-документы по JSON Schema. Поддерживается
+// подмножество draft 2020-12, достаточное для описания входа и результата задач:
+// type, enum, const, properties, required, additionalProperties, items,
+// minItems/maxItems, minLength/maxLength, pattern, minimum/maximum,
+// exclusiveMinimum/exclusiveMaximum, allOf, anyOf, oneOf, not.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema - разобранная схема, готовая к проверке документов
+type Schema struct {
+	raw json.RawMessage
+
+	// always задан для булевых схем true/false
+	always *bool
+
+	types                []string
+	enum                 []interface{}
+	constant             *interface{}
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	items                *Schema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	allOf, anyOf, oneOf  []*Schema
+	not                  *Schema
+}
+
+// Error - нарушение схемы в месте документа, заданном JSON Pointer
+type Error struct {
+	Pointer string
+	Message string
+}
+
+func (e Error) Error() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + e.Message
+}
+
+// Compile разбирает схему
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	s, err := compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	s.raw = append(json.RawMessage(nil), raw...)
+	return s, nil
+}
+
+// MarshalJSON возвращает исходный текст схемы
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return s.raw, nil
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// ignored - ключевые слова-аннотации, не влияющие на проверку
+var ignored = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true, "format": true,
+	"readOnly": true, "writeOnly": true, "deprecated": true,
+}
+
+func compile(doc interface{}, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema%s must be an object or a boolean", at(path))
+	}
+
+	s := &Schema{}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := obj[key]
+		var err error
+		switch key {
+		case "type":
+			s.types, err = compileTypes(value)
+		case "enum":
+			list, ok := value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an array")
+			}
+			s.enum = list
+		case "const":
+			s.constant = &value
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an object")
+				break
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				if s.properties[name], err = compile(sub, path+"/properties/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = compileStrings(value)
+		case "additionalProperties":
+			s.additionalProperties, err = compile(value, path+"/additionalProperties")
+		case "items":
+			s.items, err = compile(value, path+"/items")
+		case "minItems":
+			s.minItems, err = compileCount(value)
+		case "maxItems":
+			s.maxItems, err = compileCount(value)
+		case "minLength":
+			s.minLength, err = compileCount(value)
+		case "maxLength":
+			s.maxLength, err = compileCount(value)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(pattern)
+		case "minimum":
+			s.minimum, err = compileNumber(value)
+		case "maximum":
+			s.maximum, err = compileNumber(value)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = compileNumber(value)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = compileNumber(value)
+		case "allOf":
+			s.allOf, err = compileList(value, path+"/allOf")
+		case "anyOf":
+			s.anyOf, err = compileList(value, path+"/anyOf")
+		case "oneOf":
+			s.oneOf, err = compileList(value, path+"/oneOf")
+		case "not":
+			s.not, err = compile(value, path+"/not")
+		default:
+			if !ignored[key] {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("schema%s: %s: %w", at(path), key, err)
+		}
+	}
+	return s, nil
+}
+
+func at(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " at " + path
+}
+
+func compileTypes(value interface{}) ([]string, error) {
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = []string{v}
+	case []interface{}:
+		var err error
+		if types, err = compileStrings(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+	for _, t := range types {
+		if !knownTypes[t] {
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return types, nil
+}
+
+func compileStrings(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	result := make([]string, len(list))
+	for i, item := range list {
+		if result[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+	}
+	return result, nil
+}
+
+func compileCount(value interface{}) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	count := int(n)
+	return &count, nil
+}
+
+func compileNumber(value interface{}) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &n, nil
+}
+
+func compileList(value interface{}, path string) ([]*Schema, error) {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("must be a non-empty array of schemas")
+	}
+	schemas := make([]*Schema, len(list))
+	for i, item := range list {
+		var err error
+		if schemas[i], err = compile(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+// Validate проверяет документ и возвращает все найденные нарушения
+func (s *Schema) Validate(document json.RawMessage) []Error {
+	var doc interface{}
+	if len(bytes.TrimSpace(document)) == 0 {
+		// Отсутствующий документ проверяется как null
+		doc = nil
+	} else if err := json.Unmarshal(document, &doc); err != nil {
+		return []Error{{Message: "invalid JSON: " + err.Error()}}
+	}
+	return s.validate(doc, "")
+}
+
+func (s *Schema) validate(doc interface{}, pointer string) []Error {
+	if s.always != nil {
+		if *s.always {
+			return nil
+		}
+		return []Error{{pointer, "no value is allowed here"}}
+	}
+
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{pointer, fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !s.matchesType(doc) {
+		fail("must be of type %s, got %s", strings.Join(s.types, " or "), typeOf(doc))
+		// Остальные ключевые слова для значения другого типа бессмысленны
+		return errs
+	}
+	if s.enum != nil && !contains(s.enum, doc) {
+		fail("must be one of %s", marshal(s.enum))
+	}
+	if s.constant != nil && !equal(*s.constant, doc) {
+		fail("must be %s", marshal(*s.constant))
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := pointer + "/" + escape(name)
+			if prop, ok := s.properties[name]; ok {
+				errs = append(errs, prop.validate(v[name], child)...)
+			} else if s.additionalProperties != nil {
+				if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+					errs = append(errs, Error{child, "additional property is not allowed"})
+				} else {
+					errs = append(errs, s.additionalProperties.validate(v[name], child)...)
+				}
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				errs = append(errs, s.items.validate(item, fmt.Sprintf("%s/%d", pointer, i))...)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match pattern %s", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("must be < %v", *s.exclusiveMaximum)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		errs = append(errs, sub.validate(doc, pointer)...)
+	}
+	if s.anyOf != nil && countValid(s.anyOf, doc, pointer) == 0 {
+		fail("must match at least one schema in anyOf")
+	}
+	if s.oneOf != nil {
+		if n := countValid(s.oneOf, doc, pointer); n != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+	if s.not != nil && len(s.not.validate(doc, pointer)) == 0 {
+		fail("must not match the schema in not")
+	}
+	return errs
+}
+
+func countValid(schemas []*Schema, doc interface{}, pointer string) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(sub.validate(doc, pointer)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Schema) matchesType(doc interface{}) bool {
+	actual := typeOf(doc)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(doc interface{}) string {
+	switch v := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+func contains(list []interface{}, doc interface{}) bool {
+	for _, item := range list {
+		if equal(item, doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func marshal(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
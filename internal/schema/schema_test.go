@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	for _, raw := range []string{
+		`not json`,
+		`42`,
+		`{"type": "text"}`,
+		`{"$ref": "#/definitions/x"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"anyOf": []}`,
+		`{"properties": {"a": {"type": 1}}}`,
+	} {
+		_, err := Compile(json.RawMessage(raw))
+		assert.Error(t, err, raw)
+	}
+
+	s, err := Compile(json.RawMessage(`{"title": "ok", "type": "string"}`))
+	require.NoError(t, err)
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title": "ok", "type": "string"}`, string(data))
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Compile(json.RawMessage(`{
+		"type": "object",
+		"required": ["url", "method"],
+		"properties": {
+			"url": {"type": "string", "pattern": "^https?://", "maxLength": 20},
+			"method": {"enum": ["GET", "POST"]},
+			"retries": {"type": "integer", "minimum": 0, "exclusiveMaximum": 5},
+			"headers": {"type": "object", "additionalProperties": {"type": "string"}},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "maxItems": 2},
+			"a/b": {"const": true}
+		},
+		"additionalProperties": false
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		document string
+		errors   []Error
+	}{
+		{
+			name:     "Valid document",
+			document: `{"url": "https://x", "method": "GET", "retries": 4, "headers": {"a": "b"}, "tags": ["x"], "a/b": true}`,
+		},
+		{
+			name:     "Wrong root type",
+			document: `[]`,
+			errors:   []Error{{"", "must be of type object, got array"}},
+		},
+		{
+			name:     "Missing document is null",
+			document: ``,
+			errors:   []Error{{"", "must be of type object, got null"}},
+		},
+		{
+			name:     "Nested violations are reported with pointers",
+			document: `{"url": "ftp://x", "retries": 5.5, "headers": {"a": 1}, "tags": ["", "b", "c"], "a/b": false, "extra": 1}`,
+			errors: []Error{
+				{"", `missing required property "method"`},
+				{"/a~1b", "must be true"},
+				{"/extra", "additional property is not allowed"},
+				{"/headers/a", "must be of type string, got integer"},
+				{"/retries", "must be of type integer, got number"},
+				{"/tags", "must have at most 2 items"},
+				{"/tags/0", "must be at least 1 characters"},
+				{"/url", "must match pattern ^https?://"},
+			},
+		},
+		{
+			name:     "Enum and bounds",
+			document: `{"url": "http://example.com/very/long", "method": "PUT", "retries": 5}`,
+			errors: []Error{
+				{"/method", `must be one of ["GET","POST"]`},
+				{"/retries", "must be < 5"},
+				{"/url", "must be at most 20 characters"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.errors, s.Validate(json.RawMessage(tt.document)))
+		})
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	s, err := Compile(json.RawMessage(`{
+		"anyOf": [{"type": "string"}, {"type": "number"}],
+		"oneOf": [{"type": "integer"}, {"minimum": 10}],
+		"not": {"const": 3}
+	}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, s.Validate(json.RawMessage(`2`)))
+	assert.Empty(t, s.Validate(json.RawMessage(`10.5`)))
+	assert.Equal(t, []Error{{"", "must match exactly one schema in oneOf, matched 2"}}, s.Validate(json.RawMessage(`12`)))
+	assert.Equal(t, []Error{{"", "must not match the schema in not"}}, s.Validate(json.RawMessage(`3`)))
+	assert.Equal(t, []Error{{"", "must match at least one schema in anyOf"}}, s.Validate(json.RawMessage(`true`)))
+
+	never, err := Compile(json.RawMessage(`false`))
+	require.NoError(t, err)
+	assert.Len(t, never.Validate(json.RawMessage(`{}`)), 1)
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"http_api/internal/artifacts"
+	"http_api/models"
+)
+
+// Artifacts возвращает артефакты задачи
+func (s *TaskService) Artifacts(ctx context.Context, id string) ([]models.Artifact, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return nil, err
+	}
+	if s.artifacts == nil {
+		return nil, nil
+	}
+	return s.artifacts.List(id)
+}
+
+// OpenArtifact открывает артефакт задачи для чтения; закрыть файл должен вызывающий
+func (s *TaskService) OpenArtifact(ctx context.Context, id, name string) (artifacts.File, *models.Artifact, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	if s.artifacts == nil {
+		return nil, nil, artifacts.ErrNotFound
+	}
+	return s.artifacts.Open(id, name)
+}
+
+// deleteTaskData удаляет журнал и артефакты задачи
+func (s *TaskService) deleteTaskData(id string) {
+	s.logs.Delete(id)
+	if s.artifacts == nil {
+		return
+	}
+	if err := s.artifacts.Delete(id); err != nil {
+		s.logger.Warn("failed to delete task artifacts", "task_id", id, "error", err)
+	}
+}
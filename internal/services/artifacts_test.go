@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"http_api/internal/artifacts"
+	"http_api/internal/storage"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskServiceArtifacts(t *testing.T) {
+	ctx := context.Background()
+
+	types := tasktypes.NewRegistry()
+	require.NoError(t, types.Register(tasktypes.TaskType{
+		Name: "report",
+		Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+			_, err := artifacts.Put(ctx, "report.csv", "", strings.NewReader("id,total\n1,42\n"))
+			return nil, err
+		}),
+	}))
+
+	t.Run("Executor artifacts are listed, opened and deleted with the task", func(t *testing.T) {
+		store, err := artifacts.NewFSStore(t.TempDir())
+		require.NoError(t, err)
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithTaskTypes(types), WithArtifacts(store))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "export"})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			task, _ := service.GetTask(ctx, task.ID)
+			return task.Status == models.StatusCompleted
+		}, time.Second, 5*time.Millisecond)
+
+		list, err := service.Artifacts(ctx, task.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "report.csv", list[0].Name)
+		assert.Equal(t, int64(14), list[0].Size)
+
+		file, artifact, err := service.OpenArtifact(ctx, task.ID, "report.csv")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "id,total\n1,42\n", string(data))
+		assert.Equal(t, list[0], *artifact)
+
+		_, _, err = service.OpenArtifact(ctx, task.ID, "missing.csv")
+		assert.ErrorIs(t, err, artifacts.ErrNotFound)
+
+		require.NoError(t, service.DeleteTask(ctx, task.ID))
+		_, err = service.Artifacts(ctx, task.ID)
+		assert.ErrorIs(t, err, storage.ErrTaskNotFound)
+		list, err = store.List(task.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("Without a store executors get ErrUnavailable", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "export"})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			task, _ := service.GetTask(ctx, task.ID)
+			return task.Status == models.StatusFailed
+		}, time.Second, 5*time.Millisecond)
+
+		task, err = service.GetTask(ctx, task.ID)
+		require.NoError(t, err)
+		assert.Contains(t, task.Error, artifacts.ErrUnavailable.Error())
+		list, err := service.Artifacts(ctx, task.ID)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"errors"
+	"http_api/models"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("task queue is full")
+
+// dispatcher хранит очереди ожидающих задач по пространствам имен, из которых
+// планировщик выбирает следующую задачу для свободного воркера. Выбор честный:
+// первым обслуживается пространство с наименьшим числом выполняющихся задач,
+// при равенстве - по кругу. Внутри пространства задачи упорядочены по убыванию
+// приоритета, при равном приоритете - в порядке поступления. Задачи типа,
+// достигшего лимита одновременного выполнения, пропускаются.
+type dispatcher struct {
+	mu     sync.Mutex
+	limit  int
+	quotas Quotas
+	// concurrency возвращает лимит одновременного выполнения типа (0 - без ограничения)
+	concurrency func(taskType string) int
+	now         func() time.Time
+
+	queues map[string][]string
+	// namespaces - порядок обхода пространств имен, cursor - последнее обслуженное.
+	// Пространство без ожидающих и выполняющихся задач удаляется (prune).
+	namespaces []string
+	cursor     int
+	queued     map[string]string // id -> пространство имен ожидающей задачи
+	priorities map[string]int    // id -> приоритет ожидающей или выполняющейся задачи
+	types      map[string]string // id -> тип ожидающей или выполняющейся задачи
+	size       int
+
+	queuedTypes  map[string]int // тип -> число ожидающих задач
+	runningTypes map[string]int // тип -> число выполняющихся задач
+
+	running    map[string]int    // пространство имен -> число выполняющихся задач
+	runningIDs map[string]string // id -> пространство имен выполняющейся задачи
+	// Задачи, ожидающие повтора: они еще не в очереди, но занимают место в квоте MaxPending
+	waiting    map[string]int    // пространство имен -> число задач, ожидающих повтора
+	waitingIDs map[string]string // id -> пространство имен задачи, ожидающей повтора
+	created    map[string][]time.Time
+
+	// wake сигнализирует планировщику об изменении очереди
+	wake chan struct{}
+}
+
+func newDispatcher(limit int, quotas Quotas, concurrency func(taskType string) int) *dispatcher {
+	if concurrency == nil {
+		concurrency = func(string) int { return 0 }
+	}
+	return &dispatcher{
+		limit:        limit,
+		quotas:       quotas,
+		concurrency:  concurrency,
+		now:          time.Now,
+		cursor:       -1,
+		queues:       make(map[string][]string),
+		queued:       make(map[string]string),
+		priorities:   make(map[string]int),
+		types:        make(map[string]string),
+		queuedTypes:  make(map[string]int),
+		runningTypes: make(map[string]int),
+		running:      make(map[string]int),
+		runningIDs:   make(map[string]string),
+		waiting:      make(map[string]int),
+		waitingIDs:   make(map[string]string),
+		created:      make(map[string][]time.Time),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// enqueue ставит новую задачу в очередь с учетом общего лимита и квоты пространства имен
+func (d *dispatcher) enqueue(id, namespace, taskType string, priority int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.limit > 0 && d.size >= d.limit {
+		return ErrQueueFull
+	}
+
+	quota := d.quotas.For(namespace)
+	if quota.MaxPending > 0 && d.pending(namespace) >= quota.MaxPending {
+		return &QuotaError{Namespace: namespace, Limit: LimitMaxPending, Max: quota.MaxPending, RetryAfter: 5 * time.Second}
+	}
+	if quota.CreatePerMinute > 0 {
+		recent := d.recentlyCreated(namespace)
+		if len(recent) >= quota.CreatePerMinute {
+			retryAfter := recent[0].Add(time.Minute).Sub(d.now())
+			return &QuotaError{Namespace: namespace, Limit: LimitCreatePerMinute, Max: quota.CreatePerMinute, RetryAfter: retryAfter}
+		}
+		d.created[namespace] = append(recent, d.now())
+	}
+
+	d.priorities[id] = priority
+	d.types[id] = taskType
+	d.push(id, namespace, false)
+	d.notify()
+	return nil
+}
+
+// recentlyCreated возвращает моменты создания задач за последнюю минуту
+func (d *dispatcher) recentlyCreated(namespace string) []time.Time {
+	times := d.created[namespace]
+	cutoff := d.now().Add(-time.Minute)
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	if i == len(times) {
+		delete(d.created, namespace)
+		return nil
+	}
+	times = times[i:]
+	d.created[namespace] = times
+	return times
+}
+
+// pending возвращает число ожидающих задач пространства имен, включая ожидающие повтора
+func (d *dispatcher) pending(namespace string) int {
+	return len(d.queues[namespace]) + d.waiting[namespace]
+}
+
+// postpone учитывает задачу, ожидающую повтора, в квоте ее пространства имен
+// до возврата в очередь через restore или удаления через remove
+func (d *dispatcher) postpone(id, namespace string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.waitingIDs[id]; ok {
+		return
+	}
+	d.waitingIDs[id] = namespace
+	d.waiting[namespace]++
+}
+
+// unpostpone снимает задачу, ожидающую повтора, со счетчиков
+func (d *dispatcher) unpostpone(id string) (string, bool) {
+	namespace, ok := d.waitingIDs[id]
+	if !ok {
+		return "", false
+	}
+	delete(d.waitingIDs, id)
+	d.waiting[namespace]--
+	if d.waiting[namespace] == 0 {
+		delete(d.waiting, namespace)
+	}
+	return namespace, true
+}
+
+// restore возвращает в очередь задачу, уже принятую ранее, без учета лимитов
+func (d *dispatcher) restore(id, namespace, taskType string, priority int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.unpostpone(id)
+	d.priorities[id] = priority
+	d.types[id] = taskType
+	d.push(id, namespace, false)
+	d.notify()
+}
+
+func (d *dispatcher) push(id, namespace string, front bool) {
+	if _, ok := d.queues[namespace]; !ok {
+		d.namespaces = append(d.namespaces, namespace)
+	}
+	queue := d.queues[namespace]
+	pos := 0
+	if !front {
+		// Вставка после последней задачи с не меньшим приоритетом
+		pos = len(queue)
+		for pos > 0 && d.priorities[queue[pos-1]] < d.priorities[id] {
+			pos--
+		}
+	}
+	queue = append(queue, "")
+	copy(queue[pos+1:], queue[pos:])
+	queue[pos] = id
+	d.queues[namespace] = queue
+	d.queued[id] = namespace
+	d.queuedTypes[d.types[id]]++
+	d.size++
+}
+
+func (d *dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove убирает задачу из очереди, если воркер еще не успел ее забрать,
+// или из ожидающих повтора
+func (d *dispatcher) remove(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if namespace, ok := d.unpostpone(id); ok {
+		d.prune(namespace)
+	}
+	if namespace, ok := d.queued[id]; ok {
+		d.unqueue(id)
+		delete(d.priorities, id)
+		delete(d.types, id)
+		d.prune(namespace)
+		d.notify()
+	}
+}
+
+// prune забывает пространство имен, в котором не осталось ожидающих
+// и выполняющихся задач, чтобы список обхода не рос бесконечно
+func (d *dispatcher) prune(namespace string) {
+	if _, ok := d.queues[namespace]; !ok || d.pending(namespace) > 0 || d.running[namespace] > 0 {
+		return
+	}
+	delete(d.queues, namespace)
+	for i, known := range d.namespaces {
+		if known == namespace {
+			d.namespaces = append(d.namespaces[:i], d.namespaces[i+1:]...)
+			// Сохраняем позицию обхода: следующим обслуживается пространство после удаленного
+			if i <= d.cursor {
+				d.cursor--
+			}
+			return
+		}
+	}
+}
+
+// reprioritize переставляет ожидающую задачу в очереди согласно новому приоритету
+func (d *dispatcher) reprioritize(id string, priority int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	namespace, ok := d.queued[id]
+	if !ok || d.priorities[id] == priority {
+		return
+	}
+	d.unqueue(id)
+	d.priorities[id] = priority
+	d.push(id, namespace, false)
+}
+
+func (d *dispatcher) unqueue(id string) bool {
+	namespace, ok := d.queued[id]
+	if !ok {
+		return false
+	}
+	queue := d.queues[namespace]
+	for i, queued := range queue {
+		if queued == id {
+			d.queues[namespace] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	delete(d.queued, id)
+	d.uncount(id)
+	return true
+}
+
+// uncount снимает задачу, покинувшую очередь, со счетчиков ожидающих
+func (d *dispatcher) uncount(id string) {
+	taskType := d.types[id]
+	d.queuedTypes[taskType]--
+	if d.queuedTypes[taskType] == 0 {
+		delete(d.queuedTypes, taskType)
+	}
+	d.size--
+}
+
+// take выбирает следующую задачу, убирает ее из очереди и учитывает как выполняющуюся.
+// Если передать задачу воркеру не удалось, ее нужно вернуть через release.
+func (d *dispatcher) take() (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	chosen, position := -1, -1
+	for i := 1; i <= len(d.namespaces); i++ {
+		idx := (d.cursor + i) % len(d.namespaces)
+		namespace := d.namespaces[idx]
+		if limit := d.quotas.For(namespace).MaxProcessing; limit > 0 && d.running[namespace] >= limit {
+			continue
+		}
+		pos := d.next(namespace)
+		if pos < 0 {
+			continue
+		}
+		if chosen < 0 || d.running[namespace] < d.running[d.namespaces[chosen]] {
+			chosen, position = idx, pos
+		}
+	}
+	if chosen < 0 {
+		return "", false
+	}
+
+	namespace := d.namespaces[chosen]
+	queue := d.queues[namespace]
+	id := queue[position]
+	d.queues[namespace] = append(queue[:position:position], queue[position+1:]...)
+	delete(d.queued, id)
+	d.uncount(id)
+
+	d.cursor = chosen
+	d.running[namespace]++
+	d.runningIDs[id] = namespace
+	d.runningTypes[d.types[id]]++
+	return id, true
+}
+
+// next возвращает позицию первой задачи пространства имен, тип которой
+// не достиг лимита одновременного выполнения, или -1
+func (d *dispatcher) next(namespace string) int {
+	for pos, id := range d.queues[namespace] {
+		taskType := d.types[id]
+		if limit := d.concurrency(taskType); limit == 0 || d.runningTypes[taskType] < limit {
+			return pos
+		}
+	}
+	return -1
+}
+
+// release возвращает взятую задачу в начало очереди ее пространства имен.
+// Планировщик не будится: он сам вызывает release, пересматривая выбор.
+func (d *dispatcher) release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	namespace, ok := d.runningIDs[id]
+	if !ok {
+		return
+	}
+	priority, taskType := d.priorities[id], d.types[id]
+	d.stop(id, namespace)
+	d.priorities[id] = priority
+	d.types[id] = taskType
+	d.push(id, namespace, true)
+}
+
+// finish освобождает место пространства имен после выполнения задачи
+func (d *dispatcher) finish(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if namespace, ok := d.runningIDs[id]; ok {
+		d.stop(id, namespace)
+		d.prune(namespace)
+		d.notify()
+	}
+}
+
+func (d *dispatcher) stop(id, namespace string) {
+	taskType := d.types[id]
+	d.runningTypes[taskType]--
+	if d.runningTypes[taskType] == 0 {
+		delete(d.runningTypes, taskType)
+	}
+	delete(d.runningIDs, id)
+	delete(d.priorities, id)
+	delete(d.types, id)
+	d.running[namespace]--
+	if d.running[namespace] == 0 {
+		delete(d.running, namespace)
+	}
+}
+
+// full сообщает, что очередь достигла лимита
+func (d *dispatcher) full() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.limit > 0 && d.size >= d.limit
+}
+
+func (d *dispatcher) depth() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// typeUsage возвращает число ожидающих и выполняющихся задач типа
+func (d *dispatcher) typeUsage(taskType string) (queued, running int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.queuedTypes[taskType], d.runningTypes[taskType]
+}
+
+// usage возвращает загрузку пространства имен относительно его квоты
+func (d *dispatcher) usage(namespace string) models.QuotaUsage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	quota := d.quotas.For(namespace)
+	return models.QuotaUsage{
+		Namespace:         namespace,
+		Pending:           models.Usage{Used: d.pending(namespace), Limit: quota.MaxPending},
+		Processing:        models.Usage{Used: d.running[namespace], Limit: quota.MaxProcessing},
+		CreatedLastMinute: models.Usage{Used: len(d.recentlyCreated(namespace)), Limit: quota.CreatePerMinute},
+	}
+}
+
+// knownNamespaces перечисляет пространства имен с задачами или явной квотой
+func (d *dispatcher) knownNamespaces() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var namespaces []string
+	add := func(namespace string) {
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	for _, namespace := range d.namespaces {
+		add(namespace)
+	}
+	for namespace := range d.quotas.Namespaces {
+		add(namespace)
+	}
+	return namespaces
+}
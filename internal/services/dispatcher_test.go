@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func takeAll(d *dispatcher) []string {
+	var ids []string
+	for {
+		id, ok := d.take()
+		if !ok {
+			return ids
+		}
+		ids = append(ids, id)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Fair share across namespaces", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, nil)
+		for _, id := range []string{"a1", "a2", "a3", "a4"} {
+			require.NoError(t, d.enqueue(id, "team-a", "default", 0))
+		}
+		require.NoError(t, d.enqueue("b1", "team-b", "default", 0))
+		require.NoError(t, d.enqueue("b2", "team-b", "default", 0))
+
+		// Пространство b не ждет, пока выполнится весь поток a
+		assert.Equal(t, []string{"a1", "b1", "a2", "b2", "a3", "a4"}, takeAll(d))
+	})
+
+	t.Run("Higher priority first within namespace", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, nil)
+		require.NoError(t, d.enqueue("low", "team-a", "default", 0))
+		require.NoError(t, d.enqueue("high", "team-a", "default", 5))
+		require.NoError(t, d.enqueue("mid1", "team-a", "default", 1))
+		require.NoError(t, d.enqueue("mid2", "team-a", "default", 1))
+		d.reprioritize("low", 3)
+
+		assert.Equal(t, []string{"high", "low", "mid1", "mid2"}, takeAll(d))
+	})
+
+	t.Run("Least busy namespace goes first", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+		id, _ := d.take()
+		assert.Equal(t, "a1", id)
+
+		require.NoError(t, d.enqueue("b1", "team-b", "default", 0))
+		id, _ = d.take()
+		assert.Equal(t, "b1", id)
+	})
+
+	t.Run("Max processing per namespace", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{Default: Quota{MaxProcessing: 1}}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+
+		assert.Equal(t, []string{"a1"}, takeAll(d))
+		d.finish("a1")
+		assert.Equal(t, []string{"a2"}, takeAll(d))
+	})
+
+	t.Run("Release keeps position", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+
+		id, _ := d.take()
+		d.release(id)
+		assert.Equal(t, []string{"a1", "a2"}, takeAll(d))
+	})
+
+	t.Run("Max pending per namespace", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{
+			Default:    Quota{MaxPending: 1},
+			Namespaces: map[string]Quota{"big": {MaxPending: 2}},
+		}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		err := d.enqueue("a2", "team-a", "default", 0)
+
+		var quotaErr *QuotaError
+		require.True(t, errors.As(err, &quotaErr))
+		assert.True(t, errors.Is(err, ErrQuotaExceeded))
+		assert.Equal(t, LimitMaxPending, quotaErr.Limit)
+
+		require.NoError(t, d.enqueue("b1", "big", "default", 0))
+		require.NoError(t, d.enqueue("b2", "big", "default", 0))
+	})
+
+	t.Run("Retry backoff counts toward max pending", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{Default: Quota{MaxPending: 1}}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		id, ok := d.take()
+		require.True(t, ok)
+		d.postpone(id, "team-a")
+		d.finish(id)
+
+		err := d.enqueue("a2", "team-a", "default", 0)
+		assert.True(t, errors.Is(err, ErrQuotaExceeded))
+		assert.Equal(t, 1, d.usage("team-a").Pending.Used)
+
+		d.restore(id, "team-a", "default", 0)
+		assert.Equal(t, 1, d.usage("team-a").Pending.Used)
+		assert.Equal(t, []string{"a1"}, takeAll(d))
+		d.finish("a1")
+		assert.Equal(t, 0, d.usage("team-a").Pending.Used)
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+	})
+
+	t.Run("Remove releases retry backoff", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{Default: Quota{MaxPending: 1}}, nil)
+		d.postpone("a1", "team-a")
+		d.remove("a1")
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+	})
+
+	t.Run("Idle namespaces are pruned", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, nil)
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		require.NoError(t, d.enqueue("b1", "team-b", "default", 0))
+		require.NoError(t, d.enqueue("c1", "team-c", "default", 0))
+
+		id, ok := d.take()
+		require.True(t, ok)
+		assert.Equal(t, "a1", id)
+		d.finish(id)
+		d.remove("b1")
+		assert.Equal(t, []string{"team-c"}, d.namespaces)
+		assert.Equal(t, []string{"c1"}, takeAll(d))
+		d.finish("c1")
+		assert.Empty(t, d.namespaces)
+		assert.Empty(t, d.queues)
+
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+		assert.Equal(t, []string{"a2"}, takeAll(d))
+	})
+
+	t.Run("Create rate per minute", func(t *testing.T) {
+		now := time.Unix(1_700_000_000, 0)
+		d := newDispatcher(0, Quotas{Default: Quota{CreatePerMinute: 2}}, nil)
+		d.now = func() time.Time { return now }
+
+		require.NoError(t, d.enqueue("a1", "team-a", "default", 0))
+		now = now.Add(20 * time.Second)
+		require.NoError(t, d.enqueue("a2", "team-a", "default", 0))
+
+		var quotaErr *QuotaError
+		require.True(t, errors.As(d.enqueue("a3", "team-a", "default", 0), &quotaErr))
+		assert.Equal(t, LimitCreatePerMinute, quotaErr.Limit)
+		assert.Equal(t, 40*time.Second, quotaErr.RetryAfter)
+
+		now = now.Add(41 * time.Second)
+		require.NoError(t, d.enqueue("a3", "team-a", "default", 0))
+		assert.Equal(t, 2, d.usage("team-a").CreatedLastMinute.Used)
+	})
+
+	t.Run("Concurrency limit per task type", func(t *testing.T) {
+		d := newDispatcher(0, Quotas{}, func(taskType string) int {
+			if taskType == "export" {
+				return 1
+			}
+			return 0
+		})
+		require.NoError(t, d.enqueue("e1", "team-a", "export", 0))
+		require.NoError(t, d.enqueue("e2", "team-a", "export", 0))
+		require.NoError(t, d.enqueue("r1", "team-a", "report", 0))
+
+		// Второй export ждет, но не задерживает задачи других типов
+		assert.Equal(t, []string{"e1", "r1"}, takeAll(d))
+		queued, running := d.typeUsage("export")
+		assert.Equal(t, 1, queued)
+		assert.Equal(t, 1, running)
+
+		d.finish("e1")
+		assert.Equal(t, []string{"e2"}, takeAll(d))
+	})
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"http_api/internal/labels"
+	"http_api/internal/storage"
+	"http_api/internal/validation"
+	"http_api/models"
+)
+
+// requireSelector не дает массовой операции затронуть все задачи пространства имен
+func requireSelector(selector labels.Selector) error {
+	if selector.Empty() {
+		return &validation.Error{Errors: []validation.FieldError{{Parameter: "selector", Message: "is required for bulk operations"}}}
+	}
+	return nil
+}
+
+// CancelTasks отменяет ожидающие и выполняющиеся задачи, подходящие под селектор.
+// Задачи в конечном статусе пропускаются.
+func (s *TaskService) CancelTasks(ctx context.Context, selector labels.Selector) (*models.BulkResult, error) {
+	return s.bulk(ctx, selector, func(id string) error {
+		_, err := s.CancelTask(ctx, id)
+		return err
+	})
+}
+
+// DeleteTasks удаляет задачи, подходящие под селектор
+func (s *TaskService) DeleteTasks(ctx context.Context, selector labels.Selector) (*models.BulkResult, error) {
+	return s.bulk(ctx, selector, func(id string) error {
+		return s.DeleteTask(ctx, id)
+	})
+}
+
+func (s *TaskService) bulk(ctx context.Context, selector labels.Selector, apply func(id string) error) (*models.BulkResult, error) {
+	if err := requireSelector(selector); err != nil {
+		return nil, err
+	}
+	tasks, err := s.tasks(ctx).Select(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkResult{Matched: len(tasks), TaskIDs: []string{}}
+	for _, task := range tasks {
+		err := apply(task.ID)
+		// Задача могла завершиться или быть удаленной после выборки
+		if errors.Is(err, storage.ErrInvalidState) || errors.Is(err, storage.ErrTaskNotFound) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result.TaskIDs = append(result.TaskIDs, task.ID)
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"http_api/internal/labels"
+	"http_api/internal/storage"
+	"http_api/internal/validation"
+	"http_api/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, selector string) labels.Selector {
+	s, err := labels.Parse(selector)
+	require.NoError(t, err)
+	return s
+}
+
+func TestTaskServiceLabels(t *testing.T) {
+	ctx := context.Background()
+	service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+
+	create := func(description string, set map[string]string) *models.Task {
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: description, Labels: set})
+		require.NoError(t, err)
+		return task
+	}
+	prod := create("prod", map[string]string{"env": "prod", "team": "a"})
+	draft := create("draft", map[string]string{"env": "prod", "draft": "true"})
+	dev := create("dev", map[string]string{"env": "dev"})
+
+	t.Run("Invalid labels are rejected", func(t *testing.T) {
+		_, err := service.CreateTask(ctx, models.TaskCreate{Description: "bad", Labels: map[string]string{"bad key": "x", "ok": "bad value"}})
+		var validationErr *validation.Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Errors, 2)
+	})
+
+	t.Run("List by selector", func(t *testing.T) {
+		list, err := service.ListTasks(ctx, mustParse(t, "env=prod,!draft"))
+		require.NoError(t, err)
+		require.Len(t, list.Tasks, 1)
+		assert.Equal(t, prod.ID, list.Tasks[0].ID)
+
+		list, err = service.ListTasks(WithNamespace(ctx, "other"), mustParse(t, "env"))
+		require.NoError(t, err)
+		assert.Empty(t, list.Tasks)
+	})
+
+	t.Run("Bulk operations require selector", func(t *testing.T) {
+		_, err := service.DeleteTasks(ctx, nil)
+		var validationErr *validation.Error
+		assert.True(t, errors.As(err, &validationErr))
+	})
+
+	t.Run("Bulk cancel skips finished tasks", func(t *testing.T) {
+		_, err := service.CancelTask(ctx, draft.ID)
+		require.NoError(t, err)
+
+		result, err := service.CancelTasks(ctx, mustParse(t, "env in (prod,dev)"))
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Matched)
+		assert.ElementsMatch(t, []string{prod.ID, dev.ID}, result.TaskIDs)
+	})
+
+	t.Run("Bulk delete", func(t *testing.T) {
+		result, err := service.DeleteTasks(ctx, mustParse(t, "env=prod"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{prod.ID, draft.ID}, result.TaskIDs)
+
+		list, _ := service.ListTasks(ctx, nil)
+		require.Len(t, list.Tasks, 1)
+		assert.Equal(t, dev.ID, list.Tasks[0].ID)
+	})
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"http_api/internal/metrics"
+	"http_api/models"
+	"time"
+)
+
+type serviceMetrics struct {
+	created   *metrics.CounterVec
+	completed *metrics.CounterVec
+	failed    *metrics.CounterVec
+	cancelled *metrics.CounterVec
+	retried   *metrics.CounterVec
+	panics    *metrics.CounterVec
+	reaped    *metrics.CounterVec
+	queueWait *metrics.HistogramVec
+	execution *metrics.HistogramVec
+}
+
+func newServiceMetrics(reg *metrics.Registry, s *TaskService) *serviceMetrics {
+	m := &serviceMetrics{
+		created:   reg.NewCounterVec("taskapi_tasks_created_total", "Number of created tasks.", "type"),
+		completed: reg.NewCounterVec("taskapi_tasks_completed_total", "Number of successfully completed tasks.", "type"),
+		failed:    reg.NewCounterVec("taskapi_tasks_failed_total", "Number of failed tasks.", "type"),
+		cancelled: reg.NewCounterVec("taskapi_tasks_cancelled_total", "Number of cancelled tasks.", "type"),
+		retried:   reg.NewCounterVec("taskapi_tasks_retried_total", "Number of failed attempts scheduled for retry.", "type"),
+		panics:    reg.NewCounterVec("taskapi_task_panics_total", "Number of recovered panics during task processing.", "type"),
+		reaped:    reg.NewCounterVec("taskapi_tasks_reaped_total", "Number of finished tasks removed by the retention policy.", "status", "reason"),
+		queueWait: reg.NewHistogramVec("taskapi_task_queue_wait_seconds",
+			"Time between task creation and start of processing.", metrics.TaskBuckets, "type"),
+		execution: reg.NewHistogramVec("taskapi_task_execution_duration_seconds",
+			"Duration of task processing.", metrics.TaskBuckets, "type"),
+	}
+
+	reg.NewGaugeFunc("taskapi_tasks_pending", "Number of tasks waiting to be processed.", func() float64 {
+		return float64(s.countByStatus(models.StatusPending))
+	})
+	reg.NewGaugeFunc("taskapi_tasks_processing", "Number of tasks being processed.", func() float64 {
+		return float64(s.countByStatus(models.StatusProcessing))
+	})
+	reg.NewGaugeFunc("taskapi_task_queue_depth", "Number of tasks in the dispatch queue.", func() float64 {
+		return float64(s.dispatcher.depth())
+	})
+	return m
+}
+
+// statusCounter - хранилище, которое ведет счетчики задач по статусам
+type statusCounter interface {
+	CountByStatus(status models.TaskStatus) int
+}
+
+// countByStatus берет счетчик хранилища, а для хранилищ без счетчиков
+// перебирает все задачи
+func (s *TaskService) countByStatus(status models.TaskStatus) int {
+	if counter, ok := s.storage.(statusCounter); ok {
+		return counter.CountByStatus(status)
+	}
+
+	tasks, err := s.storage.GetAll()
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, task := range tasks {
+		if task.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// typeLabel возвращает тип задачи; у задач, созданных до появления типов, он пустой
+func typeLabel(task *models.Task) string {
+	if task.Type == "" {
+		return models.DefaultTaskType
+	}
+	return task.Type
+}
+
+func seconds(from, to time.Time) float64 {
+	return to.Sub(from).Seconds()
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"http_api/internal/storage"
+	"http_api/models"
+)
+
+// AllNamespaces в контексте снимает ограничение одним пространством имен.
+// Обработчик устанавливает его только для администраторов.
+const AllNamespaces = "*"
+
+type namespaceKey struct{}
+
+// WithNamespace задает пространство имен, в котором выполняются операции с задачами
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom возвращает пространство имен запроса, по умолчанию models.DefaultNamespace
+func NamespaceFrom(ctx context.Context) string {
+	if namespace, ok := ctx.Value(namespaceKey{}).(string); ok && namespace != "" {
+		return namespace
+	}
+	return models.DefaultNamespace
+}
+
+// tasks возвращает хранилище, ограниченное пространством имен запроса
+func (s *TaskService) tasks(ctx context.Context) storage.TaskStorage {
+	namespace := NamespaceFrom(ctx)
+	if namespace == AllNamespaces {
+		return s.storage
+	}
+	return storage.InNamespace(s.storage, namespace)
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"http_api/internal/storage"
+	"http_api/internal/validation"
+	"http_api/models"
+	"log/slog"
+)
+
+// UpdateTask заменяет изменяемые поля задачи (семантика PUT)
+func (s *TaskService) UpdateTask(ctx context.Context, id string, update models.TaskUpdate) (*models.Task, error) {
+	updatedTask, err := s.tasks(ctx).Update(id, func(task *models.Task) (*models.Task, error) {
+		if err := checkPrecondition(ctx, task); err != nil {
+			return nil, err
+		}
+		if err := s.applyUpdate(task, update); err != nil {
+			return nil, err
+		}
+		return task, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+
+	s.dispatcher.reprioritize(id, updatedTask.Priority)
+	s.logTask(ctx, slog.LevelInfo, "task updated", updatedTask)
+	return updatedTask, nil
+}
+
+// PatchTask применяет к изменяемым полям задачи JSON Merge Patch (RFC 7396):
+// null удаляет значение, объекты labels и metadata сливаются по ключам
+func (s *TaskService) PatchTask(ctx context.Context, id string, patch map[string]interface{}) (*models.Task, error) {
+	if err := s.rules.CheckPatch(patch); err != nil {
+		return nil, err
+	}
+
+	updatedTask, err := s.tasks(ctx).Update(id, func(task *models.Task) (*models.Task, error) {
+		if err := checkPrecondition(ctx, task); err != nil {
+			return nil, err
+		}
+
+		current, err := toDocument(models.TaskUpdate{
+			Description: &task.Description,
+			Labels:      task.Labels,
+			Priority:    task.Priority,
+			Metadata:    task.Metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+		update, err := decodeUpdate(mergePatch(current, patch).(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.applyUpdate(task, update); err != nil {
+			return nil, err
+		}
+		return task, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch task: %w", err)
+	}
+
+	s.dispatcher.reprioritize(id, updatedTask.Priority)
+	s.logTask(ctx, slog.LevelInfo, "task updated", updatedTask)
+	return updatedTask, nil
+}
+
+// applyUpdate переносит изменяемые поля в задачу. Приоритет влияет только
+// на порядок в очереди, поэтому менять его можно лишь у ожидающей задачи.
+func (s *TaskService) applyUpdate(task *models.Task, update models.TaskUpdate) error {
+	if err := s.rules.Update(update); err != nil {
+		return err
+	}
+	if update.Priority != task.Priority && task.Status != models.StatusPending {
+		return fmt.Errorf("%w: priority can only be changed while task is pending", storage.ErrInvalidState)
+	}
+
+	task.Description = ""
+	if update.Description != nil {
+		task.Description = *update.Description
+	}
+	task.Priority = update.Priority
+	// Новые карты: хранилище отдает неглубокие копии задач
+	task.Labels = nil
+	if len(update.Labels) > 0 {
+		task.Labels = make(map[string]string, len(update.Labels))
+		for k, v := range update.Labels {
+			task.Labels[k] = v
+		}
+	}
+	task.Metadata = nil
+	if len(update.Metadata) > 0 {
+		task.Metadata = update.Metadata
+	}
+	return nil
+}
+
+func toDocument(update models.TaskUpdate) (map[string]interface{}, error) {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// decodeUpdate приводит документ после слияния к типам полей задачи
+func decodeUpdate(doc map[string]interface{}) (models.TaskUpdate, error) {
+	var update models.TaskUpdate
+	var errs validation.Errors
+	for name, value := range doc {
+		data, _ := json.Marshal(value)
+		var err error
+		switch name {
+		case "description":
+			err = json.Unmarshal(data, &update.Description)
+		case "labels":
+			err = json.Unmarshal(data, &update.Labels)
+		case "priority":
+			err = json.Unmarshal(data, &update.Priority)
+		case "metadata":
+			err = json.Unmarshal(data, &update.Metadata)
+		}
+		if err != nil {
+			errs.Add(validation.Pointer(name), "invalid value type")
+		}
+	}
+	return update, errs.Err()
+}
+
+// mergePatch возвращает результат применения patch к target по RFC 7396,
+// не изменяя исходный документ
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	result := make(map[string]interface{})
+	if targetObj, ok := target.(map[string]interface{}); ok {
+		for k, v := range targetObj {
+			result[k] = v
+		}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(result, k)
+		} else {
+			result[k] = mergePatch(result[k], v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"http_api/internal/storage"
+	"http_api/internal/validation"
+	"http_api/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{
+		"description": "old",
+		"labels":      map[string]interface{}{"env": "dev", "team": "a"},
+		"metadata":    map[string]interface{}{"build": map[string]interface{}{"id": 1.0, "ref": "main"}},
+	}
+	patch := map[string]interface{}{
+		"description": nil,
+		"labels":      map[string]interface{}{"env": "prod", "team": nil},
+		"metadata":    map[string]interface{}{"build": map[string]interface{}{"ref": nil}},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"labels":   map[string]interface{}{"env": "prod"},
+		"metadata": map[string]interface{}{"build": map[string]interface{}{"id": 1.0}},
+	}, mergePatch(target, patch))
+	// Исходный документ не меняется
+	assert.Equal(t, "old", target["description"])
+	assert.Equal(t, "a", target["labels"].(map[string]interface{})["team"])
+}
+
+func TestTaskServicePatch(t *testing.T) {
+	ctx := context.Background()
+	service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+	task, err := service.CreateTask(ctx, models.TaskCreate{Description: "patch me"})
+	require.NoError(t, err)
+
+	t.Run("Merge patch keeps omitted fields", func(t *testing.T) {
+		patched, err := service.PatchTask(ctx, task.ID, map[string]interface{}{
+			"labels":   map[string]interface{}{"env": "prod"},
+			"priority": 3.0,
+			"metadata": map[string]interface{}{"owner": "ci"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "patch me", patched.Description)
+		assert.Equal(t, map[string]string{"env": "prod"}, patched.Labels)
+		assert.Equal(t, 3, patched.Priority)
+		assert.Equal(t, map[string]interface{}{"owner": "ci"}, patched.Metadata)
+	})
+
+	t.Run("PUT replaces all mutable fields", func(t *testing.T) {
+		replaced := "replaced"
+		updated, err := service.UpdateTask(ctx, task.ID, models.TaskUpdate{Description: &replaced})
+		require.NoError(t, err)
+		assert.Equal(t, "replaced", updated.Description)
+		assert.Nil(t, updated.Labels)
+		assert.Zero(t, updated.Priority)
+		assert.Nil(t, updated.Metadata)
+	})
+
+	t.Run("Immutable and unknown fields are listed", func(t *testing.T) {
+		_, err := service.PatchTask(ctx, task.ID, map[string]interface{}{
+			"status":      "completed",
+			"id":          "other",
+			"colour":      "red",
+			"description": "ok",
+		})
+		var validationErr *validation.Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []validation.FieldError{
+			{Pointer: "/colour", Message: "unknown field"},
+			{Pointer: "/id", Message: "field is immutable"},
+			{Pointer: "/status", Message: "field is immutable"},
+		}, validationErr.Errors)
+	})
+
+	t.Run("Invalid value types", func(t *testing.T) {
+		_, err := service.PatchTask(ctx, task.ID, map[string]interface{}{"priority": "high", "labels": []interface{}{"x"}})
+		var validationErr *validation.Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Errors, 2)
+	})
+
+	t.Run("Priority is fixed once task left pending", func(t *testing.T) {
+		_, err := service.CancelTask(ctx, task.ID)
+		require.NoError(t, err)
+
+		_, err = service.PatchTask(ctx, task.ID, map[string]interface{}{"priority": 5.0})
+		assert.ErrorIs(t, err, storage.ErrInvalidState)
+
+		patched, err := service.PatchTask(ctx, task.ID, map[string]interface{}{"description": "still editable"})
+		require.NoError(t, err)
+		assert.Equal(t, "still editable", patched.Description)
+	})
+}
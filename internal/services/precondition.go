@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"http_api/models"
+)
+
+// ErrPreconditionFailed - версия задачи не совпала с ожидаемой клиентом (If-Match)
+var ErrPreconditionFailed = errors.New("task version does not match")
+
+// Precondition проверяет текущую версию задачи перед изменением
+type Precondition func(version int64) bool
+
+type preconditionKey struct{}
+
+// WithPrecondition задает условие, которое проверяется атомарно с изменением
+// задачи в UpdateTask, PatchTask, CancelTask и DeleteTask
+func WithPrecondition(ctx context.Context, p Precondition) context.Context {
+	return context.WithValue(ctx, preconditionKey{}, p)
+}
+
+func checkPrecondition(ctx context.Context, task *models.Task) error {
+	if p, ok := ctx.Value(preconditionKey{}).(Precondition); ok && !p(task.Version) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
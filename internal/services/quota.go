@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"http_api/models"
+	"sort"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// Названия лимитов в QuotaError
+const (
+	LimitMaxPending      = "max_pending"
+	LimitCreatePerMinute = "create_per_minute"
+)
+
+// Quota ограничивает одно пространство имен; 0 - без ограничения
+type Quota struct {
+	MaxPending      int
+	MaxProcessing   int
+	CreatePerMinute int
+}
+
+// Quotas задает квоту по умолчанию и переопределения для отдельных пространств имен
+type Quotas struct {
+	Default    Quota
+	Namespaces map[string]Quota
+}
+
+func (q Quotas) For(namespace string) Quota {
+	if quota, ok := q.Namespaces[namespace]; ok {
+		return quota
+	}
+	return q.Default
+}
+
+// QuotaError сообщает, какой лимит пространства имен не позволил создать задачу
+type QuotaError struct {
+	Namespace  string
+	Limit      string
+	Max        int
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("namespace %s exceeded %s quota (%d)", e.Namespace, e.Limit, e.Max)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// WithQuotas включает квоты пространств имен
+func WithQuotas(quotas Quotas) Option {
+	return func(s *TaskService) {
+		s.quotas = quotas
+	}
+}
+
+// Quotas возвращает загрузку пространства имен запроса, а для AllNamespaces -
+// всех пространств с задачами или явно заданной квотой
+func (s *TaskService) Quotas(ctx context.Context) *models.QuotaList {
+	namespaces := []string{NamespaceFrom(ctx)}
+	if namespaces[0] == AllNamespaces {
+		namespaces = s.dispatcher.knownNamespaces()
+		sort.Strings(namespaces)
+	}
+
+	list := &models.QuotaList{Quotas: make([]models.QuotaUsage, 0, len(namespaces))}
+	for _, namespace := range namespaces {
+		list.Quotas = append(list.Quotas, s.dispatcher.usage(namespace))
+	}
+	return list
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"http_api/models"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+const defaultRetentionInterval = time.Minute
+
+// Причины удаления задач политикой хранения
+const (
+	ReapExpired = "expired"
+	ReapEvicted = "evicted"
+)
+
+// Archiver сохраняет задачи перед удалением политикой хранения
+type Archiver interface {
+	Archive(tasks []models.Task) error
+}
+
+// Retention - политика хранения завершенных задач. Незавершенные задачи
+// не удаляются никогда.
+type Retention struct {
+	// Сроки хранения после завершения по статусам (0 - бессрочно);
+	// TTLSeconds задачи имеет приоритет
+	Completed time.Duration
+	Failed    time.Duration
+	Cancelled time.Duration
+	// MaxTasks ограничивает общее число задач: при превышении удаляются
+	// завершенные задачи, начиная с самых давних (0 - без ограничения)
+	MaxTasks int
+	// Interval - период проверки
+	Interval time.Duration
+	// Archive, если задан, получает задачи перед удалением; при ошибке
+	// архивации задачи не удаляются
+	Archive Archiver
+}
+
+// enabled сообщает, что политика что-то удаляет без сроков отдельных задач
+func (r Retention) enabled() bool {
+	return r.Completed > 0 || r.Failed > 0 || r.Cancelled > 0 || r.MaxTasks > 0
+}
+
+// ttl возвращает срок хранения завершенной задачи (0 - бессрочно)
+func (r Retention) ttl(task *models.Task) time.Duration {
+	if task.TTLSeconds > 0 {
+		// Срок задачи, сохраненной до ограничения ttl_seconds, может не поместиться в Duration
+		return time.Duration(min(int64(task.TTLSeconds), math.MaxInt64/int64(time.Second))) * time.Second
+	}
+	switch task.Status {
+	case models.StatusCompleted:
+		return r.Completed
+	case models.StatusFailed:
+		return r.Failed
+	case models.StatusCancelled:
+		return r.Cancelled
+	}
+	return 0
+}
+
+// WithRetention задает политику хранения завершенных задач
+func WithRetention(retention Retention) Option {
+	return func(s *TaskService) {
+		s.retention = retention
+	}
+}
+
+// finishedAt возвращает момент завершения задачи
+func finishedAt(task *models.Task) time.Time {
+	switch {
+	case task.CompletedAt != nil:
+		return *task.CompletedAt
+	case task.CancelledAt != nil:
+		return *task.CancelledAt
+	}
+	return task.CreatedAt
+}
+
+// startReaper запускает периодическую проверку хранения, если она еще не
+// запущена. Без политики хранения проверка запускается только для задачи
+// с собственным ttl_seconds; после Shutdown не запускается.
+func (s *TaskService) startReaper() {
+	s.reaperOnce.Do(func() {
+		s.wg.Add(1)
+		go s.reap(s.reaperCtx)
+	})
+}
+
+func (s *TaskService) reap(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.retention.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Reap(ctx)
+		}
+	}
+}
+
+// Reap удаляет завершенные задачи с истекшим сроком хранения и, если задач
+// больше MaxTasks, самые давно завершенные. Возвращает число удаленных задач.
+func (s *TaskService) Reap(ctx context.Context) int {
+	tasks, err := s.storage.GetAll()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "retention sweep failed", "error", err)
+		return 0
+	}
+
+	now := time.Now()
+	var expired, kept []models.Task
+	for _, task := range tasks {
+		if !task.Status.IsTerminal() {
+			continue
+		}
+		if ttl := s.retention.ttl(&task); ttl > 0 && now.Sub(finishedAt(&task)) >= ttl {
+			expired = append(expired, task)
+		} else {
+			kept = append(kept, task)
+		}
+	}
+
+	removedExpired := s.removeTasks(ctx, expired, ReapExpired)
+
+	// Превышение считаем по фактически удаленным: не удаленные из-за ошибки
+	// архива задачи по-прежнему занимают место
+	var evicted []models.Task
+	if over := len(tasks) - removedExpired - s.retention.MaxTasks; s.retention.MaxTasks > 0 && over > 0 {
+		sort.Slice(kept, func(i, j int) bool {
+			return finishedAt(&kept[i]).Before(finishedAt(&kept[j]))
+		})
+		evicted = kept[:min(over, len(kept))]
+	}
+	removedEvicted := s.removeTasks(ctx, evicted, ReapEvicted)
+	removed := removedExpired + removedEvicted
+	if removed > 0 {
+		s.logger.InfoContext(ctx, "retention sweep removed tasks", "expired", removedExpired, "evicted", removedEvicted)
+	}
+	return removed
+}
+
+// removeTasks архивирует и удаляет задачи вместе с журналами и артефактами
+func (s *TaskService) removeTasks(ctx context.Context, tasks []models.Task, reason string) int {
+	if len(tasks) == 0 {
+		return 0
+	}
+	if s.retention.Archive != nil {
+		if err := s.retention.Archive.Archive(tasks); err != nil {
+			s.logger.ErrorContext(ctx, "failed to archive tasks, keeping them", "reason", reason, "count", len(tasks), "error", err)
+			return 0
+		}
+	}
+
+	removed := 0
+	for i := range tasks {
+		task := &tasks[i]
+		if !s.storage.Delete(task.ID) {
+			continue
+		}
+		s.deleteTaskData(task.ID)
+		s.metrics.reaped.WithLabelValues(string(task.Status), reason).Inc()
+		s.logTask(ctx, slog.LevelDebug, "task removed by retention policy", task, "reason", reason)
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"http_api/internal/metrics"
+	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/models"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type archiveFunc func([]models.Task) error
+
+func (f archiveFunc) Archive(tasks []models.Task) error {
+	return f(tasks)
+}
+
+// finishedTask создает задачу, завершившуюся age назад
+func finishedTask(store storage.TaskStorage, id string, status models.TaskStatus, age time.Duration, ttlSeconds int) {
+	at := time.Now().Add(-age)
+	task := &models.Task{ID: id, Status: status, CreatedAt: at.Add(-time.Minute), TTLSeconds: ttlSeconds}
+	if status == models.StatusCancelled {
+		task.CancelledAt = &at
+	} else if status.IsTerminal() {
+		task.CompletedAt = &at
+	}
+	store.Create(task)
+}
+
+func ids(store storage.TaskStorage) []string {
+	tasks, _ := store.GetAll()
+	var result []string
+	for _, task := range tasks {
+		result = append(result, task.ID)
+	}
+	return result
+}
+
+func TestTaskServiceRetention(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Expired tasks are removed by status TTL or their own ttl", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		logs := tasklogs.NewStore(0)
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(0), WithMetrics(reg), WithTaskLogs(logs), WithRetention(Retention{
+			Completed: 24 * time.Hour,
+			Failed:    7 * 24 * time.Hour,
+		}))
+
+		finishedTask(store, "old-completed", models.StatusCompleted, 25*time.Hour, 0)
+		finishedTask(store, "new-completed", models.StatusCompleted, time.Hour, 0)
+		finishedTask(store, "old-failed", models.StatusFailed, 25*time.Hour, 0)
+		finishedTask(store, "short-ttl", models.StatusCompleted, time.Hour, 60)
+		finishedTask(store, "long-ttl", models.StatusCompleted, 25*time.Hour, 30*24*3600)
+		finishedTask(store, "huge-ttl", models.StatusCompleted, 25*time.Hour, math.MaxInt)
+		// Без срока для статуса задача хранится бессрочно, если не задан ее ttl
+		finishedTask(store, "cancelled", models.StatusCancelled, 1000*time.Hour, 0)
+		finishedTask(store, "cancelled-ttl", models.StatusCancelled, time.Hour, 60)
+		finishedTask(store, "pending", models.StatusPending, 1000*time.Hour, 1)
+		logs.Append("old-completed", tasklogs.System, "attempt 1 started")
+
+		assert.Equal(t, 3, service.Reap(ctx))
+		assert.ElementsMatch(t, []string{"new-completed", "old-failed", "long-ttl", "huge-ttl", "cancelled", "pending"}, ids(store))
+		assert.Empty(t, logs.Entries("old-completed"))
+
+		var out strings.Builder
+		reg.WriteTo(&out)
+		assert.Contains(t, out.String(), `taskapi_tasks_reaped_total{status="completed",reason="expired"} 2`)
+		assert.Contains(t, out.String(), `taskapi_tasks_reaped_total{status="cancelled",reason="expired"} 1`)
+	})
+
+	t.Run("Oldest finished tasks are evicted above MaxTasks", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(0), WithRetention(Retention{MaxTasks: 3}))
+
+		finishedTask(store, "oldest", models.StatusCompleted, 3*time.Hour, 0)
+		finishedTask(store, "older", models.StatusFailed, 2*time.Hour, 0)
+		finishedTask(store, "newest", models.StatusCompleted, time.Hour, 0)
+		// Незавершенные задачи учитываются в лимите, но не вытесняются
+		finishedTask(store, "pending", models.StatusPending, 10*time.Hour, 0)
+		finishedTask(store, "processing", models.StatusProcessing, 10*time.Hour, 0)
+
+		assert.Equal(t, 2, service.Reap(ctx))
+		assert.ElementsMatch(t, []string{"newest", "pending", "processing"}, ids(store))
+	})
+
+	t.Run("Eviction counts expired tasks that were not removed", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		archive := archiveFunc(func(tasks []models.Task) error {
+			if tasks[0].ID == "expired" {
+				return errors.New("disk full")
+			}
+			return nil
+		})
+		service := NewTaskService(store, WithWorkers(0), WithRetention(Retention{Failed: time.Hour, MaxTasks: 2, Archive: archive}))
+
+		finishedTask(store, "expired", models.StatusFailed, 5*time.Hour, 0)
+		finishedTask(store, "older", models.StatusCompleted, 3*time.Hour, 0)
+		finishedTask(store, "newer", models.StatusCompleted, 2*time.Hour, 0)
+
+		// Просроченная задача осталась, поэтому ради лимита вытесняется еще одна
+		assert.Equal(t, 1, service.Reap(ctx))
+		assert.ElementsMatch(t, []string{"expired", "newer"}, ids(store))
+	})
+
+	t.Run("Tasks are archived before removal and kept if archiving fails", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		var archived []string
+		var archiveErr error
+		archive := archiveFunc(func(tasks []models.Task) error {
+			if archiveErr != nil {
+				return archiveErr
+			}
+			for _, task := range tasks {
+				archived = append(archived, task.ID)
+			}
+			return nil
+		})
+		service := NewTaskService(store, WithWorkers(0), WithRetention(Retention{Completed: time.Hour, Archive: archive}))
+
+		finishedTask(store, "first", models.StatusCompleted, 2*time.Hour, 0)
+		archiveErr = errors.New("disk full")
+		assert.Equal(t, 0, service.Reap(ctx))
+		assert.Equal(t, []string{"first"}, ids(store))
+
+		archiveErr = nil
+		assert.Equal(t, 1, service.Reap(ctx))
+		assert.Empty(t, ids(store))
+		assert.Equal(t, []string{"first"}, archived)
+	})
+
+	t.Run("Reaper runs in the background", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(0), WithRetention(Retention{Completed: time.Hour, Interval: 10 * time.Millisecond}))
+		finishedTask(store, "old", models.StatusCompleted, 2*time.Hour, 0)
+
+		require.Eventually(t, func() bool { return len(ids(store)) == 0 }, time.Second, 5*time.Millisecond)
+		require.NoError(t, service.Shutdown(ctx))
+	})
+
+	t.Run("Reaper starts only when something can expire", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(0), WithRetention(Retention{Interval: 10 * time.Millisecond}))
+		finishedTask(store, "old", models.StatusCompleted, 2*time.Hour, 60)
+
+		// Без политики хранения проверка не запущена
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, []string{"old"}, ids(store))
+
+		// Задача с собственным сроком запускает проверку
+		_, err := service.CreateTask(ctx, models.TaskCreate{Description: "short lived", TTLSeconds: 600})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			_, ok := store.Get("old")
+			return !ok
+		}, time.Second, 5*time.Millisecond)
+		require.NoError(t, service.Shutdown(ctx))
+	})
+
+	t.Run("TTL is set at creation", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "short lived", TTLSeconds: 600})
+		require.NoError(t, err)
+		assert.Equal(t, 600, task.TTLSeconds)
+
+		_, err = service.CreateTask(ctx, models.TaskCreate{Description: "bad", TTLSeconds: -1})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"http_api/models"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultStatsWindow = time.Hour
+
+// WithStatsWindow задает скользящее окно статистики по типам задач
+func WithStatsWindow(window time.Duration) Option {
+	return func(s *TaskService) {
+		s.statsWindow = window
+	}
+}
+
+type outcome struct {
+	at       time.Time
+	status   models.TaskStatus
+	duration float64
+}
+
+// typeStats хранит исходы задач по типам за последние window
+type typeStats struct {
+	mu       sync.Mutex
+	window   time.Duration
+	now      func() time.Time
+	outcomes map[string][]outcome
+}
+
+func newTypeStats(window time.Duration) *typeStats {
+	return &typeStats{
+		window:   window,
+		now:      time.Now,
+		outcomes: make(map[string][]outcome),
+	}
+}
+
+func (t *typeStats) record(task *models.Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	taskType := typeLabel(task)
+	t.outcomes[taskType] = append(t.prune(taskType), outcome{at: t.now(), status: task.Status, duration: task.Duration})
+}
+
+// prune отбрасывает исходы старше окна; исходы упорядочены по времени
+func (t *typeStats) prune(taskType string) []outcome {
+	outcomes := t.outcomes[taskType]
+	cutoff := t.now().Add(-t.window)
+	i := sort.Search(len(outcomes), func(i int) bool { return outcomes[i].at.After(cutoff) })
+	if i == len(outcomes) {
+		delete(t.outcomes, taskType)
+		return nil
+	}
+	outcomes = outcomes[i:]
+	t.outcomes[taskType] = outcomes
+	return outcomes
+}
+
+func (t *typeStats) snapshot(taskType string) *models.TaskTypeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := &models.TaskTypeStats{Type: taskType, WindowSeconds: t.window.Seconds()}
+	var durations []float64
+	for _, o := range t.prune(taskType) {
+		switch o.status {
+		case models.StatusCompleted:
+			stats.Completed++
+		case models.StatusFailed:
+			stats.Failed++
+		case models.StatusCancelled:
+			stats.Cancelled++
+			continue
+		}
+		durations = append(durations, o.duration)
+	}
+
+	if finished := stats.Completed + stats.Failed; finished > 0 {
+		rate := float64(stats.Completed) / float64(finished)
+		stats.SuccessRate = &rate
+
+		sort.Float64s(durations)
+		stats.Duration = &models.DurationPercentiles{
+			P50: percentile(durations, 50),
+			P90: percentile(durations, 90),
+			P95: percentile(durations, 95),
+			P99: percentile(durations, 99),
+			Max: durations[len(durations)-1],
+		}
+	}
+	return stats
+}
+
+// percentile - перцентиль по методу ближайшего ранга для отсортированных значений
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
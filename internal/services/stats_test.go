@@ -0,0 +1,46 @@
+package services
+
+import (
+	"http_api/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeStats(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	stats := newTypeStats(time.Minute)
+	stats.now = func() time.Time { return now }
+
+	record := func(status models.TaskStatus, duration float64) {
+		stats.record(&models.Task{Type: "report", Status: status, Duration: duration})
+	}
+
+	record(models.StatusFailed, 100)
+	now = now.Add(30 * time.Second)
+	for i := 1; i <= 10; i++ {
+		record(models.StatusCompleted, float64(i))
+	}
+	record(models.StatusFailed, 20)
+	record(models.StatusCancelled, 50)
+
+	snapshot := stats.snapshot("report")
+	assert.Equal(t, 10, snapshot.Completed)
+	assert.Equal(t, 2, snapshot.Failed)
+	assert.Equal(t, 1, snapshot.Cancelled)
+	require.NotNil(t, snapshot.SuccessRate)
+	assert.InDelta(t, 10.0/12, *snapshot.SuccessRate, 1e-9)
+	assert.Equal(t, &models.DurationPercentiles{P50: 6, P90: 20, P95: 100, P99: 100, Max: 100}, snapshot.Duration)
+
+	// Первый исход выходит из окна
+	now = now.Add(31 * time.Second)
+	snapshot = stats.snapshot("report")
+	assert.Equal(t, 1, snapshot.Failed)
+	assert.Equal(t, &models.DurationPercentiles{P50: 6, P90: 10, P95: 20, P99: 20, Max: 20}, snapshot.Duration)
+
+	empty := stats.snapshot("other")
+	assert.Nil(t, empty.SuccessRate)
+	assert.Nil(t, empty.Duration)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"http_api/internal/tasklogs"
+	"http_api/models"
+)
+
+// TaskLogs возвращает строки журнала задачи, подходящие под запрос
+func (s *TaskService) TaskLogs(ctx context.Context, id string, q tasklogs.Query) ([]models.TaskLogEntry, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.logs.Read(id, q), nil
+}
+
+// FollowTaskLogs передает в emit строки журнала задачи по мере появления,
+// пока задача не завершится, не будет удалена, не отменится ctx
+// или сервис не начнет остановку
+func (s *TaskService) FollowTaskLogs(ctx context.Context, id string, q tasklogs.Query, emit func([]models.TaskLogEntry) error) error {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return err
+	}
+
+	for {
+		// Завершенной задаче подписка не нужна: журнал только читается
+		if task, exists := s.storage.Get(id); !exists || task.Status.IsTerminal() {
+			return s.emitLogs(id, &q, emit)
+		}
+		// Подписка до чтения, чтобы не пропустить строки между чтением и ожиданием
+		changed := s.logs.Changed(id)
+		if err := s.emitLogs(id, &q, emit); err != nil {
+			return err
+		}
+
+		task, exists := s.storage.Get(id)
+		if !exists || task.Status.IsTerminal() {
+			// Строки, дописанные до смены статуса, но после чтения
+			return s.emitLogs(id, &q, emit)
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.followCtx.Done():
+			return nil
+		}
+	}
+}
+
+// emitLogs передает новые строки и сдвигает запрос за последнюю из них
+func (s *TaskService) emitLogs(id string, q *tasklogs.Query, emit func([]models.TaskLogEntry) error) error {
+	entries := s.logs.Read(id, *q)
+	if len(entries) == 0 {
+		return nil
+	}
+	*q = tasklogs.Query{AfterSeq: entries[len(entries)-1].Seq}
+	return emit(entries)
+}
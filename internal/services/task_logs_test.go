@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chattyTypes регистрирует тип "chatty", который пишет строку в журнал,
+// ждет release и пишет еще одну
+func chattyTypes(t *testing.T, release <-chan struct{}) *tasktypes.Registry {
+	t.Helper()
+	types := tasktypes.NewRegistry()
+	require.NoError(t, types.Register(tasktypes.TaskType{
+		Name: "chatty",
+		Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+			out := tasklogs.Writer(ctx, tasklogs.Stdout)
+			defer out.Close()
+			io.WriteString(out, "working\n")
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			tasklogs.Logger(ctx).Info("done")
+			return nil, nil
+		}),
+	}))
+	return types
+}
+
+func logLines(entries []models.TaskLogEntry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.Stream+": "+e.Line)
+	}
+	return result
+}
+
+func TestTaskServiceLogs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Follow streams lines until the task finishes", func(t *testing.T) {
+		release := make(chan struct{})
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(chattyTypes(t, release)))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "chatty", Description: "logs"})
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var streamed []models.TaskLogEntry
+		done := make(chan error)
+		go func() {
+			done <- service.FollowTaskLogs(ctx, task.ID, tasklogs.Query{}, func(entries []models.TaskLogEntry) error {
+				mu.Lock()
+				defer mu.Unlock()
+				streamed = append(streamed, entries...)
+				return nil
+			})
+		}()
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(streamed) == 2
+		}, time.Second, 5*time.Millisecond)
+		close(release)
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("follow did not stop after the task finished")
+		}
+		assert.Equal(t, []string{"system: attempt 1 started", "stdout: working", "system: level=INFO msg=done"}, logLines(streamed))
+
+		entries, err := service.TaskLogs(ctx, task.ID, tasklogs.Query{Tail: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"system: level=INFO msg=done"}, logLines(entries))
+
+		require.NoError(t, service.DeleteTask(ctx, task.ID))
+		_, err = service.TaskLogs(ctx, task.ID, tasklogs.Query{})
+		assert.ErrorIs(t, err, storage.ErrTaskNotFound)
+	})
+
+	t.Run("Follow of a pending task ends when it is cancelled", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "queued"})
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			done <- service.FollowTaskLogs(ctx, task.ID, tasklogs.Query{}, func([]models.TaskLogEntry) error { return nil })
+		}()
+		time.Sleep(20 * time.Millisecond)
+		_, err = service.CancelTask(ctx, task.ID)
+		require.NoError(t, err)
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("follow did not stop after cancellation")
+		}
+	})
+
+	t.Run("Shutdown ends follow streams", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "queued"})
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			done <- service.FollowTaskLogs(ctx, task.ID, tasklogs.Query{}, func([]models.TaskLogEntry) error { return nil })
+		}()
+		service.StopAccepting()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("follow did not stop on shutdown")
+		}
+	})
+}
@@ -3,13 +3,26 @@ package services
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"http_api/internal/models"
+	"http_api/internal/artifacts"
+	"http_api/internal/auth"
+	"http_api/internal/labels"
+	"http_api/internal/metrics"
 	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/internal/validation"
+	"http_api/models"
+	"log/slog"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var ErrShuttingDown = errors.New("service is shutting down")
+
 func generateID() string {
 	b := make([]byte, 8)
 	if _, err := rand.Read(b); err != nil {
@@ -19,39 +32,252 @@ func generateID() string {
 	return hex.EncodeToString(b)
 }
 
+const (
+	defaultWorkers       = 16
+	defaultQueueLimit    = 1000
+	defaultMinProcessing = 3 * time.Minute
+	defaultMaxProcessing = 5 * time.Minute
+	defaultSchedulerTick = time.Second
+)
+
 type TaskService struct {
-	storage storage.TaskStorage
+	storage    storage.TaskStorage
+	dispatcher *dispatcher
+
+	workers       int
+	queueLimit    int
+	minProcessing time.Duration
+	maxProcessing time.Duration
+	quotas        Quotas
+	rules         validation.Rules
+	types         *tasktypes.Registry
+	statsWindow   time.Duration
+	stats         *typeStats
+	logs          *tasklogs.Store
+	artifacts     artifacts.Store
+	retention     Retention
+	registry      *metrics.Registry
+	metrics       *serviceMetrics
+	logger        *slog.Logger
+
+	schedulerInterval time.Duration
+	work              chan string
+	busy              atomic.Int32
+	lastTick          atomic.Int64
+
+	// stopWorkers запрещает воркерам брать новые задачи,
+	// abortTasks прерывает уже выполняющиеся
+	stopWorkers context.CancelFunc
+	execCtx     context.Context
+	abortTasks  context.CancelFunc
+	wg          sync.WaitGroup
+	draining    atomic.Bool
+	// reaperCtx и reaperOnce запускают проверку хранения не более одного раза
+	reaperCtx  context.Context
+	reaperOnce sync.Once
+	// followCtx отменяется при остановке, завершая потоки журналов задач
+	followCtx  context.Context
+	stopFollow context.CancelFunc
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+type Option func(*TaskService)
+
+// WithWorkers задает число задач, выполняемых одновременно
+func WithWorkers(n int) Option {
+	return func(s *TaskService) {
+		s.workers = n
+	}
+}
+
+// WithQueueLimit ограничивает число задач, ожидающих свободного воркера (0 - без ограничения)
+func WithQueueLimit(n int) Option {
+	return func(s *TaskService) {
+		s.queueLimit = n
+	}
 }
 
-func NewTaskService(storage storage.TaskStorage) *TaskService {
-	return &TaskService{storage: storage}
+// WithProcessingTime задает диапазон длительности имитируемой обработки
+func WithProcessingTime(minDuration, maxDuration time.Duration) Option {
+	return func(s *TaskService) {
+		s.minProcessing = minDuration
+		s.maxProcessing = maxDuration
+	}
+}
+
+// WithSchedulerInterval задает период пробуждения планировщика
+func WithSchedulerInterval(interval time.Duration) Option {
+	return func(s *TaskService) {
+		s.schedulerInterval = interval
+	}
+}
+
+// WithLogger задает логгер событий жизненного цикла задач
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *TaskService) {
+		s.logger = logger
+	}
 }
 
-func (s *TaskService) CreateTask(ctx context.Context, description string) (*models.Task, error) {
+// WithValidation задает правила проверки запросов на создание и изменение задач
+func WithValidation(rules validation.Rules) Option {
+	return func(s *TaskService) {
+		s.rules = rules
+	}
+}
+
+// WithTaskLogs задает хранилище журналов выполнения задач
+func WithTaskLogs(logs *tasklogs.Store) Option {
+	return func(s *TaskService) {
+		s.logs = logs
+	}
+}
+
+// WithArtifacts задает хранилище артефактов задач; без него исполнители
+// не могут сохранять артефакты
+func WithArtifacts(store artifacts.Store) Option {
+	return func(s *TaskService) {
+		s.artifacts = store
+	}
+}
+
+// WithMetrics регистрирует метрики сервиса в reg
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *TaskService) {
+		s.registry = reg
+	}
+}
+
+func NewTaskService(storage storage.TaskStorage, opts ...Option) *TaskService {
+	s := &TaskService{
+		storage:           storage,
+		workers:           defaultWorkers,
+		queueLimit:        defaultQueueLimit,
+		minProcessing:     defaultMinProcessing,
+		maxProcessing:     defaultMaxProcessing,
+		schedulerInterval: defaultSchedulerTick,
+		work:              make(chan string),
+		running:           make(map[string]context.CancelFunc),
+		logger:            slog.Default(),
+		rules:             validation.DefaultRules(),
+		statsWindow:       defaultStatsWindow,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.types == nil {
+		s.types = tasktypes.NewRegistry()
+	}
+	s.registerDefaultType()
+	s.stats = newTypeStats(s.statsWindow)
+	if s.logs == nil {
+		s.logs = tasklogs.NewStore(0)
+	}
+	s.dispatcher = newDispatcher(s.queueLimit, s.quotas, s.maxConcurrency)
+	if s.registry == nil {
+		s.registry = metrics.NewRegistry()
+	}
+	s.metrics = newServiceMetrics(s.registry, s)
+	s.execCtx, s.abortTasks = context.WithCancel(context.Background())
+	s.followCtx, s.stopFollow = context.WithCancel(context.Background())
+
+	var workersCtx context.Context
+	workersCtx, s.stopWorkers = context.WithCancel(context.Background())
+	s.lastTick.Store(time.Now().UnixNano())
+	if s.retention.Interval <= 0 {
+		s.retention.Interval = defaultRetentionInterval
+	}
+	s.wg.Add(s.workers + 1)
+	go s.schedule(workersCtx)
+	for i := 0; i < s.workers; i++ {
+		go s.worker(workersCtx)
+	}
+	s.reaperCtx = workersCtx
+	if s.retention.enabled() {
+		s.startReaper()
+	}
+	return s
+}
+
+// ValidationRules возвращает правила проверки, чтобы обработчики разбирали запросы по ним же
+func (s *TaskService) ValidationRules() validation.Rules {
+	return s.rules
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, req models.TaskCreate) (*models.Task, error) {
+	if s.draining.Load() {
+		return nil, ErrShuttingDown
+	}
+
+	taskType := req.Type
+	if taskType == "" {
+		taskType = models.DefaultTaskType
+	}
+	if err := s.rules.Create(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkInput(taskType, req.Input); err != nil {
+		return nil, err
+	}
+
 	task := &models.Task{
 		ID:          generateID(),
+		Type:        taskType,
 		Status:      models.StatusPending,
 		CreatedAt:   time.Now(),
-		Description: description,
+		Description: req.Description,
+		Input:       req.Input,
+		Priority:    req.Priority,
+		Labels:      req.Labels,
+		Metadata:    req.Metadata,
+		TTLSeconds:  req.TTLSeconds,
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		task.CreatedBy = principal.ID
+	}
+	task.Namespace = NamespaceFrom(ctx)
+	if task.Namespace == AllNamespaces {
+		task.Namespace = models.DefaultNamespace
 	}
 
 	s.storage.Create(task)
+	// Копия фиксирует состояние на момент создания: воркер может сразу взять задачу
+	created := *task
 
-	go s.processTask(task.ID)
+	if err := s.dispatcher.enqueue(task.ID, task.Namespace, taskType, task.Priority); err != nil {
+		s.storage.Delete(task.ID)
+		s.logger.WarnContext(ctx, "task rejected", "namespace", task.Namespace, "type", taskType, "error", err)
+		return nil, err
+	}
 
-	return task, nil
+	if task.TTLSeconds > 0 {
+		s.startReaper()
+	}
+	s.metrics.created.WithLabelValues(taskType).Inc()
+	s.logTask(ctx, slog.LevelInfo, "task created", &created)
+	return &created, nil
 }
 
 func (s *TaskService) GetTask(ctx context.Context, id string) (*models.Task, error) {
-	task, exists := s.storage.Get(id)
+	task, exists := s.tasks(ctx).Get(id)
 	if !exists {
 		return nil, storage.ErrTaskNotFound
 	}
 	return task, nil
 }
 
-func (s *TaskService) ListTasks(ctx context.Context) (*models.TaskList, error) {
-	tasks, err := s.storage.GetAll()
+// ListTasks возвращает задачи, подходящие под селектор меток (пустой - все)
+func (s *TaskService) ListTasks(ctx context.Context, selector labels.Selector) (*models.TaskList, error) {
+	var tasks []models.Task
+	var err error
+	if selector.Empty() {
+		tasks, err = s.tasks(ctx).GetAll()
+	} else {
+		tasks, err = s.tasks(ctx).Select(selector)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -62,23 +288,11 @@ func (s *TaskService) ListTasks(ctx context.Context) (*models.TaskList, error) {
 	}, nil
 }
 
-func (s *TaskService) UpdateTask(ctx context.Context, id string, update models.TaskUpdate) (*models.Task, error) {
-	updatedTask, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
-		if update.Description != nil {
-			task.Description = *update.Description
-		}
-		return task, nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to update task: %w", err)
-	}
-
-	return updatedTask, nil
-}
-
 func (s *TaskService) CancelTask(ctx context.Context, id string) (*models.Task, error) {
-	updatedTask, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
+	updatedTask, err := s.tasks(ctx).Update(id, func(task *models.Task) (*models.Task, error) {
+		if err := checkPrecondition(ctx, task); err != nil {
+			return nil, err
+		}
 		if task.Status != models.StatusPending && task.Status != models.StatusProcessing {
 			return nil, storage.ErrInvalidState
 		}
@@ -96,18 +310,44 @@ func (s *TaskService) CancelTask(ctx context.Context, id string) (*models.Task,
 		return nil, fmt.Errorf("failed to cancel task: %w", err)
 	}
 
+	s.metrics.cancelled.WithLabelValues(typeLabel(updatedTask)).Inc()
+	s.stats.record(updatedTask)
+	s.dispatcher.remove(id)
+	s.stopRunning(id)
+	s.logs.Finish(id)
+	s.logTask(ctx, slog.LevelInfo, "task cancelled", updatedTask)
 	return updatedTask, nil
 }
 
 func (s *TaskService) DeleteTask(ctx context.Context, id string) error {
-	if !s.storage.Delete(id) {
-		return storage.ErrTaskNotFound
+	err := s.tasks(ctx).DeleteIf(id, func(task *models.Task) error {
+		return checkPrecondition(ctx, task)
+	})
+	if err != nil {
+		return err
 	}
+	s.dispatcher.remove(id)
+	s.stopRunning(id)
+	s.deleteTaskData(id)
+	s.logger.InfoContext(ctx, "task deleted", "task_id", id)
 	return nil
 }
 
 func (s *TaskService) processTask(id string) {
-	_, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
+	ctx, cancel := context.WithCancel(s.execCtx)
+	defer cancel()
+
+	// Регистрируем задачу до смены статуса, чтобы отмена не потерялась
+	s.mu.Lock()
+	s.running[id] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.mu.Unlock()
+	}()
+
+	task, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
 		if task.Status != models.StatusPending {
 			return nil, storage.ErrInvalidState
 		}
@@ -115,28 +355,131 @@ func (s *TaskService) processTask(id string) {
 		now := time.Now()
 		task.Status = models.StatusProcessing
 		task.StartedAt = &now
+		task.Attempts++
 		return task, nil
 	})
 
 	if err != nil {
+		// Задачу отменили или удалили, пока она ждала в очереди
+		s.logTransitionError(ctx, id, "start", err)
 		return
 	}
+	queueWait := seconds(task.CreatedAt, *task.StartedAt)
+	s.metrics.queueWait.WithLabelValues(typeLabel(task)).Observe(queueWait)
+	s.logTask(ctx, slog.LevelInfo, "task started", task, "queue_wait_seconds", queueWait)
 
-	// Имитация длительной задачи
-	processingTime := 3*time.Minute + time.Duration(rand.Intn(120))*time.Second
-	time.Sleep(processingTime)
+	taskType, _ := s.types.Get(typeLabel(task))
+	s.logs.Append(id, tasklogs.System, fmt.Sprintf("attempt %d started", task.Attempts))
+	execCtx := tasklogs.WithTask(ctx, s.logs, id)
+	if s.artifacts != nil {
+		execCtx = artifacts.WithTask(execCtx, s.artifacts, id)
+	}
+	result, execErr := s.execute(execCtx, taskType, task)
+	if ctx.Err() != nil {
+		if s.execCtx.Err() != nil {
+			s.logTask(ctx, slog.LevelWarn, "task interrupted by shutdown", task)
+			s.requeueInterrupted(id)
+		} else if _, exists := s.storage.Get(id); !exists {
+			// Исполнитель мог дописать журнал и артефакты уже после удаления задачи
+			s.deleteTaskData(id)
+		}
+		return
+	}
+	var failure *models.TaskError
+	if execErr != nil {
+		failure = tasktypes.Describe(execErr)
+		s.logs.Append(id, tasklogs.System, fmt.Sprintf("attempt %d failed: %s: %s", task.Attempts, failure.Code, failure.Message))
+	}
+	retry := failure != nil && failure.Retryable && taskType != nil && task.Attempts < taskType.Retry.MaxAttempts
 
-	// Завершение задачи
-	_, err = s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
+	// Завершение задачи или возврат в ожидание перед повтором
+	task, err = s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
 		if task.Status != models.StatusProcessing {
 			return nil, storage.ErrInvalidState
 		}
 
 		now := time.Now()
-		task.Status = models.StatusCompleted
+		if failure != nil {
+			task.Error = failure.Message
+			task.ErrorInfo = failure
+		}
+		if retry {
+			task.Status = models.StatusPending
+			task.StartedAt = nil
+			return task, nil
+		}
 		task.CompletedAt = &now
 		task.Duration = now.Sub(*task.StartedAt).Seconds()
-		task.Result = fmt.Sprintf("Processed for %.2f seconds", task.Duration)
+		if failure != nil {
+			task.Status = models.StatusFailed
+			return task, nil
+		}
+		task.Status = models.StatusCompleted
+		task.Result = result
+		task.Error = ""
+		task.ErrorInfo = nil
 		return task, nil
 	})
+	if err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			s.deleteTaskData(id)
+		}
+		s.logTransitionError(ctx, id, "complete", err)
+		return
+	}
+
+	if retry {
+		delay := taskType.Retry.Delay(task.Attempts)
+		s.metrics.retried.WithLabelValues(typeLabel(task)).Inc()
+		s.logTask(ctx, slog.LevelWarn, "task retry scheduled", task, "attempt", task.Attempts, "delay", delay, "code", failure.Code, "error", execErr)
+		s.scheduleRetry(task, delay)
+		return
+	}
+
+	s.stats.record(task)
+	s.metrics.execution.WithLabelValues(typeLabel(task)).Observe(task.Duration)
+	if execErr != nil {
+		s.metrics.failed.WithLabelValues(typeLabel(task)).Inc()
+		if failure.Code == models.ErrorCodePanic {
+			s.metrics.panics.WithLabelValues(typeLabel(task)).Inc()
+			s.logTask(ctx, slog.LevelError, "task executor panicked", task, "error", execErr, "stack", failure.Stack)
+			return
+		}
+		s.logTask(ctx, slog.LevelWarn, "task failed", task, "code", failure.Code, "error", execErr)
+		return
+	}
+	s.metrics.completed.WithLabelValues(typeLabel(task)).Inc()
+	s.logTask(ctx, slog.LevelInfo, "task completed", task)
+}
+
+func (s *TaskService) logTask(ctx context.Context, level slog.Level, msg string, task *models.Task, attrs ...any) {
+	attrs = append([]any{
+		"task_id", task.ID,
+		"namespace", models.NamespaceOf(task),
+		"type", typeLabel(task),
+		"status", task.Status,
+	}, attrs...)
+	if task.Duration > 0 {
+		attrs = append(attrs, "duration_seconds", task.Duration)
+	}
+	s.logger.Log(ctx, level, msg, attrs...)
+}
+
+// logTransitionError различает ожидаемую гонку с отменой/удалением и сбой хранилища
+func (s *TaskService) logTransitionError(ctx context.Context, id, transition string, err error) {
+	if errors.Is(err, storage.ErrInvalidState) || errors.Is(err, storage.ErrTaskNotFound) {
+		s.logger.DebugContext(ctx, "task transition skipped", "task_id", id, "transition", transition, "reason", err)
+		return
+	}
+	s.logger.ErrorContext(ctx, "task transition failed", "task_id", id, "transition", transition, "error", err)
+}
+
+// stopRunning прерывает выполнение задачи, если она сейчас обрабатывается
+func (s *TaskService) stopRunning(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+	}
 }
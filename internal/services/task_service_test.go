@@ -3,9 +3,16 @@ package services
 import (
 	"context"
 	"errors"
-	"http_api/internal/models"
+	"http_api/internal/auth"
+	"http_api/internal/labels"
+	"http_api/internal/logging"
+	"http_api/internal/metrics"
 	"http_api/internal/storage"
+	"http_api/models"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -31,7 +38,23 @@ func (m *MockStorage) GetAll() ([]models.Task, error) {
 
 func (m *MockStorage) Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error) {
 	args := m.Called(id, updateFn)
-	return args.Get(0).(*models.Task), args.Error(1)
+	task, _ := args.Get(0).(*models.Task)
+	return task, args.Error(1)
+}
+
+func (m *MockStorage) Select(selector labels.Selector) ([]models.Task, error) {
+	args := m.Called(selector)
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockStorage) DeleteIf(id string, cond func(*models.Task) error) error {
+	args := m.Called(id)
+	if task, ok := args.Get(0).(*models.Task); ok && task != nil {
+		if err := cond(task); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
 }
 
 func (m *MockStorage) Delete(id string) bool {
@@ -47,8 +70,9 @@ func TestTaskService(t *testing.T) {
 		service := NewTaskService(mockStorage)
 
 		mockStorage.On("Create", mock.AnythingOfType("*models.Task")).Once()
+		mockStorage.On("Update", mock.Anything, mock.Anything).Return(nil, storage.ErrInvalidState).Maybe()
 
-		task, err := service.CreateTask(ctx, "test desc")
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "test desc"})
 
 		assert.NoError(t, err)
 		assert.Equal(t, "test desc", task.Description)
@@ -56,6 +80,21 @@ func TestTaskService(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 
+	t.Run("Reject task when queue is full", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewTaskService(mockStorage, WithWorkers(0), WithQueueLimit(1))
+
+		mockStorage.On("Create", mock.AnythingOfType("*models.Task")).Twice()
+		mockStorage.On("Delete", mock.AnythingOfType("string")).Return(true).Once()
+
+		_, err := service.CreateTask(ctx, models.TaskCreate{Description: "first"})
+		assert.NoError(t, err)
+
+		_, err = service.CreateTask(ctx, models.TaskCreate{Description: "second"})
+		assert.True(t, errors.Is(err, ErrQueueFull))
+		mockStorage.AssertExpectations(t)
+	})
+
 	t.Run("Get existing task", func(t *testing.T) {
 		mockStorage := new(MockStorage)
 		service := NewTaskService(mockStorage)
@@ -123,7 +162,7 @@ func TestTaskService(t *testing.T) {
 		mockStorage := new(MockStorage)
 		service := NewTaskService(mockStorage)
 
-		mockStorage.On("Delete", "test4").Return(true)
+		mockStorage.On("DeleteIf", "test4").Return(&models.Task{ID: "test4"}, nil)
 
 		err := service.DeleteTask(ctx, "test4")
 
@@ -135,7 +174,7 @@ func TestTaskService(t *testing.T) {
 		mockStorage := new(MockStorage)
 		service := NewTaskService(mockStorage)
 
-		mockStorage.On("Delete", "nonexistent").Return(false)
+		mockStorage.On("DeleteIf", "nonexistent").Return((*models.Task)(nil), storage.ErrTaskNotFound)
 
 		err := service.DeleteTask(ctx, "nonexistent")
 
@@ -143,3 +182,232 @@ func TestTaskService(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 }
+
+func waitForStatus(t *testing.T, s storage.TaskStorage, id string, status models.TaskStatus) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		task, ok := s.Get(id)
+		return ok && task.Status == status
+	}, time.Second, time.Millisecond)
+}
+
+func TestTaskServiceLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Shutdown waits for running tasks", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithProcessingTime(20*time.Millisecond, 20*time.Millisecond))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "short"})
+		assert.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusProcessing)
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		assert.NoError(t, service.Shutdown(shutdownCtx))
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, models.StatusCompleted, stored.Status)
+
+		_, err = service.CreateTask(ctx, models.TaskCreate{Description: "late"})
+		assert.True(t, errors.Is(err, ErrShuttingDown))
+	})
+
+	t.Run("Shutdown interrupts tasks after drain timeout", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(1), WithProcessingTime(time.Minute, time.Minute))
+
+		running, err := service.CreateTask(ctx, models.TaskCreate{Description: "long"})
+		assert.NoError(t, err)
+		waitForStatus(t, store, running.ID, models.StatusProcessing)
+		queued, err := service.CreateTask(ctx, models.TaskCreate{Description: "queued"})
+		assert.NoError(t, err)
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err = service.Shutdown(shutdownCtx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		stored, _ := store.Get(running.ID)
+		assert.Equal(t, models.StatusPending, stored.Status)
+		assert.Nil(t, stored.StartedAt)
+
+		stored, _ = store.Get(queued.ID)
+		assert.Equal(t, models.StatusPending, stored.Status)
+	})
+
+	t.Run("Recover unfinished tasks", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		startedAt := time.Now()
+		store.Create(&models.Task{ID: "pending", Status: models.StatusPending, CreatedAt: time.Now()})
+		store.Create(&models.Task{ID: "interrupted", Status: models.StatusProcessing, StartedAt: &startedAt, CreatedAt: time.Now()})
+		store.Create(&models.Task{ID: "done", Status: models.StatusCompleted, CreatedAt: time.Now()})
+
+		service := NewTaskService(store, WithProcessingTime(time.Millisecond, time.Millisecond))
+		recovered, err := service.RecoverTasks(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, recovered)
+		waitForStatus(t, store, "pending", models.StatusCompleted)
+		waitForStatus(t, store, "interrupted", models.StatusCompleted)
+	})
+
+	t.Run("Cancel frees the worker", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(1), WithProcessingTime(time.Minute, time.Minute))
+
+		first, err := service.CreateTask(ctx, models.TaskCreate{Description: "first"})
+		assert.NoError(t, err)
+		waitForStatus(t, store, first.ID, models.StatusProcessing)
+
+		second, err := service.CreateTask(ctx, models.TaskCreate{Description: "second"})
+		assert.NoError(t, err)
+
+		_, err = service.CancelTask(ctx, first.ID)
+		assert.NoError(t, err)
+		waitForStatus(t, store, second.ID, models.StatusProcessing)
+	})
+}
+
+func TestTaskServiceMetrics(t *testing.T) {
+	ctx := context.Background()
+	reg := metrics.NewRegistry()
+	store := storage.NewInMemoryTaskStorage()
+	service := NewTaskService(store, WithMetrics(reg), WithTaskTypes(reportTypes(t)))
+
+	done, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "done"})
+	assert.NoError(t, err)
+	waitForStatus(t, store, done.ID, models.StatusCompleted)
+
+	service.StopAccepting()
+	store.Create(&models.Task{ID: "pending", Type: "report", Status: models.StatusPending})
+	_, err = service.CancelTask(ctx, "pending")
+	assert.NoError(t, err)
+
+	var out strings.Builder
+	reg.WriteTo(&out)
+
+	assert.Contains(t, out.String(), `taskapi_tasks_created_total{type="report"} 1`)
+	assert.Contains(t, out.String(), `taskapi_tasks_completed_total{type="report"} 1`)
+	assert.Contains(t, out.String(), `taskapi_tasks_cancelled_total{type="report"} 1`)
+	assert.Contains(t, out.String(), `taskapi_task_queue_wait_seconds_count{type="report"} 1`)
+	assert.Contains(t, out.String(), `taskapi_task_execution_duration_seconds_count{type="report"} 1`)
+	assert.Contains(t, out.String(), "taskapi_tasks_processing 0")
+}
+
+func TestTaskServiceCreatedBy(t *testing.T) {
+	service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "alice"})
+	task, err := service.CreateTask(ctx, models.TaskCreate{Description: "owned"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", task.CreatedBy)
+
+	task, err = service.CreateTask(context.Background(), models.TaskCreate{Description: "anonymous"})
+	assert.NoError(t, err)
+	assert.Empty(t, task.CreatedBy)
+}
+
+func TestTaskServiceQuotas(t *testing.T) {
+	store := storage.NewInMemoryTaskStorage()
+	service := NewTaskService(store, WithWorkers(0), WithQuotas(Quotas{
+		Default: Quota{MaxPending: 1, MaxProcessing: 2},
+	}))
+	teamA := WithNamespace(context.Background(), "team-a")
+
+	_, err := service.CreateTask(teamA, models.TaskCreate{Description: "first"})
+	assert.NoError(t, err)
+	_, err = service.CreateTask(teamA, models.TaskCreate{Description: "second"})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// Отклоненная задача не остается в хранилище
+	tasks, _ := store.GetAll()
+	assert.Len(t, tasks, 1)
+
+	_, err = service.CreateTask(WithNamespace(context.Background(), "team-b"), models.TaskCreate{Description: "other team"})
+	assert.NoError(t, err)
+
+	usage := service.Quotas(teamA)
+	assert.Equal(t, []models.QuotaUsage{{
+		Namespace:         "team-a",
+		Pending:           models.Usage{Used: 1, Limit: 1},
+		Processing:        models.Usage{Used: 0, Limit: 2},
+		CreatedLastMinute: models.Usage{Used: 0, Limit: 0},
+	}}, usage.Quotas)
+
+	all := service.Quotas(WithNamespace(context.Background(), AllNamespaces))
+	assert.Len(t, all.Quotas, 2)
+}
+
+// syncBuffer позволяет воркерам и тесту одновременно писать и читать лог
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTaskServiceLogging(t *testing.T) {
+	var out syncBuffer
+	logger, err := logging.New(&out, "info", "text")
+	assert.NoError(t, err)
+
+	store := storage.NewInMemoryTaskStorage()
+	service := NewTaskService(store, WithLogger(logger), WithTaskTypes(reportTypes(t)))
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "logged"})
+	assert.NoError(t, err)
+	waitForStatus(t, store, task.ID, models.StatusCompleted)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), `msg="task completed"`)
+	}, time.Second, 5*time.Millisecond)
+
+	logs := out.String()
+	assert.Contains(t, logs, `msg="task created" task_id=`+task.ID+` namespace=default type=report status=pending request_id=req-1`)
+	assert.Contains(t, logs, `msg="task started" task_id=`+task.ID)
+	assert.Regexp(t, `msg="task completed" task_id=`+task.ID+` namespace=default type=report status=completed duration_seconds=\S+`, logs)
+}
+
+func TestTaskServiceHealthChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Healthy service", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage())
+
+		assert.NoError(t, service.CheckWorkers(ctx))
+		assert.NoError(t, service.CheckScheduler(ctx))
+		assert.NoError(t, service.CheckDraining(ctx))
+	})
+
+	t.Run("Saturated worker pool", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(1), WithQueueLimit(1), WithProcessingTime(time.Minute, time.Minute))
+
+		running, err := service.CreateTask(ctx, models.TaskCreate{Description: "running"})
+		assert.NoError(t, err)
+		waitForStatus(t, store, running.ID, models.StatusProcessing)
+		_, err = service.CreateTask(ctx, models.TaskCreate{Description: "queued"})
+		assert.NoError(t, err)
+
+		assert.Error(t, service.CheckWorkers(ctx))
+	})
+
+	t.Run("Stopped scheduler and draining", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithSchedulerInterval(5*time.Millisecond))
+		assert.NoError(t, service.Shutdown(ctx))
+
+		assert.Eventually(t, func() bool { return service.CheckScheduler(ctx) != nil }, time.Second, 5*time.Millisecond)
+		assert.Error(t, service.CheckDraining(ctx))
+	})
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/internal/tasktypes"
+	"http_api/internal/validation"
+	"http_api/models"
+	"math/rand"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// WithTaskTypes задает реестр типов задач. Если тип "default" не
+// зарегистрирован явно, сервис добавляет его с имитацией обработки
+// в собственную копию реестра, не изменяя переданный.
+func WithTaskTypes(types *tasktypes.Registry) Option {
+	return func(s *TaskService) {
+		s.types = types
+	}
+}
+
+var ErrTaskTimeout = errors.New("task timed out")
+
+// TaskTypes возвращает все зарегистрированные типы задач с текущей загрузкой
+func (s *TaskService) TaskTypes() *models.TaskTypeList {
+	types := s.types.List()
+	list := &models.TaskTypeList{TaskTypes: make([]models.TaskTypeInfo, 0, len(types)), Total: len(types)}
+	for _, t := range types {
+		list.TaskTypes = append(list.TaskTypes, *s.describe(t))
+	}
+	return list
+}
+
+// TaskType возвращает описание зарегистрированного типа задачи
+func (s *TaskService) TaskType(name string) (*models.TaskTypeInfo, error) {
+	t, ok := s.types.Get(name)
+	if !ok {
+		return nil, tasktypes.ErrUnknownType
+	}
+	return s.describe(t), nil
+}
+
+// TaskTypeStats возвращает итоги выполнения задач типа за окно статистики
+func (s *TaskService) TaskTypeStats(name string) (*models.TaskTypeStats, error) {
+	if _, ok := s.types.Get(name); !ok {
+		return nil, tasktypes.ErrUnknownType
+	}
+	return s.stats.snapshot(name), nil
+}
+
+func (s *TaskService) describe(t *tasktypes.TaskType) *models.TaskTypeInfo {
+	info := &models.TaskTypeInfo{
+		Name:           t.Name,
+		Description:    t.Description,
+		InputSchema:    t.InputSchema,
+		OutputSchema:   t.OutputSchema,
+		TimeoutSeconds: t.Timeout.Seconds(),
+		MaxConcurrency: t.MaxConcurrency,
+	}
+	if t.Retry.MaxAttempts > 1 {
+		info.RetryPolicy = &models.RetryPolicy{
+			MaxAttempts:       t.Retry.MaxAttempts,
+			BackoffSeconds:    t.Retry.Backoff.Seconds(),
+			MaxBackoffSeconds: t.Retry.MaxBackoff.Seconds(),
+		}
+	}
+	info.QueueDepth, info.Processing = s.dispatcher.typeUsage(t.Name)
+	return info
+}
+
+// maxConcurrency - лимит одновременного выполнения типа для диспетчера
+func (s *TaskService) maxConcurrency(taskType string) int {
+	if t, ok := s.types.Get(taskType); ok {
+		return t.MaxConcurrency
+	}
+	return 0
+}
+
+func (s *TaskService) registerDefaultType() {
+	if _, ok := s.types.Get(models.DefaultTaskType); ok {
+		return
+	}
+	// Исполнитель привязан к этому сервису, поэтому переданный реестр
+	// не трогаем: он может быть общим для нескольких сервисов
+	s.types = s.types.Clone()
+	// Ошибки быть не может: схемы нет, имя свободно
+	_ = s.types.Register(tasktypes.TaskType{
+		Name:         models.DefaultTaskType,
+		Description:  "Simulated processing for a random time within the configured range",
+		OutputSchema: json.RawMessage(`{"type": "string"}`),
+		Executor:     tasktypes.ExecutorFunc(s.simulate),
+	})
+}
+
+// simulate имитирует длительную задачу
+func (s *TaskService) simulate(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+	processingTime := s.minProcessing
+	if s.maxProcessing > s.minProcessing {
+		processingTime += time.Duration(rand.Int63n(int64(s.maxProcessing - s.minProcessing)))
+	}
+
+	timer := time.NewTimer(processingTime)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return json.Marshal(fmt.Sprintf("Processed for %.2f seconds", processingTime.Seconds()))
+}
+
+// checkInput проверяет тип задачи и ее вход по схеме типа
+func (s *TaskService) checkInput(taskType string, input json.RawMessage) error {
+	var errs validation.Errors
+	t, ok := s.types.Get(taskType)
+	if !ok {
+		errs.Add("/type", "unknown task type %q", taskType)
+		return errs.Err()
+	}
+	for _, e := range t.ValidateInput(input) {
+		errs.Add("/input"+e.Pointer, "%s", e.Message)
+	}
+	return errs.Err()
+}
+
+// execute запускает исполнитель типа задачи с ограничением времени
+// и проверяет результат по схеме
+func (s *TaskService) execute(ctx context.Context, t *tasktypes.TaskType, task *models.Task) (json.RawMessage, error) {
+	if t == nil {
+		return nil, fmt.Errorf("%w %q", tasktypes.ErrUnknownType, task.Type)
+	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	result, err := invoke(ctx, t.Executor, task)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, &tasktypes.Error{
+			Code:      models.ErrorCodeTimeout,
+			Message:   fmt.Sprintf("%s after %s", ErrTaskTimeout, t.Timeout),
+			Retryable: true,
+			Details:   map[string]interface{}{"timeout_seconds": t.Timeout.Seconds()},
+			Err:       ErrTaskTimeout,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if errs := t.ValidateOutput(result); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		violations := make([]interface{}, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Error()
+			violations[i] = map[string]interface{}{"pointer": e.Pointer, "message": e.Message}
+		}
+		return nil, &tasktypes.Error{
+			Code:    models.ErrorCodeInvalidOutput,
+			Message: "result does not match output schema: " + strings.Join(messages, "; "),
+			Details: map[string]interface{}{"errors": violations},
+		}
+	}
+	return result, nil
+}
+
+// invoke вызывает исполнитель, превращая его панику в ошибку с кодом panic.
+// Паника в горутинах, запущенных исполнителем, по-прежнему завершает процесс.
+func invoke(ctx context.Context, executor tasktypes.Executor, task *models.Task) (result json.RawMessage, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			result, err = nil, tasktypes.Panic(value, debug.Stack())
+		}
+	}()
+	return executor.Execute(ctx, task)
+}
+
+// scheduleRetry возвращает задачу в очередь после задержки, если ее
+// за это время не отменили и не удалили. До возврата задача занимает
+// место в квоте ожидающих задач своего пространства имен.
+func (s *TaskService) scheduleRetry(task *models.Task, delay time.Duration) {
+	id, namespace, taskType, priority := task.ID, models.NamespaceOf(task), typeLabel(task), task.Priority
+	s.dispatcher.postpone(id, namespace)
+	time.AfterFunc(delay, func() {
+		current, ok := s.storage.Get(id)
+		if !ok || current.Status != models.StatusPending || s.draining.Load() {
+			s.dispatcher.remove(id)
+			return
+		}
+		s.dispatcher.restore(id, namespace, taskType, priority)
+	})
+}
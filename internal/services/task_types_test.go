@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"http_api/internal/metrics"
+	"http_api/internal/storage"
+	"http_api/internal/tasktypes"
+	"http_api/internal/validation"
+	"http_api/models"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reportTypes регистрирует тип "report" с необязательным входом,
+// который сразу возвращает число строк из входа
+func reportTypes(t *testing.T) *tasktypes.Registry {
+	t.Helper()
+	types := tasktypes.NewRegistry()
+	require.NoError(t, types.Register(tasktypes.TaskType{
+		Name:         "report",
+		InputSchema:  json.RawMessage(`{"type": ["object", "null"], "properties": {"rows": {"type": "integer", "minimum": 0}}, "additionalProperties": false}`),
+		OutputSchema: json.RawMessage(`{"type": "object", "required": ["rows"]}`),
+		Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+			var input struct {
+				Rows int `json:"rows"`
+			}
+			if len(task.Input) > 0 {
+				if err := json.Unmarshal(task.Input, &input); err != nil {
+					return nil, err
+				}
+			}
+			return json.Marshal(map[string]int{"rows": input.Rows})
+		}),
+	}))
+	return types
+}
+
+func TestTaskServiceTaskTypes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Input is validated against the type schema", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0), WithTaskTypes(reportTypes(t)))
+
+		_, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "bad", Input: json.RawMessage(`{"rows": -1, "extra": true}`)})
+		var validationErr *validation.Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "/input/extra", validationErr.Errors[0].Pointer)
+		assert.Equal(t, "/input/rows", validationErr.Errors[1].Pointer)
+
+		_, err = service.CreateTask(ctx, models.TaskCreate{Type: "unknown", Description: "bad"})
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, "/type", validationErr.Errors[0].Pointer)
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "good", Input: json.RawMessage(`{"rows": 3}`)})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"rows": 3}`, string(task.Input))
+	})
+
+	t.Run("Executor result is stored", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(reportTypes(t)))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "rows", Input: json.RawMessage(`{"rows": 7}`)})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusCompleted)
+
+		stored, _ := store.Get(task.ID)
+		assert.JSONEq(t, `{"rows": 7}`, string(stored.Result))
+	})
+
+	t.Run("Result violating the output schema fails the task", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:         "broken",
+			OutputSchema: json.RawMessage(`{"type": "object"}`),
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				return json.RawMessage(`[1, 2]`), nil
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "broken", Description: "broken"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		assert.Empty(t, stored.Result)
+		assert.Equal(t, "result does not match output schema: /: must be of type object, got array", stored.Error)
+		require.NotNil(t, stored.ErrorInfo)
+		assert.Equal(t, models.ErrorCodeInvalidOutput, stored.ErrorInfo.Code)
+		assert.False(t, stored.ErrorInfo.Retryable)
+		assert.Equal(t, []interface{}{map[string]interface{}{"pointer": "", "message": "must be of type object, got array"}}, stored.ErrorInfo.Details["errors"])
+	})
+
+	t.Run("Executor error fails the task", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name: "failing",
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				return nil, errors.New("upstream unavailable")
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "failing", Description: "failing"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, "upstream unavailable", stored.Error)
+		assert.Equal(t, &models.TaskError{Code: models.ErrorCodeExecutor, Message: "upstream unavailable", Retryable: true}, stored.ErrorInfo)
+		assert.NotNil(t, stored.CompletedAt)
+	})
+
+	t.Run("Default type is registered", func(t *testing.T) {
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0))
+
+		info, err := service.TaskType(models.DefaultTaskType)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "string"}`, string(info.OutputSchema))
+
+		_, err = service.TaskType("missing")
+		assert.ErrorIs(t, err, tasktypes.ErrUnknownType)
+	})
+
+	t.Run("Shared registry is not modified", func(t *testing.T) {
+		types := reportTypes(t)
+		service := NewTaskService(storage.NewInMemoryTaskStorage(), WithWorkers(0), WithTaskTypes(types))
+
+		_, err := service.TaskType(models.DefaultTaskType)
+		require.NoError(t, err)
+		_, ok := types.Get(models.DefaultTaskType)
+		assert.False(t, ok)
+	})
+}
+
+func TestTaskServiceExecutionPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Timeout fails the task", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:    "slow",
+			Timeout: 20 * time.Millisecond,
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "slow", Description: "slow"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, "task timed out after 20ms", stored.Error)
+		require.NotNil(t, stored.ErrorInfo)
+		assert.Equal(t, models.ErrorCodeTimeout, stored.ErrorInfo.Code)
+		assert.True(t, stored.ErrorInfo.Retryable)
+	})
+
+	t.Run("Failed attempts are retried", func(t *testing.T) {
+		var calls atomic.Int32
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:  "flaky",
+			Retry: tasktypes.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				if calls.Add(1) < 3 {
+					return nil, errors.New("temporary failure")
+				}
+				return json.RawMessage(`"ok"`), nil
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "flaky", Description: "flaky"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusCompleted)
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, 3, stored.Attempts)
+		assert.Empty(t, stored.Error)
+		assert.Nil(t, stored.ErrorInfo)
+		assert.JSONEq(t, `"ok"`, string(stored.Result))
+	})
+
+	t.Run("Retries stop after the last attempt", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:  "broken",
+			Retry: tasktypes.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				return nil, errors.New("permanent failure")
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "broken", Description: "broken"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, 2, stored.Attempts)
+		assert.Equal(t, "permanent failure", stored.Error)
+	})
+
+	t.Run("Permanent errors are not retried", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:  "strict",
+			Retry: tasktypes.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				return nil, tasktypes.InvalidInput(errors.New("width must be even"))
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "strict", Description: "strict"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		assert.Equal(t, 1, stored.Attempts)
+		assert.Equal(t, &models.TaskError{Code: models.ErrorCodeInvalidInput, Message: "width must be even"}, stored.ErrorInfo)
+	})
+
+	t.Run("Catalog reports limits and queue depth", func(t *testing.T) {
+		release := make(chan struct{})
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:           "export",
+			Timeout:        time.Minute,
+			Retry:          tasktypes.RetryPolicy{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 10 * time.Second},
+			MaxConcurrency: 1,
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				<-release
+				return nil, nil
+			}),
+		}))
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(types))
+		defer close(release)
+
+		first, err := service.CreateTask(ctx, models.TaskCreate{Type: "export", Description: "first"})
+		require.NoError(t, err)
+		waitForStatus(t, store, first.ID, models.StatusProcessing)
+		_, err = service.CreateTask(ctx, models.TaskCreate{Type: "export", Description: "second"})
+		require.NoError(t, err)
+
+		list := service.TaskTypes()
+		require.Equal(t, 2, list.Total)
+		assert.Equal(t, models.DefaultTaskType, list.TaskTypes[0].Name)
+
+		export := list.TaskTypes[1]
+		assert.Equal(t, "export", export.Name)
+		assert.Equal(t, 60.0, export.TimeoutSeconds)
+		assert.Equal(t, &models.RetryPolicy{MaxAttempts: 3, BackoffSeconds: 1, MaxBackoffSeconds: 10}, export.RetryPolicy)
+		assert.Equal(t, 1, export.MaxConcurrency)
+		assert.Equal(t, 1, export.QueueDepth)
+		assert.Equal(t, 1, export.Processing)
+	})
+
+	t.Run("Stats summarize finished tasks", func(t *testing.T) {
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithTaskTypes(reportTypes(t)), WithStatsWindow(time.Minute))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Type: "report", Description: "stats"})
+		require.NoError(t, err)
+		waitForStatus(t, store, task.ID, models.StatusCompleted)
+
+		stats, err := service.TaskTypeStats("report")
+		require.NoError(t, err)
+		assert.Equal(t, 60.0, stats.WindowSeconds)
+		assert.Equal(t, 1, stats.Completed)
+		require.NotNil(t, stats.SuccessRate)
+		assert.Equal(t, 1.0, *stats.SuccessRate)
+		assert.NotNil(t, stats.Duration)
+
+		_, err = service.TaskTypeStats("missing")
+		assert.ErrorIs(t, err, tasktypes.ErrUnknownType)
+	})
+}
+
+// panickyStorage паникует один раз при завершении выполняющейся задачи
+type panickyStorage struct {
+	*storage.InMemoryTaskStorage
+	armed atomic.Bool
+}
+
+func (s *panickyStorage) Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error) {
+	if task, ok := s.Get(id); ok && task.Status == models.StatusProcessing && s.armed.CompareAndSwap(true, false) {
+		panic("storage exploded")
+	}
+	return s.InMemoryTaskStorage.Update(id, updateFn)
+}
+
+func TestTaskServicePanics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Executor panic fails the task and keeps the worker", func(t *testing.T) {
+		types := tasktypes.NewRegistry()
+		require.NoError(t, types.Register(tasktypes.TaskType{
+			Name:  "crash",
+			Retry: tasktypes.RetryPolicy{MaxAttempts: 3},
+			Executor: tasktypes.ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+				var m map[string]int
+				m["boom"]++
+				return nil, nil
+			}),
+		}))
+		reg := metrics.NewRegistry()
+		store := storage.NewInMemoryTaskStorage()
+		service := NewTaskService(store, WithWorkers(1), WithMetrics(reg), WithTaskTypes(types), WithProcessingTime(time.Millisecond, time.Millisecond))
+
+		crashed, err := service.CreateTask(ctx, models.TaskCreate{Type: "crash", Description: "crash"})
+		require.NoError(t, err)
+		waitForStatus(t, store, crashed.ID, models.StatusFailed)
+
+		stored, _ := store.Get(crashed.ID)
+		assert.Equal(t, 1, stored.Attempts, "panics are not retried")
+		require.NotNil(t, stored.ErrorInfo)
+		assert.Equal(t, models.ErrorCodePanic, stored.ErrorInfo.Code)
+		assert.Equal(t, "executor panicked: assignment to entry in nil map", stored.ErrorInfo.Message)
+		assert.Contains(t, stored.ErrorInfo.Stack, "task_types_test.go")
+
+		// Единственный воркер вернулся в пул
+		next, err := service.CreateTask(ctx, models.TaskCreate{Description: "next"})
+		require.NoError(t, err)
+		waitForStatus(t, store, next.ID, models.StatusCompleted)
+
+		var out strings.Builder
+		reg.WriteTo(&out)
+		assert.Contains(t, out.String(), `taskapi_task_panics_total{type="crash"} 1`)
+		assert.Contains(t, out.String(), `taskapi_tasks_failed_total{type="crash"} 1`)
+	})
+
+	t.Run("Panic outside the executor is recovered", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		store := &panickyStorage{InMemoryTaskStorage: storage.NewInMemoryTaskStorage()}
+		store.armed.Store(true)
+		service := NewTaskService(store, WithWorkers(1), WithMetrics(reg), WithProcessingTime(time.Millisecond, time.Millisecond))
+
+		task, err := service.CreateTask(ctx, models.TaskCreate{Description: "exploding"})
+		require.NoError(t, err)
+		waitForStatus(t, store.InMemoryTaskStorage, task.ID, models.StatusFailed)
+
+		stored, _ := store.Get(task.ID)
+		require.NotNil(t, stored.ErrorInfo)
+		assert.Equal(t, "task processing panicked: storage exploded", stored.Error)
+		assert.NotEmpty(t, stored.ErrorInfo.Stack)
+
+		next, err := service.CreateTask(ctx, models.TaskCreate{Description: "next"})
+		require.NoError(t, err)
+		waitForStatus(t, store.InMemoryTaskStorage, next.ID, models.StatusCompleted)
+
+		var out strings.Builder
+		reg.WriteTo(&out)
+		assert.Contains(t, out.String(), `taskapi_task_panics_total{type="default"} 1`)
+	})
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"http_api/internal/storage"
+	"http_api/internal/tasktypes"
+	"http_api/models"
+	"runtime/debug"
+	"time"
+)
+
+func (s *TaskService) worker(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.work:
+			s.busy.Add(1)
+			s.runTask(id)
+			s.dispatcher.finish(id)
+			s.busy.Add(-1)
+		}
+	}
+}
+
+// runTask выполняет задачу, перехватывая панику вне исполнителя (паника
+// самого исполнителя обрабатывается в invoke), чтобы воркер вернулся в пул
+func (s *TaskService) runTask(id string) {
+	// Подписчики журнала узнают о завершении попытки уже после смены статуса
+	defer s.logs.Finish(id)
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := tasktypes.Panic(value, debug.Stack())
+			panicErr.Message = fmt.Sprintf("task processing panicked: %v", value)
+			s.failPanicked(id, panicErr)
+		}
+	}()
+	s.processTask(id)
+}
+
+// failPanicked переводит выполнявшуюся задачу в failed после паники
+func (s *TaskService) failPanicked(id string, panicErr *tasktypes.Error) {
+	failure := tasktypes.Describe(panicErr)
+	task, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
+		if task.Status != models.StatusProcessing {
+			return nil, storage.ErrInvalidState
+		}
+		now := time.Now()
+		task.Status = models.StatusFailed
+		task.CompletedAt = &now
+		task.Duration = now.Sub(*task.StartedAt).Seconds()
+		task.Error = failure.Message
+		task.ErrorInfo = failure
+		return task, nil
+	})
+
+	taskType := models.DefaultTaskType
+	if err == nil {
+		taskType = typeLabel(task)
+		s.stats.record(task)
+		s.metrics.failed.WithLabelValues(taskType).Inc()
+	} else if current, ok := s.storage.Get(id); ok {
+		taskType = typeLabel(current)
+	}
+	s.metrics.panics.WithLabelValues(taskType).Inc()
+	s.logger.Error("task processing panicked", "task_id", id, "type", taskType, "error", panicErr, "stack", failure.Stack)
+}
+
+// schedule передает задачи из очереди свободным воркерам. Цикл просыпается
+// при изменении очереди и по таймеру, отмечая каждую итерацию для проверки готовности.
+func (s *TaskService) schedule(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.lastTick.Store(time.Now().UnixNano())
+
+		id, ok := s.dispatcher.take()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.dispatcher.wake:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		// Ждем свободного воркера, но пересматриваем выбор, если очередь изменилась
+		select {
+		case <-ctx.Done():
+			s.dispatcher.release(id)
+			return
+		case s.work <- id:
+		case <-s.dispatcher.wake:
+			s.dispatcher.release(id)
+		case <-ticker.C:
+			s.dispatcher.release(id)
+		}
+	}
+}
+
+// RecoverTasks ставит в очередь задачи, не завершенные при прошлом запуске.
+// Задачи в статусе processing остались от аварийной остановки и начинаются заново.
+func (s *TaskService) RecoverTasks(ctx context.Context) (int, error) {
+	tasks, err := s.storage.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, task := range tasks {
+		if task.TTLSeconds > 0 {
+			s.startReaper()
+		}
+		switch task.Status {
+		case models.StatusProcessing:
+			s.requeueInterrupted(task.ID)
+		case models.StatusPending:
+			s.dispatcher.restore(task.ID, models.NamespaceOf(&task), typeLabel(&task), task.Priority)
+		default:
+			continue
+		}
+		s.logger.InfoContext(ctx, "task recovered", "task_id", task.ID, "type", typeLabel(&task), "status", task.Status)
+		recovered++
+	}
+	return recovered, nil
+}
+
+// Shutdown перестает принимать новые задачи и ждет завершения выполняющихся,
+// пока не истечет ctx. Оставшиеся задачи прерываются и возвращаются в статус
+// pending, чтобы их подхватил следующий запуск. Ожидающие в очереди задачи
+// не запускаются.
+func (s *TaskService) Shutdown(ctx context.Context) error {
+	s.StopAccepting()
+	// Проверка хранения больше не запустится, и wg.Add не пересечется с wg.Wait
+	s.reaperOnce.Do(func() {})
+	s.stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.abortTasks()
+	<-done
+	return ctx.Err()
+}
+
+// StopAccepting отклоняет создание новых задач с ErrShuttingDown
+// и завершает потоки журналов, чтобы они не задерживали остановку HTTP-сервера
+func (s *TaskService) StopAccepting() {
+	s.draining.Store(true)
+	s.stopFollow()
+}
+
+// CheckWorkers сообщает о неготовности, когда все воркеры заняты и очередь заполнена
+func (s *TaskService) CheckWorkers(ctx context.Context) error {
+	if int(s.busy.Load()) >= s.workers && s.dispatcher.full() {
+		return fmt.Errorf("worker pool saturated: %d busy workers, %d queued tasks", s.busy.Load(), s.dispatcher.depth())
+	}
+	return nil
+}
+
+// CheckScheduler проверяет, что цикл планировщика не завис
+func (s *TaskService) CheckScheduler(ctx context.Context) error {
+	last := time.Unix(0, s.lastTick.Load())
+	if since := time.Since(last); since > 3*s.schedulerInterval {
+		return fmt.Errorf("scheduler loop last ticked %s ago", since.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// CheckDraining сообщает о неготовности во время остановки сервиса
+func (s *TaskService) CheckDraining(ctx context.Context) error {
+	if s.draining.Load() {
+		return errors.New("service is draining")
+	}
+	return nil
+}
+
+func (s *TaskService) requeueInterrupted(id string) {
+	task, err := s.storage.Update(id, func(task *models.Task) (*models.Task, error) {
+		if task.Status != models.StatusProcessing {
+			return task, nil
+		}
+
+		task.Status = models.StatusPending
+		task.StartedAt = nil
+		return task, nil
+	})
+	if err != nil {
+		s.logTransitionError(context.Background(), id, "requeue", err)
+		return
+	}
+
+	if !s.draining.Load() {
+		s.dispatcher.restore(id, models.NamespaceOf(task), typeLabel(task), task.Priority)
+	}
+}
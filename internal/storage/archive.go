@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"http_api/models"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileArchive дописывает удаляемые задачи в файл JSON Lines, по задаче на строку
+type FileArchive struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileArchive(path string) (*FileArchive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileArchive{file: file}, nil
+}
+
+// Archive записывает задачи и сбрасывает их на диск до возврата,
+// чтобы задача не была удалена раньше, чем сохранена
+func (a *FileArchive) Archive(tasks []models.Task) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w := bufio.NewWriter(a.file)
+	enc := json.NewEncoder(w)
+	for i := range tasks {
+		if err := enc.Encode(&tasks[i]); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *FileArchive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
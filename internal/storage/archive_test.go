@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"http_api/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive", "tasks.jsonl")
+
+	archive, err := NewFileArchive(path)
+	require.NoError(t, err)
+	require.NoError(t, archive.Archive([]models.Task{{ID: "a", Status: models.StatusCompleted}, {ID: "b", Status: models.StatusFailed}}))
+	require.NoError(t, archive.Close())
+
+	// Повторное открытие дописывает в конец
+	archive, err = NewFileArchive(path)
+	require.NoError(t, err)
+	require.NoError(t, archive.Archive([]models.Task{{ID: "c", Status: models.StatusCancelled}}))
+	require.NoError(t, archive.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var task models.Task
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &task))
+		ids = append(ids, task.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+}
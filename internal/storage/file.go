@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/models"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileTaskStorage держит задачи в памяти и сохраняет их снимок в JSON-файл,
+// из которого читает при старте. Изменения сохраняются в фоне не чаще раза
+// в flushInterval (0 - после каждого изменения), Close сохраняет последнее
+// состояние. Ошибка последнего сохранения возвращается из Check.
+type FileTaskStorage struct {
+	*InMemoryTaskStorage
+	path   string
+	saveMu sync.Mutex
+
+	flushInterval time.Duration
+	dirty         chan struct{}
+	done          chan struct{}
+	stopped       chan struct{}
+	closeOnce     sync.Once
+
+	errMu    sync.Mutex
+	flushErr error
+}
+
+func NewFileTaskStorage(path string, flushInterval time.Duration) (*FileTaskStorage, error) {
+	s := &FileTaskStorage{
+		InMemoryTaskStorage: NewInMemoryTaskStorage(),
+		path:                path,
+		flushInterval:       flushInterval,
+		dirty:               make(chan struct{}, 1),
+		done:                make(chan struct{}),
+		stopped:             make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.start()
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage file: %w", err)
+	}
+
+	var tasks []models.Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("invalid storage file %s: %w", path, err)
+	}
+	for i := range tasks {
+		s.put(&tasks[i])
+	}
+	s.start()
+	return s, nil
+}
+
+// start запускает фоновое сохранение; без интервала изменения сохраняются сразу
+func (s *FileTaskStorage) start() {
+	if s.flushInterval > 0 {
+		go s.flushLoop()
+	} else {
+		close(s.stopped)
+	}
+}
+
+func (s *FileTaskStorage) Create(task *models.Task) {
+	s.InMemoryTaskStorage.Create(task)
+	s.changed()
+}
+
+func (s *FileTaskStorage) Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error) {
+	task, err := s.InMemoryTaskStorage.Update(id, updateFn)
+	if err != nil {
+		return nil, err
+	}
+	s.changed()
+	return task, nil
+}
+
+func (s *FileTaskStorage) Delete(id string) bool {
+	if !s.InMemoryTaskStorage.Delete(id) {
+		return false
+	}
+	s.changed()
+	return true
+}
+
+func (s *FileTaskStorage) DeleteIf(id string, cond func(*models.Task) error) error {
+	if err := s.InMemoryTaskStorage.DeleteIf(id, cond); err != nil {
+		return err
+	}
+	s.changed()
+	return nil
+}
+
+// Flush записывает текущее состояние на диск
+func (s *FileTaskStorage) Flush() error {
+	err := s.save()
+	s.errMu.Lock()
+	s.flushErr = err
+	s.errMu.Unlock()
+	return err
+}
+
+// Close останавливает фоновое сохранение и записывает последнее состояние
+func (s *FileTaskStorage) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	<-s.stopped
+	return s.Flush()
+}
+
+func (s *FileTaskStorage) save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	tasks, err := s.GetAll()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return err
+	}
+
+	// Запись через временный файл, чтобы при сбое не оставить файл наполовину записанным
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Check проверяет, что последнее сохранение удалось и каталог хранилища доступен для записи
+func (s *FileTaskStorage) Check(ctx context.Context) error {
+	if err := s.InMemoryTaskStorage.Check(ctx); err != nil {
+		return err
+	}
+	s.errMu.Lock()
+	flushErr := s.flushErr
+	s.errMu.Unlock()
+	if flushErr != nil {
+		return fmt.Errorf("failed to persist tasks: %w", flushErr)
+	}
+
+	probe, err := os.CreateTemp(filepath.Dir(s.path), ".healthcheck*")
+	if err != nil {
+		return fmt.Errorf("storage directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// changed отмечает изменение: сохраняет сразу или будит фоновое сохранение
+func (s *FileTaskStorage) changed() {
+	if s.flushInterval <= 0 {
+		s.persist()
+		return
+	}
+	select {
+	case s.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// flushLoop сохраняет накопленные изменения одним снимком не чаще раза в flushInterval
+func (s *FileTaskStorage) flushLoop() {
+	defer close(s.stopped)
+	timer := time.NewTimer(s.flushInterval)
+	timer.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.dirty:
+		}
+		timer.Reset(s.flushInterval)
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.persist()
+	}
+}
+
+func (s *FileTaskStorage) persist() {
+	if err := s.Flush(); err != nil {
+		slog.Error("failed to persist tasks", "path", s.path, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"http_api/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTaskStorage(t *testing.T) {
+	t.Run("State survives reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data", "tasks.json")
+
+		storage, err := NewFileTaskStorage(path, 0)
+		require.NoError(t, err)
+
+		storage.Create(&models.Task{ID: "kept", Status: models.StatusPending})
+		storage.Create(&models.Task{ID: "removed", Status: models.StatusPending})
+		_, err = storage.Update("kept", func(t *models.Task) (*models.Task, error) {
+			t.Status = models.StatusProcessing
+			return t, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, storage.Delete("removed"))
+
+		reopened, err := NewFileTaskStorage(path, 0)
+		require.NoError(t, err)
+
+		task, exists := reopened.Get("kept")
+		assert.True(t, exists)
+		assert.Equal(t, models.StatusProcessing, task.Status)
+
+		_, exists = reopened.Get("removed")
+		assert.False(t, exists)
+	})
+
+	t.Run("Corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+		require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+		_, err := NewFileTaskStorage(path, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Changes are saved in batches and on close", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+
+		storage, err := NewFileTaskStorage(path, time.Hour)
+		require.NoError(t, err)
+		storage.Create(&models.Task{ID: "pending", Status: models.StatusPending})
+
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		require.NoError(t, storage.Close())
+		reopened, err := NewFileTaskStorage(path, 0)
+		require.NoError(t, err)
+		_, exists := reopened.Get("pending")
+		assert.True(t, exists)
+	})
+
+	t.Run("Failed save makes storage unready", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+
+		storage, err := NewFileTaskStorage(path, 0)
+		require.NoError(t, err)
+		require.NoError(t, storage.Check(context.Background()))
+
+		// Каталог на месте файла не дает заменить снимок
+		require.NoError(t, os.Mkdir(path, 0o755))
+		storage.Create(&models.Task{ID: "lost", Status: models.StatusPending})
+		assert.Error(t, storage.Check(context.Background()))
+
+		require.NoError(t, os.Remove(path))
+		storage.Create(&models.Task{ID: "saved", Status: models.StatusPending})
+		assert.NoError(t, storage.Check(context.Background()))
+	})
+}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
 	"errors"
-	"http_api/internal/models"
+	"http_api/internal/labels"
+	"http_api/models"
+	"sort"
 	"sync"
 )
 
@@ -10,32 +13,102 @@ type TaskStorage interface {
 	Create(task *models.Task)
 	Get(id string) (*models.Task, bool)
 	GetAll() ([]models.Task, error)
+	// Select возвращает задачи, метки которых удовлетворяют селектору
+	Select(selector labels.Selector) ([]models.Task, error)
 	Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error)
 	Delete(id string) bool
+	// DeleteIf удаляет задачу, если cond не вернула ошибку; проверка и удаление атомарны
+	DeleteIf(id string, cond func(*models.Task) error) error
 }
 
 type InMemoryTaskStorage struct {
 	mu    sync.RWMutex
 	tasks map[string]*models.Task
+	// index - индекс меток: ключ -> значение -> id задач
+	index map[string]map[string]map[string]struct{}
+	// counts - число задач в каждом статусе
+	counts map[models.TaskStatus]int
 }
 
 func NewInMemoryTaskStorage() *InMemoryTaskStorage {
 	return &InMemoryTaskStorage{
-		tasks: make(map[string]*models.Task),
+		tasks:  make(map[string]*models.Task),
+		index:  make(map[string]map[string]map[string]struct{}),
+		counts: make(map[models.TaskStatus]int),
 	}
 }
 
 func (s *InMemoryTaskStorage) Create(task *models.Task) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if task.Version == 0 {
+		task.Version = 1
+	}
+	s.put(task)
+}
+
+// put сохраняет задачу и обновляет индекс меток и счетчики статусов;
+// вызывается под блокировкой
+func (s *InMemoryTaskStorage) put(task *models.Task) {
+	if old, exists := s.tasks[task.ID]; exists {
+		s.unindex(old)
+		s.counts[old.Status]--
+	}
 	s.tasks[task.ID] = task
+	s.counts[task.Status]++
+	for key, value := range task.Labels {
+		values, ok := s.index[key]
+		if !ok {
+			values = make(map[string]map[string]struct{})
+			s.index[key] = values
+		}
+		ids, ok := values[value]
+		if !ok {
+			ids = make(map[string]struct{})
+			values[value] = ids
+		}
+		ids[task.ID] = struct{}{}
+	}
+}
+
+func (s *InMemoryTaskStorage) remove(id string) {
+	if task, exists := s.tasks[id]; exists {
+		s.unindex(task)
+		s.counts[task.Status]--
+		delete(s.tasks, id)
+	}
+}
+
+// CountByStatus возвращает число задач в статусе status без перебора хранилища
+func (s *InMemoryTaskStorage) CountByStatus(status models.TaskStatus) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.counts[status]
+}
+
+func (s *InMemoryTaskStorage) unindex(task *models.Task) {
+	for key, value := range task.Labels {
+		values := s.index[key]
+		delete(values[value], task.ID)
+		if len(values[value]) == 0 {
+			delete(values, value)
+		}
+		if len(values) == 0 {
+			delete(s.index, key)
+		}
+	}
 }
 
 func (s *InMemoryTaskStorage) Get(id string) (*models.Task, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	task, exists := s.tasks[id]
-	return task, exists
+	if !exists {
+		return nil, false
+	}
+	// Возвращаем копию: хранимую задачу параллельно изменяют воркеры
+	copied := *task
+	return &copied, true
 }
 
 func (s *InMemoryTaskStorage) GetAll() ([]models.Task, error) {
@@ -46,9 +119,82 @@ func (s *InMemoryTaskStorage) GetAll() ([]models.Task, error) {
 	for _, task := range s.tasks {
 		tasks = append(tasks, *task)
 	}
+	sortTasks(tasks)
 	return tasks, nil
 }
 
+func (s *InMemoryTaskStorage) Select(selector labels.Selector) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]models.Task, 0)
+	if ids, ok := s.candidates(selector); ok {
+		for id := range ids {
+			if task, exists := s.tasks[id]; exists && selector.Matches(task.Labels) {
+				tasks = append(tasks, *task)
+			}
+		}
+	} else {
+		for _, task := range s.tasks {
+			if selector.Matches(task.Labels) {
+				tasks = append(tasks, *task)
+			}
+		}
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// candidates сужает выборку по индексу: условия =, in и наличия ключа дают
+// множества id, которые пересекаются. Если таких условий нет, возвращает false
+// и задачи проверяются полным перебором.
+func (s *InMemoryTaskStorage) candidates(selector labels.Selector) (map[string]struct{}, bool) {
+	var result map[string]struct{}
+	indexed := false
+	for _, r := range selector {
+		var matched map[string]struct{}
+		switch r.Operator {
+		case labels.Equals, labels.In:
+			matched = make(map[string]struct{})
+			for _, value := range r.Values {
+				for id := range s.index[r.Key][value] {
+					matched[id] = struct{}{}
+				}
+			}
+		case labels.Exists:
+			matched = make(map[string]struct{})
+			for _, ids := range s.index[r.Key] {
+				for id := range ids {
+					matched[id] = struct{}{}
+				}
+			}
+		default:
+			continue
+		}
+
+		if !indexed {
+			result, indexed = matched, true
+			continue
+		}
+		for id := range result {
+			if _, ok := matched[id]; !ok {
+				delete(result, id)
+			}
+		}
+	}
+	return result, indexed
+}
+
+// sortTasks задает стабильный порядок, нужный для корректной постраничной выдачи
+func sortTasks(tasks []models.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
 func (s *InMemoryTaskStorage) Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -58,12 +204,16 @@ func (s *InMemoryTaskStorage) Update(id string, updateFn func(*models.Task) (*mo
 		return nil, ErrTaskNotFound
 	}
 
-	updatedTask, err := updateFn(task)
+	// Изменения применяются к копии, чтобы не трогать ранее выданные задачи
+	copied := *task
+	updatedTask, err := updateFn(&copied)
 	if err != nil {
 		return nil, err
 	}
 
-	s.tasks[id] = updatedTask
+	// Версия растет при каждом изменении и служит для оптимистичной блокировки
+	updatedTask.Version = task.Version + 1
+	s.put(updatedTask)
 	return updatedTask, nil
 }
 
@@ -75,10 +225,34 @@ func (s *InMemoryTaskStorage) Delete(id string) bool {
 		return false
 	}
 
-	delete(s.tasks, id)
+	s.remove(id)
 	return true
 }
 
+func (s *InMemoryTaskStorage) DeleteIf(id string, cond func(*models.Task) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	copied := *task
+	if err := cond(&copied); err != nil {
+		return err
+	}
+
+	s.remove(id)
+	return nil
+}
+
+// Check подтверждает, что хранилище доступно: блокировка не удерживается бесконечно
+func (s *InMemoryTaskStorage) Check(ctx context.Context) error {
+	s.mu.Lock()
+	s.mu.Unlock()
+	return nil
+}
+
 var (
 	ErrTaskNotFound = errors.New("task not found")
 	ErrInvalidState = errors.New("invalid task state for operation")
@@ -2,7 +2,8 @@ package storage
 
 import (
 	"errors"
-	"http_api/internal/models"
+	"http_api/internal/labels"
+	"http_api/models"
 	"sync"
 	"testing"
 
@@ -42,6 +43,28 @@ func TestInMemoryTaskStorage(t *testing.T) {
 		assert.Equal(t, models.StatusCompleted, updatedTask.Status)
 	})
 
+	t.Run("Status counts follow changes", func(t *testing.T) {
+		storage := NewInMemoryTaskStorage()
+		storage.Create(&models.Task{ID: "a", Status: models.StatusPending})
+		storage.Create(&models.Task{ID: "b", Status: models.StatusPending})
+		storage.Update("a", func(t *models.Task) (*models.Task, error) {
+			t.Status = models.StatusProcessing
+			return t, nil
+		})
+		assert.Equal(t, 1, storage.CountByStatus(models.StatusPending))
+		assert.Equal(t, 1, storage.CountByStatus(models.StatusProcessing))
+
+		// Неудачное изменение счетчики не трогает
+		storage.Update("b", func(t *models.Task) (*models.Task, error) {
+			t.Status = models.StatusFailed
+			return nil, ErrInvalidState
+		})
+		storage.Delete("a")
+		assert.Equal(t, 1, storage.CountByStatus(models.StatusPending))
+		assert.Equal(t, 0, storage.CountByStatus(models.StatusProcessing))
+		assert.Equal(t, 0, storage.CountByStatus(models.StatusFailed))
+	})
+
 	t.Run("Update non-existent task", func(t *testing.T) {
 		storage := NewInMemoryTaskStorage()
 
@@ -52,6 +75,76 @@ func TestInMemoryTaskStorage(t *testing.T) {
 		assert.True(t, errors.Is(err, ErrTaskNotFound))
 	})
 
+	t.Run("Update increments version", func(t *testing.T) {
+		storage := NewInMemoryTaskStorage()
+		storage.Create(&models.Task{ID: "versioned"})
+
+		task, _ := storage.Get("versioned")
+		assert.Equal(t, int64(1), task.Version)
+
+		updated, err := storage.Update("versioned", func(t *models.Task) (*models.Task, error) {
+			return t, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), updated.Version)
+
+		_, err = storage.Update("versioned", func(t *models.Task) (*models.Task, error) {
+			return nil, ErrInvalidState
+		})
+		assert.ErrorIs(t, err, ErrInvalidState)
+		task, _ = storage.Get("versioned")
+		assert.Equal(t, int64(2), task.Version)
+	})
+
+	t.Run("DeleteIf checks condition", func(t *testing.T) {
+		storage := NewInMemoryTaskStorage()
+		storage.Create(&models.Task{ID: "cond"})
+		errStale := errors.New("stale")
+
+		err := storage.DeleteIf("cond", func(*models.Task) error { return errStale })
+		assert.ErrorIs(t, err, errStale)
+		_, exists := storage.Get("cond")
+		assert.True(t, exists)
+
+		assert.NoError(t, storage.DeleteIf("cond", func(*models.Task) error { return nil }))
+		assert.ErrorIs(t, storage.DeleteIf("cond", func(*models.Task) error { return nil }), ErrTaskNotFound)
+	})
+
+	t.Run("Select by labels uses index", func(t *testing.T) {
+		storage := NewInMemoryTaskStorage()
+		storage.Create(&models.Task{ID: "a", Labels: map[string]string{"env": "prod", "team": "a"}})
+		storage.Create(&models.Task{ID: "b", Labels: map[string]string{"env": "prod", "team": "b", "draft": ""}})
+		storage.Create(&models.Task{ID: "c", Labels: map[string]string{"env": "dev"}})
+		storage.Create(&models.Task{ID: "d"})
+
+		ids := func(selector string) []string {
+			sel, err := labels.Parse(selector)
+			assert.NoError(t, err)
+			tasks, err := storage.Select(sel)
+			assert.NoError(t, err)
+			var ids []string
+			for _, task := range tasks {
+				ids = append(ids, task.ID)
+			}
+			return ids
+		}
+
+		assert.Equal(t, []string{"a", "b"}, ids("env=prod"))
+		assert.Equal(t, []string{"a"}, ids("env=prod,!draft"))
+		assert.Equal(t, []string{"b"}, ids("team in (b,c),env"))
+		assert.Equal(t, []string{"a", "b", "c"}, ids("env in (prod,dev)"))
+		assert.Equal(t, []string{"c", "d"}, ids("env!=prod"))
+
+		storage.Update("c", func(task *models.Task) (*models.Task, error) {
+			task.Labels = map[string]string{"env": "prod"}
+			return task, nil
+		})
+		storage.Delete("a")
+		assert.Equal(t, []string{"b", "c"}, ids("env=prod"))
+		assert.Empty(t, ids("env=dev"))
+		assert.Empty(t, storage.index["team"]["a"])
+	})
+
 	t.Run("Delete existing task", func(t *testing.T) {
 		storage := NewInMemoryTaskStorage()
 		task := &models.Task{ID: "test3"}
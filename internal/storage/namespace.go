@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"http_api/internal/labels"
+	"http_api/models"
+)
+
+// namespaced ограничивает хранилище одним пространством имен: задачи
+// других пространств для него не существуют.
+type namespaced struct {
+	TaskStorage
+	namespace string
+}
+
+// InNamespace возвращает представление хранилища, видящее только задачи namespace.
+// Create проставляет задаче это пространство имен.
+func InNamespace(s TaskStorage, namespace string) TaskStorage {
+	return &namespaced{TaskStorage: s, namespace: namespace}
+}
+
+func (s *namespaced) owns(task *models.Task) bool {
+	return models.NamespaceOf(task) == s.namespace
+}
+
+func (s *namespaced) Create(task *models.Task) {
+	task.Namespace = s.namespace
+	s.TaskStorage.Create(task)
+}
+
+func (s *namespaced) Get(id string) (*models.Task, bool) {
+	task, exists := s.TaskStorage.Get(id)
+	if !exists || !s.owns(task) {
+		return nil, false
+	}
+	return task, true
+}
+
+func (s *namespaced) GetAll() ([]models.Task, error) {
+	all, err := s.TaskStorage.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(all), nil
+}
+
+func (s *namespaced) Select(selector labels.Selector) ([]models.Task, error) {
+	selected, err := s.TaskStorage.Select(selector)
+	if err != nil {
+		return nil, err
+	}
+	return s.filter(selected), nil
+}
+
+func (s *namespaced) filter(all []models.Task) []models.Task {
+	tasks := all[:0]
+	for i := range all {
+		if s.owns(&all[i]) {
+			tasks = append(tasks, all[i])
+		}
+	}
+	return tasks
+}
+
+func (s *namespaced) Update(id string, updateFn func(*models.Task) (*models.Task, error)) (*models.Task, error) {
+	return s.TaskStorage.Update(id, func(task *models.Task) (*models.Task, error) {
+		if !s.owns(task) {
+			return nil, ErrTaskNotFound
+		}
+		return updateFn(task)
+	})
+}
+
+func (s *namespaced) Delete(id string) bool {
+	return s.DeleteIf(id, func(*models.Task) error { return nil }) == nil
+}
+
+func (s *namespaced) DeleteIf(id string, cond func(*models.Task) error) error {
+	return s.TaskStorage.DeleteIf(id, func(task *models.Task) error {
+		if !s.owns(task) {
+			return ErrTaskNotFound
+		}
+		return cond(task)
+	})
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"http_api/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInNamespace(t *testing.T) {
+	store := NewInMemoryTaskStorage()
+	teamA := InNamespace(store, "team-a")
+	teamB := InNamespace(store, "team-b")
+	defaults := InNamespace(store, models.DefaultNamespace)
+
+	teamA.Create(&models.Task{ID: "a1", Status: models.StatusPending})
+	teamB.Create(&models.Task{ID: "b1", Status: models.StatusPending})
+	// Задача без пространства имен, например из файла старой версии
+	store.Create(&models.Task{ID: "legacy", Status: models.StatusPending})
+
+	t.Run("Create stamps namespace", func(t *testing.T) {
+		task, exists := store.Get("a1")
+		assert.True(t, exists)
+		assert.Equal(t, "team-a", task.Namespace)
+	})
+
+	t.Run("Get does not cross namespaces", func(t *testing.T) {
+		_, exists := teamA.Get("b1")
+		assert.False(t, exists)
+		_, exists = teamA.Get("a1")
+		assert.True(t, exists)
+		_, exists = defaults.Get("legacy")
+		assert.True(t, exists)
+	})
+
+	t.Run("GetAll lists own tasks", func(t *testing.T) {
+		tasks, err := teamB.GetAll()
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 1)
+		assert.Equal(t, "b1", tasks[0].ID)
+	})
+
+	t.Run("Update does not cross namespaces", func(t *testing.T) {
+		called := false
+		_, err := teamA.Update("b1", func(task *models.Task) (*models.Task, error) {
+			called = true
+			return task, nil
+		})
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		assert.False(t, called)
+	})
+
+	t.Run("Delete does not cross namespaces", func(t *testing.T) {
+		assert.False(t, teamA.Delete("b1"))
+		_, exists := store.Get("b1")
+		assert.True(t, exists)
+
+		assert.True(t, teamB.Delete("b1"))
+		_, exists = store.Get("b1")
+		assert.False(t, exists)
+	})
+}
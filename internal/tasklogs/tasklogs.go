@@ -0,0 +1,384 @@
+// Package tasklogs хранит построчные журналы выполнения задач, которые
+// исполнители пишут через writer или логгер из контекста.
+package tasklogs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"http_api/models"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Потоки журнала
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+	// System - сообщения самого сервиса о выполнении
+	System = "system"
+)
+
+const (
+	defaultMaxLines = 10000
+	// maxLineBytes - строка длиннее разбивается на несколько записей
+	maxLineBytes = 64 << 10
+)
+
+// Entry - строка журнала задачи
+type Entry = models.TaskLogEntry
+
+// Query отбирает строки журнала. Условия применяются по порядку: AfterSeq, Since, Tail.
+type Query struct {
+	// AfterSeq оставляет строки с номером больше AfterSeq
+	AfterSeq int64
+	// Since оставляет строки, записанные не раньше Since
+	Since time.Time
+	// Tail оставляет последние Tail строк (0 - все)
+	Tail int
+}
+
+func (q Query) apply(entries []Entry) []Entry {
+	var result []Entry
+	for _, e := range entries {
+		if e.Seq > q.AfterSeq && !e.Time.Before(q.Since) {
+			result = append(result, e)
+		}
+	}
+	if q.Tail > 0 && len(result) > q.Tail {
+		result = result[len(result)-q.Tail:]
+	}
+	return result
+}
+
+type taskLog struct {
+	entries []Entry
+	next    int64
+	file    *os.File
+	// changed закрывается при появлении новых строк и завершении попытки
+	changed chan struct{}
+}
+
+// complete сообщает, что в памяти весь журнал, без вытесненных строк
+func (l *taskLog) complete() bool {
+	return len(l.entries) == 0 || l.entries[0].Seq == 1
+}
+
+func (l *taskLog) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Store держит журналы задач в памяти: для каждой задачи хранятся последние
+// maxLines строк, более старые вытесняются. Хранилище, открытое OpenStore,
+// дополнительно пишет журналы в файлы, из которых читаются вытесненные строки
+// и журналы задач, выполнявшихся до перезапуска.
+type Store struct {
+	mu       sync.Mutex
+	maxLines int
+	dir      string
+	now      func() time.Time
+	logs     map[string]*taskLog
+	// waiting - подписчики журналов, которых нет в памяти; канал закрывается,
+	// когда журнал откроется, попытка завершится или журнал удалят
+	waiting map[string]chan struct{}
+}
+
+// NewStore создает хранилище журналов в памяти; maxLines <= 0 - значение по умолчанию
+func NewStore(maxLines int) *Store {
+	if maxLines <= 0 {
+		maxLines = defaultMaxLines
+	}
+	return &Store{
+		maxLines: maxLines,
+		now:      time.Now,
+		logs:     make(map[string]*taskLog),
+		waiting:  make(map[string]chan struct{}),
+	}
+}
+
+// OpenStore создает хранилище, сохраняющее журналы в каталоге dir
+func OpenStore(dir string, maxLines int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := NewStore(maxLines)
+	s.dir = dir
+	return s, nil
+}
+
+// Append добавляет строку в журнал задачи
+func (s *Store) Append(taskID, stream, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.log(taskID)
+	log.next++
+	entry := Entry{Seq: log.next, Time: s.now(), Stream: stream, Line: line}
+	log.entries = append(log.entries, entry)
+	if over := len(log.entries) - s.maxLines; over > 0 {
+		log.entries = append(log.entries[:0:0], log.entries[over:]...)
+	}
+	if s.dir != "" {
+		if err := s.persist(taskID, log, entry); err != nil {
+			slog.Warn("failed to persist task log", "task_id", taskID, "error", err)
+		}
+	}
+	log.notify()
+}
+
+// log возвращает журнал задачи, при необходимости продолжая журнал из файла.
+// Вызывается под s.mu.
+func (s *Store) log(taskID string) *taskLog {
+	log, ok := s.logs[taskID]
+	if ok {
+		return log
+	}
+	log = &taskLog{changed: make(chan struct{})}
+	if s.dir != "" {
+		if entries := readFile(s.path(taskID)); len(entries) > 0 {
+			log.next = entries[len(entries)-1].Seq
+			log.entries = Query{Tail: s.maxLines}.apply(entries)
+		}
+	}
+	s.logs[taskID] = log
+	s.wake(taskID)
+	return log
+}
+
+// wake будит подписчиков журнала, которого не было в памяти. Вызывается под s.mu.
+func (s *Store) wake(taskID string) {
+	if ch, ok := s.waiting[taskID]; ok {
+		close(ch)
+		delete(s.waiting, taskID)
+	}
+}
+
+func (s *Store) persist(taskID string, log *taskLog, entry Entry) error {
+	if log.file == nil {
+		file, err := os.OpenFile(s.path(taskID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		log.file = file
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = log.file.Write(append(data, '\n'))
+	return err
+}
+
+func (s *Store) path(taskID string) string {
+	return filepath.Join(s.dir, url.PathEscape(taskID)+".log")
+}
+
+// Entries возвращает копию журнала задачи
+func (s *Store) Entries(taskID string) []Entry {
+	return s.Read(taskID, Query{})
+}
+
+// Read возвращает строки журнала задачи, подходящие под запрос. Если нужные
+// строки уже вытеснены из памяти, журнал читается из файла.
+func (s *Store) Read(taskID string, q Query) []Entry {
+	s.mu.Lock()
+	log, ok := s.logs[taskID]
+	if ok {
+		entries := q.apply(log.entries)
+		if s.dir == "" || log.complete() || q.AfterSeq >= log.entries[0].Seq-1 || q.Tail > 0 && len(entries) == q.Tail {
+			s.mu.Unlock()
+			return entries
+		}
+	}
+	s.mu.Unlock()
+
+	if s.dir == "" {
+		return nil
+	}
+	return q.apply(readFile(s.path(taskID)))
+}
+
+// Changed возвращает канал, который закроется при появлении в журнале задачи
+// новых строк, завершении попытки или удалении журнала. Журнал, которого нет
+// в памяти, не создается и не читается из файла.
+func (s *Store) Changed(taskID string) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if log, ok := s.logs[taskID]; ok {
+		return log.changed
+	}
+	ch, ok := s.waiting[taskID]
+	if !ok {
+		ch = make(chan struct{})
+		s.waiting[taskID] = ch
+	}
+	return ch
+}
+
+// Finish сообщает подписчикам о завершении попытки и закрывает файл журнала.
+// Журнал, сохраненный в файл, вытесняется из памяти.
+func (s *Store) Finish(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wake(taskID)
+	log, ok := s.logs[taskID]
+	if !ok {
+		return
+	}
+	if log.file != nil {
+		log.file.Close()
+		log.file = nil
+	}
+	log.notify()
+	if s.dir != "" {
+		delete(s.logs, taskID)
+	}
+}
+
+// Delete удаляет журнал задачи
+func (s *Store) Delete(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wake(taskID)
+	if log, ok := s.logs[taskID]; ok {
+		if log.file != nil {
+			log.file.Close()
+		}
+		log.notify()
+		delete(s.logs, taskID)
+	}
+	if s.dir != "" {
+		if err := os.Remove(s.path(taskID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to remove task log", "task_id", taskID, "error", err)
+		}
+	}
+}
+
+// Close закрывает открытые файлы журналов
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, log := range s.logs {
+		if log.file != nil {
+			errs = append(errs, log.file.Close())
+			log.file = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readFile читает журнал из файла, пропуская поврежденные строки
+// (например, недописанную последнюю)
+func readFile(path string) []Entry {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 8*maxLineBytes)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Logger возвращает логгер для исполнителей: записи попадают в поток System
+// журнала задачи из ctx, время записи хранится в самой строке журнала
+func Logger(ctx context.Context) *slog.Logger {
+	return slog.New(slog.NewTextHandler(Writer(ctx, System), &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+type contextKey struct{}
+
+type sink struct {
+	store  *Store
+	taskID string
+}
+
+// WithTask привязывает к контексту журнал задачи taskID
+func WithTask(ctx context.Context, store *Store, taskID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, sink{store: store, taskID: taskID})
+}
+
+// Writer возвращает writer, который разбивает вывод на строки и пишет их
+// в поток stream журнала задачи из ctx. Без журнала в ctx вывод отбрасывается.
+// Незавершенная последняя строка записывается при Close.
+func Writer(ctx context.Context, stream string) io.WriteCloser {
+	s, ok := ctx.Value(contextKey{}).(sink)
+	if !ok {
+		return nopCloser{io.Discard}
+	}
+	return &lineWriter{sink: s, stream: stream}
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+type lineWriter struct {
+	mu     sync.Mutex
+	sink   sink
+	stream string
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= maxLineBytes {
+		w.emit(w.buf[:maxLineBytes])
+		w.buf = w.buf[maxLineBytes:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *lineWriter) emit(line []byte) {
+	w.sink.store.Append(w.sink.taskID, w.stream, string(bytes.TrimSuffix(line, []byte("\r"))))
+}
@@ -0,0 +1,163 @@
+package tasklogs
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(entries []Entry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.Stream+": "+e.Line)
+	}
+	return result
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(3)
+	for _, line := range []string{"one", "two", "three", "four"} {
+		store.Append("t1", Stdout, line)
+	}
+	store.Append("t2", Stderr, "other")
+
+	entries := store.Entries("t1")
+	assert.Equal(t, []string{"stdout: two", "stdout: three", "stdout: four"}, lines(entries))
+	assert.Equal(t, int64(2), entries[0].Seq)
+
+	store.Delete("t1")
+	assert.Empty(t, store.Entries("t1"))
+	assert.Len(t, store.Entries("t2"), 1)
+}
+
+func TestWriter(t *testing.T) {
+	store := NewStore(0)
+	ctx := WithTask(context.Background(), store, "t1")
+
+	w := Writer(ctx, Stderr)
+	io.WriteString(w, "first\r\nsec")
+	io.WriteString(w, "ond\nthird")
+	assert.Equal(t, []string{"stderr: first", "stderr: second"}, lines(store.Entries("t1")))
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, "stderr: third", lines(store.Entries("t1"))[2])
+
+	long := Writer(ctx, Stdout)
+	io.WriteString(long, strings.Repeat("x", maxLineBytes+1))
+	long.Close()
+	entries := store.Entries("t1")
+	assert.Len(t, entries[3].Line, maxLineBytes)
+	assert.Equal(t, "x", entries[4].Line)
+
+	// Без журнала в контексте вывод отбрасывается
+	n, err := Writer(context.Background(), Stdout).Write([]byte("lost\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+}
+
+func TestQuery(t *testing.T) {
+	store := NewStore(0)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	store.now = func() time.Time { return now }
+	for i, line := range []string{"a", "b", "c", "d"} {
+		now = start.Add(time.Duration(i) * time.Minute)
+		store.Append("t1", Stdout, line)
+	}
+
+	assert.Equal(t, []string{"stdout: c", "stdout: d"}, lines(store.Read("t1", Query{Tail: 2})))
+	assert.Equal(t, []string{"stdout: b", "stdout: c", "stdout: d"}, lines(store.Read("t1", Query{Since: start.Add(time.Minute)})))
+	assert.Equal(t, []string{"stdout: d"}, lines(store.Read("t1", Query{AfterSeq: 2, Tail: 1})))
+	assert.Empty(t, store.Read("t1", Query{AfterSeq: 4}))
+}
+
+func TestDurableStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(dir, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"one", "two", "three"} {
+		store.Append("t1", Stdout, line)
+	}
+
+	// Вытесненные из памяти строки читаются из файла
+	assert.Equal(t, []string{"stdout: two", "stdout: three"}, lines(store.Read("t1", Query{Tail: 2})))
+	assert.Equal(t, []string{"stdout: one", "stdout: two", "stdout: three"}, lines(store.Entries("t1")))
+
+	store.Finish("t1")
+	require.NoError(t, store.Close())
+
+	// После перезапуска журнал продолжается с прежней нумерацией
+	reopened, err := OpenStore(dir, 2)
+	require.NoError(t, err)
+	reopened.Append("t1", System, "four")
+	entries := reopened.Entries("t1")
+	require.Len(t, entries, 4)
+	assert.Equal(t, int64(4), entries[3].Seq)
+
+	reopened.Delete("t1")
+	assert.Empty(t, reopened.Entries("t1"))
+	files, _ := os.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestChanged(t *testing.T) {
+	store := NewStore(0)
+	changed := store.Changed("t1")
+
+	store.Append("t1", Stdout, "line")
+	select {
+	case <-changed:
+	default:
+		t.Fatal("append did not notify subscribers")
+	}
+
+	changed = store.Changed("t1")
+	store.Finish("t1")
+	select {
+	case <-changed:
+	default:
+		t.Fatal("finish did not notify subscribers")
+	}
+
+	// Подписка на журнал, которого нет в памяти, его не создает
+	changed = store.Changed("t2")
+	assert.NotContains(t, store.logs, "t2")
+	store.Finish("t2")
+	select {
+	case <-changed:
+	default:
+		t.Fatal("finish did not notify subscribers of a missing log")
+	}
+	assert.Empty(t, store.waiting)
+}
+
+func TestChangedDoesNotLoadFinishedLog(t *testing.T) {
+	store, err := OpenStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	store.Append("t1", Stdout, "line")
+	store.Finish("t1")
+
+	changed := store.Changed("t1")
+	assert.NotContains(t, store.logs, "t1")
+
+	store.Append("t1", Stdout, "retry")
+	select {
+	case <-changed:
+	default:
+		t.Fatal("append did not notify subscribers")
+	}
+	assert.Equal(t, []string{"stdout: line", "stdout: retry"}, lines(store.Entries("t1")))
+}
+
+func TestLogger(t *testing.T) {
+	store := NewStore(0)
+	Logger(WithTask(context.Background(), store, "t1")).Info("request sent", "status", 200)
+
+	assert.Equal(t, []string{"system: level=INFO msg=\"request sent\" status=200"}, lines(store.Entries("t1")))
+}
@@ -0,0 +1,79 @@
+package tasktypes
+
+import (
+	"errors"
+	"fmt"
+	"http_api/models"
+)
+
+// Error - ошибка исполнителя с кодом, признаком повторяемости и подробностями,
+// которые попадают в error_info задачи. Ошибки без этого типа считаются
+// повторяемыми с кодом executor_error.
+type Error struct {
+	Code      string
+	Message   string
+	Retryable bool
+	Details   map[string]interface{}
+	// Stack - стек вызовов в момент паники исполнителя
+	Stack string
+	// Err - исходная ошибка, доступная через errors.Is/As
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Permanent помечает ошибку как неповторяемую с кодом code
+func Permanent(code string, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+// Retryable помечает ошибку как повторяемую с кодом code
+func Retryable(code string, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Retryable: true, Err: err}
+}
+
+// InvalidInput сообщает, что вход задачи не годится для исполнителя; повтор не поможет
+func InvalidInput(err error) *Error {
+	return Permanent(models.ErrorCodeInvalidInput, err)
+}
+
+// Panic описывает перехваченную панику исполнителя
+func Panic(value interface{}, stack []byte) *Error {
+	return &Error{
+		Code:    models.ErrorCodePanic,
+		Message: fmt.Sprintf("executor panicked: %v", value),
+		Details: map[string]interface{}{"value": fmt.Sprint(value)},
+		Stack:   string(stack),
+	}
+}
+
+// Describe переводит ошибку выполнения в структурированную причину неудачи
+func Describe(err error) *models.TaskError {
+	var typed *Error
+	if errors.As(err, &typed) {
+		code := typed.Code
+		if code == "" {
+			code = models.ErrorCodeExecutor
+		}
+		return &models.TaskError{
+			Code:      code,
+			Message:   typed.Error(),
+			Retryable: typed.Retryable,
+			Details:   typed.Details,
+			Stack:     typed.Stack,
+		}
+	}
+	if errors.Is(err, ErrUnknownType) {
+		return &models.TaskError{Code: models.ErrorCodeUnknownType, Message: err.Error()}
+	}
+	return &models.TaskError{Code: models.ErrorCodeExecutor, Message: err.Error(), Retryable: true}
+}
@@ -0,0 +1,35 @@
+package tasktypes
+
+import (
+	"errors"
+	"fmt"
+	"http_api/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	cause := errors.New("bad width")
+
+	assert.Equal(t, &models.TaskError{Code: models.ErrorCodeInvalidInput, Message: "bad width"},
+		Describe(fmt.Errorf("resize: %w", InvalidInput(cause))))
+	assert.Equal(t, &models.TaskError{Code: "rate_limited", Message: "slow down", Retryable: true, Details: map[string]interface{}{"retry_after": 5}},
+		Describe(&Error{Code: "rate_limited", Message: "slow down", Retryable: true, Details: map[string]interface{}{"retry_after": 5}}))
+	assert.Equal(t, &models.TaskError{Code: models.ErrorCodeExecutor, Message: "bad width"},
+		Describe(&Error{Err: cause}))
+	assert.Equal(t, &models.TaskError{Code: models.ErrorCodeUnknownType, Message: `unknown task type "x"`},
+		Describe(fmt.Errorf("%w %q", ErrUnknownType, "x")))
+	assert.Equal(t, &models.TaskError{Code: models.ErrorCodeExecutor, Message: "bad width", Retryable: true},
+		Describe(cause))
+
+	assert.ErrorIs(t, Retryable("upstream", cause), cause)
+
+	panicked := Describe(Panic("boom", []byte("goroutine 1")))
+	assert.Equal(t, &models.TaskError{
+		Code:    models.ErrorCodePanic,
+		Message: "executor panicked: boom",
+		Details: map[string]interface{}{"value": "boom"},
+		Stack:   "goroutine 1",
+	}, panicked)
+}
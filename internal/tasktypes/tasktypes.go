@@ -0,0 +1,173 @@
+// Package tasktypes описывает типы задач: схемы входа и результата
+// и исполнитель, который выполняет задачи этого типа.
+package tasktypes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/internal/schema"
+	"http_api/models"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnknownType = errors.New("unknown task type")
+
+// Executor выполняет задачу и возвращает результат в виде JSON.
+// При отмене задачи ctx отменяется, и исполнитель должен вернуться как можно скорее.
+type Executor interface {
+	Execute(ctx context.Context, task *models.Task) (json.RawMessage, error)
+}
+
+// ExecutorFunc позволяет использовать функцию как Executor
+type ExecutorFunc func(ctx context.Context, task *models.Task) (json.RawMessage, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+	return f(ctx, task)
+}
+
+// TaskType - зарегистрированный тип задачи
+type TaskType struct {
+	Name        string
+	Description string
+	// InputSchema и OutputSchema - JSON Schema входа и результата; nil - без проверки
+	InputSchema  json.RawMessage
+	OutputSchema json.RawMessage
+	// CheckInput дополняет схему проверками, которые ею не выразить; вызывается
+	// для входа, прошедшего схему. nil - без дополнительной проверки
+	CheckInput func(input json.RawMessage) []schema.Error
+	Executor   Executor
+
+	// Timeout ограничивает время одной попытки выполнения (0 - без ограничения)
+	Timeout time.Duration
+	// Retry задает повторы после ошибки исполнителя
+	Retry RetryPolicy
+	// MaxConcurrency ограничивает число одновременно выполняющихся задач типа (0 - без ограничения)
+	MaxConcurrency int
+
+	input, output *schema.Schema
+}
+
+// RetryPolicy - повторы упавших задач с экспоненциальной задержкой
+type RetryPolicy struct {
+	// MaxAttempts - общее число попыток, включая первую; 0 и 1 - без повторов
+	MaxAttempts int
+	// Backoff - задержка перед первым повтором, каждая следующая вдвое больше
+	Backoff time.Duration
+	// MaxBackoff ограничивает задержку (0 - без ограничения)
+	MaxBackoff time.Duration
+}
+
+// Delay возвращает задержку перед попыткой attempt+1 после attempt неудачных
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay > 0 && delay < math.MaxInt64/2; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// ValidateInput проверяет вход задачи по схеме типа
+func (t *TaskType) ValidateInput(input json.RawMessage) []schema.Error {
+	if t.input != nil {
+		if errs := t.input.Validate(input); len(errs) > 0 {
+			return errs
+		}
+	}
+	if t.CheckInput != nil {
+		return t.CheckInput(input)
+	}
+	return nil
+}
+
+// ValidateOutput проверяет результат исполнителя по схеме типа
+func (t *TaskType) ValidateOutput(output json.RawMessage) []schema.Error {
+	if t.output == nil {
+		return nil
+	}
+	return t.output.Validate(output)
+}
+
+// Registry хранит типы задач по имени
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]*TaskType
+}
+
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]*TaskType)}
+}
+
+// Register добавляет тип, предварительно разобрав его схемы
+func (r *Registry) Register(t TaskType) error {
+	if t.Name == "" {
+		return errors.New("task type name is required")
+	}
+	if t.Executor == nil {
+		return fmt.Errorf("task type %q: executor is required", t.Name)
+	}
+	if t.Timeout < 0 || t.MaxConcurrency < 0 || t.Retry.MaxAttempts < 0 || t.Retry.Backoff < 0 || t.Retry.MaxBackoff < 0 {
+		return fmt.Errorf("task type %q: limits must not be negative", t.Name)
+	}
+
+	var err error
+	if t.InputSchema != nil {
+		if t.input, err = schema.Compile(t.InputSchema); err != nil {
+			return fmt.Errorf("task type %q: input %w", t.Name, err)
+		}
+	}
+	if t.OutputSchema != nil {
+		if t.output, err = schema.Compile(t.OutputSchema); err != nil {
+			return fmt.Errorf("task type %q: output %w", t.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.types[t.Name]; exists {
+		return fmt.Errorf("task type %q is already registered", t.Name)
+	}
+	r.types[t.Name] = &t
+	return nil
+}
+
+// Get возвращает тип по имени
+func (r *Registry) Get(name string) (*TaskType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Clone возвращает независимую копию реестра с теми же типами
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clone := &Registry{types: make(map[string]*TaskType, len(r.types))}
+	for name, t := range r.types {
+		clone.types[name] = t
+	}
+	return clone
+}
+
+// List возвращает все типы, упорядоченные по имени
+func (r *Registry) List() []*TaskType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]*TaskType, 0, len(r.types))
+	for _, t := range r.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
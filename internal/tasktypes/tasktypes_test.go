@@ -0,0 +1,49 @@
+package tasktypes
+
+import (
+	"context"
+	"encoding/json"
+	"http_api/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var noop = ExecutorFunc(func(ctx context.Context, task *models.Task) (json.RawMessage, error) {
+	return nil, nil
+})
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	require.NoError(t, r.Register(TaskType{Name: "b", Executor: noop}))
+	require.NoError(t, r.Register(TaskType{Name: "a", Executor: noop, InputSchema: json.RawMessage(`{"type":"object"}`)}))
+
+	assert.Error(t, r.Register(TaskType{Name: "a", Executor: noop}), "duplicate name")
+	assert.Error(t, r.Register(TaskType{Name: "c"}), "missing executor")
+	assert.Error(t, r.Register(TaskType{Name: "c", Executor: noop, OutputSchema: json.RawMessage(`{"type":"text"}`)}), "bad schema")
+	assert.Error(t, r.Register(TaskType{Name: "c", Executor: noop, Timeout: -time.Second}), "negative timeout")
+
+	types := r.List()
+	require.Len(t, types, 2)
+	assert.Equal(t, "a", types[0].Name)
+	assert.Equal(t, "b", types[1].Name)
+
+	a, ok := r.Get("a")
+	require.True(t, ok)
+	assert.Len(t, a.ValidateInput(json.RawMessage(`[]`)), 1)
+	assert.Empty(t, a.ValidateOutput(json.RawMessage(`[]`)))
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, p.Delay(1))
+	assert.Equal(t, 2*time.Second, p.Delay(2))
+	assert.Equal(t, 4*time.Second, p.Delay(3))
+	assert.Equal(t, 5*time.Second, p.Delay(4))
+	assert.Equal(t, 5*time.Second, p.Delay(40))
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.Delay(3))
+}
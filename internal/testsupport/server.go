@@ -0,0 +1,29 @@
+// Package testsupport содержит общие заготовки для тестов клиентов API.
+package testsupport
+
+import (
+	"http_api/internal/handlers"
+	"http_api/internal/services"
+	"http_api/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NewServer запускает API задач поверх хранилища в памяти;
+// сервер останавливается по завершении теста
+func NewServer(t *testing.T, opts ...services.Option) *httptest.Server {
+	t.Helper()
+	taskService := services.NewTaskService(storage.NewInMemoryTaskStorage(), opts...)
+	taskHandler := handlers.NewTaskHandler(taskService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", taskHandler.HandleTasks)
+	mux.HandleFunc("/tasks/", taskHandler.HandleTaskByID)
+	mux.HandleFunc("/task-types", taskHandler.HandleTaskTypes)
+	mux.HandleFunc("/task-types/", taskHandler.HandleTaskTypes)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
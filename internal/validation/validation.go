@@ -0,0 +1,359 @@
+// Package validation проверяет запросы на создание и изменение задач
+// и собирает все ошибки сразу, указывая поля через JSON Pointer (RFC 6901).
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"http_api/internal/labels"
+	"http_api/models"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldError описывает ошибку в поле тела запроса (Pointer)
+// или в параметре строки запроса (Parameter)
+type FieldError struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (e FieldError) location() string {
+	if e.Parameter != "" {
+		return "?" + e.Parameter
+	}
+	if e.Pointer == "" {
+		return "/"
+	}
+	return e.Pointer
+}
+
+// Error перечисляет все ошибочные поля запроса
+type Error struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.location() + ": " + fe.Message
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// MarshalJSON добавляет к списку ошибок общее сообщение
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message string       `json:"message"`
+		Errors  []FieldError `json:"errors"`
+	}{e.Error(), e.Errors})
+}
+
+// Errors накапливает ошибки полей
+type Errors []FieldError
+
+func (errs *Errors) Add(pointer, format string, args ...interface{}) {
+	*errs = append(*errs, FieldError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err возвращает *Error, если ошибки есть, иначе nil
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sorted := append([]FieldError(nil), errs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].location() < sorted[j].location() })
+	return &Error{Errors: sorted}
+}
+
+// Pointer строит JSON Pointer из токенов, экранируя "~" и "/"
+func Pointer(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// Rules - настраиваемые правила проверки запросов
+type Rules struct {
+	RequireDescription   bool
+	MaxDescriptionLength int
+	// DescriptionPattern, если задан, проверяется через MatchString:
+	// для соответствия всему описанию выражение должно быть с ^ и $
+	DescriptionPattern *regexp.Regexp
+	MaxTypeLength      int
+	MaxLabels          int
+	MaxMetadataBytes   int
+	// MaxBodyBytes ограничивает размер тела запроса
+	MaxBodyBytes int64
+	// DisallowUnknownFields отклоняет поля, которых нет в схеме запроса
+	DisallowUnknownFields bool
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		RequireDescription:    true,
+		MaxDescriptionLength:  1024,
+		MaxTypeLength:         64,
+		MaxLabels:             64,
+		MaxMetadataBytes:      16 << 10,
+		MaxBodyBytes:          1 << 20,
+		DisallowUnknownFields: true,
+	}
+}
+
+// MaxTTLSeconds - наибольший срок хранения задачи, 10 лет
+const MaxTTLSeconds = 10 * 365 * 24 * 60 * 60
+
+var typePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+
+// Create проверяет запрос на создание задачи
+func (r Rules) Create(req models.TaskCreate) error {
+	var errs Errors
+	if req.Type != "" {
+		if len(req.Type) > r.MaxTypeLength && r.MaxTypeLength > 0 {
+			errs.Add("/type", "must be at most %d characters", r.MaxTypeLength)
+		} else if !typePattern.MatchString(req.Type) {
+			errs.Add("/type", "must consist of lower case letters, digits, '.', '_' and '-'")
+		}
+	}
+	if req.TTLSeconds < 0 {
+		errs.Add("/ttl_seconds", "must not be negative")
+	} else if req.TTLSeconds > MaxTTLSeconds {
+		errs.Add("/ttl_seconds", "must be at most %d", MaxTTLSeconds)
+	}
+	r.checkMutable(&errs, req.Description, req.Labels, req.Metadata)
+	return errs.Err()
+}
+
+// Update проверяет изменяемые поля задачи после PUT или PATCH
+func (r Rules) Update(update models.TaskUpdate) error {
+	var errs Errors
+	var description string
+	if update.Description != nil {
+		description = *update.Description
+	}
+	r.checkMutable(&errs, description, update.Labels, update.Metadata)
+	return errs.Err()
+}
+
+func (r Rules) checkMutable(errs *Errors, description string, set map[string]string, metadata map[string]interface{}) {
+	switch {
+	case strings.TrimSpace(description) == "":
+		if r.RequireDescription {
+			errs.Add("/description", "is required")
+		}
+	case r.MaxDescriptionLength > 0 && utf8.RuneCountInString(description) > r.MaxDescriptionLength:
+		errs.Add("/description", "must be at most %d characters", r.MaxDescriptionLength)
+	case !utf8.ValidString(description) || strings.IndexFunc(description, isForbidden) >= 0:
+		errs.Add("/description", "must not contain control characters")
+	case r.DescriptionPattern != nil && !r.DescriptionPattern.MatchString(description):
+		errs.Add("/description", "must match pattern %s", r.DescriptionPattern)
+	}
+
+	if r.MaxLabels > 0 && len(set) > r.MaxLabels {
+		errs.Add("/labels", "must have at most %d labels", r.MaxLabels)
+	}
+	for key, value := range set {
+		if err := labels.ValidateKey(key); err != nil {
+			errs.Add(Pointer("labels", key), "%v", err)
+		} else if err := labels.ValidateValue(value); err != nil {
+			errs.Add(Pointer("labels", key), "%v", err)
+		}
+	}
+
+	if r.MaxMetadataBytes > 0 && metadata != nil {
+		if data, err := json.Marshal(metadata); err != nil || len(data) > r.MaxMetadataBytes {
+			errs.Add("/metadata", "must be at most %d bytes of JSON", r.MaxMetadataBytes)
+		}
+	}
+}
+
+// isForbidden запрещает управляющие символы, кроме перевода строки и табуляции
+func isForbidden(c rune) bool {
+	return unicode.IsControl(c) && c != '\n' && c != '\t' && c != '\r'
+}
+
+// ErrBodyTooLarge возвращается при чтении тела больше MaxBodyBytes
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// mutableFields - поля задачи, которые клиент может менять через PUT и PATCH
+var mutableFields = jsonFields(reflect.TypeOf(models.TaskUpdate{}))
+
+// taskFields - все поля представления задачи
+var taskFields = jsonFields(reflect.TypeOf(models.Task{}))
+
+// DecodeCreate читает запрос на создание задачи
+func (r Rules) DecodeCreate(body io.Reader, req *models.TaskCreate) error {
+	raw, err := r.readObject(body)
+	if err != nil {
+		return err
+	}
+	return r.decodeFields(raw, req, func(string) string {
+		if r.DisallowUnknownFields {
+			return "unknown field"
+		}
+		return ""
+	})
+}
+
+// DecodeUpdate читает документ PUT с полной заменой изменяемых полей
+func (r Rules) DecodeUpdate(body io.Reader, update *models.TaskUpdate) error {
+	raw, err := r.readObject(body)
+	if err != nil {
+		return err
+	}
+	return r.decodeFields(raw, update, r.updateField)
+}
+
+// DecodePatch читает JSON Merge Patch. Значения null сохраняются: они удаляют поля.
+func (r Rules) DecodePatch(body io.Reader) (map[string]interface{}, error) {
+	raw, err := r.readObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверка типов на пустой структуре: null для нее ничего не меняет
+	var probe models.TaskUpdate
+	if err := r.decodeFields(raw, &probe, r.updateField); err != nil {
+		return nil, err
+	}
+	patch := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		var v interface{}
+		json.Unmarshal(value, &v)
+		patch[name] = v
+	}
+	return patch, nil
+}
+
+// CheckPatch проверяет имена полей уже разобранного документа PATCH
+func (r Rules) CheckPatch(patch map[string]interface{}) error {
+	var errs Errors
+	for name := range patch {
+		if _, ok := mutableFields[name]; ok {
+			continue
+		}
+		if message := r.updateField(name); message != "" {
+			errs.Add(Pointer(name), "%s", message)
+		}
+	}
+	return errs.Err()
+}
+
+// updateField объясняет, почему поле нельзя передавать в PUT или PATCH
+func (r Rules) updateField(name string) string {
+	if _, ok := taskFields[name]; ok {
+		return "field is immutable"
+	}
+	if r.DisallowUnknownFields {
+		return "unknown field"
+	}
+	return ""
+}
+
+func (r Rules) readObject(body io.Reader) (map[string]json.RawMessage, error) {
+	if r.MaxBodyBytes > 0 {
+		body = io.LimitReader(body, r.MaxBodyBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if r.MaxBodyBytes > 0 && int64(len(data)) > r.MaxBodyBytes {
+		return nil, ErrBodyTooLarge
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		message := "body must be a JSON object"
+		if err != nil {
+			message += ": " + syntaxMessage(err)
+		}
+		return nil, &Error{Errors: []FieldError{{Message: message}}}
+	}
+	return raw, nil
+}
+
+// decodeFields раскладывает поля объекта по полям структуры v. Для полей,
+// которых нет в структуре, unknown возвращает текст ошибки или "", если поле можно пропустить.
+func (r Rules) decodeFields(raw map[string]json.RawMessage, v interface{}, unknown func(name string) string) error {
+	var errs Errors
+	fields := jsonFields(reflect.TypeOf(v).Elem())
+	target := reflect.ValueOf(v).Elem()
+	for name, value := range raw {
+		index, known := fields[name]
+		if !known {
+			if message := unknown(name); message != "" {
+				errs.Add(Pointer(name), "%s", message)
+			}
+			continue
+		}
+		if err := json.Unmarshal(value, target.Field(index).Addr().Interface()); err != nil {
+			pointer := Pointer(name)
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				pointer += Pointer(strings.Split(typeErr.Field, ".")...)
+			}
+			errs.Add(pointer, "%s", typeMessage(err))
+		}
+	}
+	return errs.Err()
+}
+
+// jsonFields сопоставляет json-имена полей структуры с их индексами
+func jsonFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+func typeMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("must be %s, got %s", jsonType(typeErr.Type), typeErr.Value)
+	}
+	return "invalid value"
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "an integer"
+	case reflect.Float64, reflect.Float32:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Slice:
+		return "an array"
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	}
+	return t.String()
+}
+
+func syntaxMessage(err error) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("%v at offset %d", syntaxErr, syntaxErr.Offset)
+	}
+	return err.Error()
+}
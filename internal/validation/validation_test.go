@@ -0,0 +1,109 @@
+package validation
+
+import (
+	"errors"
+	"http_api/models"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesCreate(t *testing.T) {
+	rules := DefaultRules()
+
+	assert.NoError(t, rules.Create(models.TaskCreate{Description: "valid\ndescription", Type: "report.v2"}))
+
+	err := rules.Create(models.TaskCreate{
+		Type:       "Bad Type",
+		Labels:     map[string]string{"example.com/owner": "bad value"},
+		Metadata:   map[string]interface{}{"blob": strings.Repeat("x", rules.MaxMetadataBytes)},
+		TTLSeconds: -1,
+	})
+	var validationErr *Error
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []string{"/description", "/labels/example.com~1owner", "/metadata", "/ttl_seconds", "/type"}, pointers(validationErr))
+
+	assert.NoError(t, rules.Create(models.TaskCreate{Description: "kept", TTLSeconds: MaxTTLSeconds}))
+	assert.Error(t, rules.Create(models.TaskCreate{Description: "overflow", TTLSeconds: MaxTTLSeconds + 1}))
+
+	for _, description := range []string{strings.Repeat("я", rules.MaxDescriptionLength+1), "bell\a", "  "} {
+		assert.Error(t, rules.Create(models.TaskCreate{Description: description}), description)
+	}
+
+	rules.RequireDescription = false
+	rules.DescriptionPattern = regexp.MustCompile(`^[a-z ]*$`)
+	assert.NoError(t, rules.Create(models.TaskCreate{}))
+	assert.NoError(t, rules.Create(models.TaskCreate{Description: "lower case"}))
+	assert.Error(t, rules.Create(models.TaskCreate{Description: "Upper"}))
+}
+
+func TestRulesDecode(t *testing.T) {
+	rules := DefaultRules()
+
+	t.Run("All unknown fields and type errors at once", func(t *testing.T) {
+		var req models.TaskCreate
+		err := rules.DecodeCreate(strings.NewReader(`{"description":1,"colour":"red","priority":"high","labels":{"env":5},"extra":null}`), &req)
+		var validationErr *Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"/colour", "/description", "/extra", "/labels/env", "/priority"}, pointers(validationErr))
+	})
+
+	t.Run("Unknown fields can be allowed", func(t *testing.T) {
+		lenient := rules
+		lenient.DisallowUnknownFields = false
+		var req models.TaskCreate
+		require.NoError(t, lenient.DecodeCreate(strings.NewReader(`{"description":"ok","colour":"red"}`), &req))
+		assert.Equal(t, "ok", req.Description)
+	})
+
+	t.Run("Body size limit", func(t *testing.T) {
+		small := rules
+		small.MaxBodyBytes = 16
+		var req models.TaskCreate
+		err := small.DecodeCreate(strings.NewReader(`{"description":"too long for the limit"}`), &req)
+		assert.ErrorIs(t, err, ErrBodyTooLarge)
+	})
+
+	t.Run("Not an object", func(t *testing.T) {
+		var req models.TaskCreate
+		for _, body := range []string{`[]`, `null`, `{"description":`} {
+			var validationErr *Error
+			assert.True(t, errors.As(rules.DecodeCreate(strings.NewReader(body), &req), &validationErr), body)
+		}
+	})
+
+	t.Run("Update rejects immutable fields", func(t *testing.T) {
+		var update models.TaskUpdate
+		err := rules.DecodeUpdate(strings.NewReader(`{"description":"x","status":"completed","colour":1}`), &update)
+		var validationErr *Error
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []FieldError{
+			{Pointer: "/colour", Message: "unknown field"},
+			{Pointer: "/status", Message: "field is immutable"},
+		}, validationErr.Errors)
+	})
+
+	t.Run("Patch keeps nulls", func(t *testing.T) {
+		patch, err := rules.DecodePatch(strings.NewReader(`{"labels":{"env":null},"metadata":null}`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"labels": map[string]interface{}{"env": nil}, "metadata": nil}, patch)
+
+		_, err = rules.DecodePatch(strings.NewReader(`{"priority":"high"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestPointer(t *testing.T) {
+	assert.Equal(t, "/labels/a~1b~0c", Pointer("labels", "a/b~c"))
+}
+
+func pointers(err *Error) []string {
+	var result []string
+	for _, fe := range err.Errors {
+		result = append(result, fe.Pointer)
+	}
+	return result
+}
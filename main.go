@@ -1,28 +1,434 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"http_api/internal/artifacts"
+	"http_api/internal/auth"
+	"http_api/internal/config"
+	"http_api/internal/executors/command"
+	"http_api/internal/executors/httpcall"
 	"http_api/internal/handlers"
+	"http_api/internal/health"
+	"http_api/internal/logging"
+	"http_api/internal/metrics"
+	"http_api/internal/middleware"
 	"http_api/internal/services"
 	"http_api/internal/storage"
+	"http_api/internal/tasklogs"
+	"http_api/internal/tasktypes"
+	"http_api/internal/validation"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
 )
 
 func main() {
-	// Инициализация хранилища в памяти
-	taskStorage := storage.NewInMemoryTaskStorage()
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logger)
+	logger.Info("effective configuration", "config", cfg.String())
+
+	// Инициализация хранилища
+	taskStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		fatal(logger, "failed to initialize storage", err)
+	}
+
+	taskLogs, err := newTaskLogs(cfg.TaskLogs)
+	if err != nil {
+		fatal(logger, "failed to initialize task logs", err)
+	}
+
+	artifactStore, err := newArtifacts(cfg.Artifacts)
+	if err != nil {
+		fatal(logger, "failed to initialize artifact store", err)
+	}
+
+	retention, archive, err := newRetention(cfg.Retention)
+	if err != nil {
+		fatal(logger, "failed to open retention archive", err)
+	}
+
+	registry := metrics.NewRegistry()
+
+	taskTypes, err := newTaskTypes(cfg.Executors)
+	if err != nil {
+		fatal(logger, "failed to configure task types", err)
+	}
 
 	// Сервис для работы с задачами
-	taskService := services.NewTaskService(taskStorage)
+	taskService := services.NewTaskService(taskStorage,
+		services.WithMetrics(registry),
+		services.WithLogger(logger),
+		services.WithWorkers(cfg.Workers.Count),
+		services.WithQueueLimit(cfg.Queue.MaxPending),
+		services.WithProcessingTime(cfg.Tasks.MinDuration, cfg.Tasks.MaxDuration),
+		services.WithStatsWindow(cfg.Tasks.StatsWindow),
+		services.WithQuotas(newQuotas(cfg.Quotas)),
+		services.WithValidation(newValidationRules(cfg.Validation)),
+		services.WithTaskTypes(taskTypes),
+		services.WithTaskLogs(taskLogs),
+		services.WithArtifacts(artifactStore),
+		services.WithRetention(retention),
+	)
+	recovered, err := taskService.RecoverTasks(context.Background())
+	if err != nil {
+		fatal(logger, "failed to recover tasks", err)
+	}
+	if recovered > 0 {
+		logger.Info("recovered unfinished tasks", "count", recovered)
+	}
 
 	// HTTP обработчики
-	taskHandler := handlers.NewTaskHandler(taskService)
+	handlerOpts := []handlers.Option{
+		handlers.WithPageSize(cfg.API.DefaultPageSize, cfg.API.MaxPageSize),
+	}
+	// Служебные endpoint'ы остаются открытыми, ключ нужен только для /tasks
+	var authenticator auth.Authenticator
+	if cfg.Auth.Enabled {
+		var err error
+		if authenticator, err = newAuthenticator(cfg.Auth); err != nil {
+			fatal(logger, "failed to configure authentication", err)
+		}
+		handlerOpts = append(handlerOpts, handlers.RequireScopes())
+	}
+	var limiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled {
+		limiter = newRateLimiter(cfg.RateLimit)
+	}
+	protect := newProtect(authenticator, limiter)
+	taskHandler := handlers.NewTaskHandler(taskService, handlerOpts...)
 
 	// Настройка маршрутов
-	http.HandleFunc("/tasks", taskHandler.HandleTasks)
-	http.HandleFunc("/tasks/", taskHandler.HandleTaskByID)
+	mux := http.NewServeMux()
+	mux.Handle("/tasks", protect(http.HandlerFunc(taskHandler.HandleTasks)))
+	mux.Handle("/tasks/", protect(http.HandlerFunc(taskHandler.HandleTaskByID)))
+	mux.Handle("/quotas", protect(http.HandlerFunc(taskHandler.HandleQuotas)))
+	mux.Handle("/task-types", protect(http.HandlerFunc(taskHandler.HandleTaskTypes)))
+	mux.Handle("/task-types/", protect(http.HandlerFunc(taskHandler.HandleTaskTypes)))
+	mux.Handle("/metrics", registry.Handler())
+
+	checks := health.New()
+	if checker, ok := taskStorage.(health.Checker); ok {
+		checks.Register("storage", true, checker)
+	}
+	checks.Register("workers", true, health.CheckerFunc(taskService.CheckWorkers))
+	checks.Register("scheduler", true, health.CheckerFunc(taskService.CheckScheduler))
+	checks.Register("draining", true, health.CheckerFunc(taskService.CheckDraining))
+	mux.Handle("/healthz", checks.LivenessHandler())
+	mux.Handle("/readyz", checks.ReadinessHandler())
+
+	server := &http.Server{
+		Addr: cfg.Server.Addr,
+		Handler: middleware.RequestID(
+			middleware.AccessLog(logger, routeName)(
+				middleware.Metrics(registry, routeName)(mux),
+			),
+		),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 
 	// Запуск сервера
-	log.Println("Server starting on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting", "addr", cfg.Server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serverErr:
+		fatal(logger, "server failed", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdown(server, taskService, taskStorage, cfg)
+	if err := taskLogs.Close(); err != nil {
+		logger.Error("failed to close task logs", "error", err)
+	}
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			logger.Error("failed to close retention archive", "error", err)
+		}
+	}
+	logger.Info("shutdown complete")
+}
+
+// shutdown перестает принимать задачи, дожидается HTTP-запросов и выполняющихся
+// задач, после чего сохраняет состояние хранилища
+func shutdown(server *http.Server, taskService *services.TaskService, taskStorage storage.TaskStorage, cfg *config.Config) {
+	// Новые задачи отклоняются с 503, пока сервер завершает текущие запросы
+	taskService.StopAccepting()
+
+	httpCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(httpCtx); err != nil {
+		slog.Warn("http server shutdown", "error", err)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Workers.DrainTimeout)
+	defer cancel()
+	if err := taskService.Shutdown(drainCtx); err != nil {
+		slog.Warn("drain timeout exceeded, unfinished tasks will be resumed on next start")
+	}
+
+	if fileStorage, ok := taskStorage.(*storage.FileTaskStorage); ok {
+		if err := fileStorage.Close(); err != nil {
+			slog.Error("failed to persist tasks", "error", err)
+		}
+	}
+}
+
+// routeName возвращает шаблон маршрута для меток метрик
+func routeName(r *http.Request) string {
+	if name := handlers.RouteName(r.URL.Path); name != "" {
+		return name
+	}
+	switch r.URL.Path {
+	case "/metrics", "/healthz", "/readyz":
+		return r.URL.Path
+	}
+	return "other"
+}
+
+func newStorage(cfg config.StorageConfig) (storage.TaskStorage, error) {
+	if cfg.Backend == "file" {
+		return storage.NewFileTaskStorage(cfg.Path, cfg.FlushInterval)
+	}
+	return storage.NewInMemoryTaskStorage(), nil
+}
+
+func newTaskLogs(cfg config.TaskLogsConfig) (*tasklogs.Store, error) {
+	if cfg.Dir == "" {
+		return tasklogs.NewStore(cfg.MaxLines), nil
+	}
+	return tasklogs.OpenStore(cfg.Dir, cfg.MaxLines)
+}
+
+// newRetention строит политику хранения; архив возвращается, чтобы закрыть его при остановке
+func newRetention(cfg config.RetentionConfig) (services.Retention, *storage.FileArchive, error) {
+	retention := services.Retention{
+		Completed: cfg.Completed,
+		Failed:    cfg.Failed,
+		Cancelled: cfg.Cancelled,
+		MaxTasks:  cfg.MaxTasks,
+		Interval:  cfg.Interval,
+	}
+	if cfg.Archive == "" {
+		return retention, nil, nil
+	}
+	archive, err := storage.NewFileArchive(cfg.Archive)
+	if err != nil {
+		return retention, nil, err
+	}
+	retention.Archive = archive
+	return retention, archive, nil
+}
+
+// newArtifacts возвращает nil, если каталог артефактов не задан
+func newArtifacts(cfg config.ArtifactsConfig) (artifacts.Store, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	return artifacts.NewFSStore(cfg.Dir, artifacts.WithMaxBytes(cfg.MaxBytes))
+}
+
+func newRateLimiter(cfg config.RateLimitConfig) *middleware.RateLimiter {
+	rule := func(r config.RateLimitRule) middleware.Rule {
+		return middleware.Rule{PerMinute: r.PerMinute, Burst: r.Burst}
+	}
+	return middleware.NewRateLimiter(map[string]middleware.Rule{
+		handlers.ClassRead:   rule(cfg.Read),
+		handlers.ClassCreate: rule(cfg.Create),
+		handlers.ClassWrite:  rule(cfg.Write),
+		handlers.ClassDelete: rule(cfg.Delete),
+	}, cfg.MaxClients)
+}
+
+// newProtect собирает аутентификацию и ограничение частоты для API задач;
+// nil отключает соответствующую часть
+func newProtect(authenticator auth.Authenticator, limiter *middleware.RateLimiter) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		if authenticator != nil {
+			h = auth.Require(h)
+		}
+		if limiter != nil {
+			// Лимит действует до отказа в аутентификации, так что отклоненные
+			// запросы тоже учитываются, но по адресу - только без субъекта
+			h = middleware.RateLimit(limiter, handlers.RouteClass, clientKey)(h)
+		}
+		if authenticator != nil {
+			h = auth.Identify(authenticator)(h)
+		}
+		return h
+	}
+}
+
+// clientKey - ключ ограничения частоты: субъект, а без него адрес клиента
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return "principal:" + principal.ID
+	}
+	return "ip:" + middleware.ClientIP(r)
+}
+
+func newValidationRules(cfg config.ValidationConfig) validation.Rules {
+	rules := validation.DefaultRules()
+	rules.RequireDescription = cfg.RequireDescription
+	rules.MaxDescriptionLength = cfg.MaxDescriptionLength
+	rules.MaxLabels = cfg.MaxLabels
+	rules.MaxMetadataBytes = cfg.MaxMetadataBytes
+	rules.MaxBodyBytes = int64(cfg.MaxBodyBytes)
+	rules.DisallowUnknownFields = cfg.DisallowUnknownFields
+	if cfg.DescriptionPattern != "" {
+		// Выражение уже проверено в config.Validate
+		rules.DescriptionPattern = regexp.MustCompile("^(?:" + cfg.DescriptionPattern + ")$")
+	}
+	return rules
+}
+
+func newQuotas(cfg config.QuotasConfig) services.Quotas {
+	convert := func(q config.QuotaConfig) services.Quota {
+		return services.Quota{MaxPending: q.MaxPending, MaxProcessing: q.MaxProcessing, CreatePerMinute: q.CreatePerMinute}
+	}
+
+	quotas := services.Quotas{Default: convert(cfg.Default), Namespaces: make(map[string]services.Quota, len(cfg.Namespaces))}
+	for namespace, q := range cfg.Namespaces {
+		quotas.Namespaces[namespace] = convert(q)
+	}
+	return quotas
+}
+
+// newTaskTypes регистрирует встроенные типы задач, включенные в конфигурации
+func newTaskTypes(cfg config.ExecutorsConfig) (*tasktypes.Registry, error) {
+	registry := tasktypes.NewRegistry()
+	if cfg.HTTP.Enabled {
+		executor, err := httpcall.New(httpcall.Config{
+			AllowedHosts:     cfg.HTTP.AllowedHosts,
+			Headers:          hostHeaders(cfg.HTTP.Headers),
+			RequestTimeout:   cfg.HTTP.RequestTimeout,
+			MaxResponseBytes: cfg.HTTP.MaxResponseBytes,
+			Retry:            retryPolicy(cfg.HTTP.Retry),
+			PollInterval:     cfg.HTTP.PollInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		taskType := executor.TaskType()
+		taskType.Timeout = cfg.HTTP.Timeout
+		taskType.MaxConcurrency = cfg.HTTP.MaxConcurrency
+		if err := registry.Register(taskType); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Exec.Commands) == 0 {
+		return registry, nil
+	}
+
+	commands := make(map[string]command.Command, len(cfg.Exec.Commands))
+	for name, c := range cfg.Exec.Commands {
+		commands[name] = command.Command{
+			Path:               c.Path,
+			Args:               c.Args,
+			WorkDir:            c.WorkDir,
+			Env:                c.Env,
+			AllowEnv:           c.AllowEnv,
+			Timeout:            c.Timeout,
+			MaxOutputBytes:     c.MaxOutputBytes,
+			RetryableExitCodes: c.RetryableExitCodes,
+		}
+	}
+	executor, err := command.New(commands, command.WithKillGrace(cfg.Exec.KillGrace))
+	if err != nil {
+		return nil, err
+	}
+	taskType := executor.TaskType()
+	taskType.MaxConcurrency = cfg.Exec.MaxConcurrency
+	taskType.Retry = retryPolicy(cfg.Exec.Retry)
+	return registry, registry.Register(taskType)
+}
+
+func retryPolicy(cfg config.RetryConfig) tasktypes.RetryPolicy {
+	return tasktypes.RetryPolicy{MaxAttempts: cfg.MaxAttempts, Backoff: cfg.Backoff, MaxBackoff: cfg.MaxBackoff}
+}
+
+// hostHeaders раскрывает секретные заголовки для исполнителя http
+func hostHeaders(cfg map[string]map[string]config.Secret) map[string]map[string]string {
+	headers := make(map[string]map[string]string, len(cfg))
+	for host, values := range cfg {
+		headers[host] = make(map[string]string, len(values))
+		for name, value := range values {
+			headers[host][name] = string(value)
+		}
+	}
+	return headers
+}
+
+// newAuthenticator объединяет API-ключи из конфигурации и файла ключей
+// и проверку JWT, если она настроена
+func newAuthenticator(cfg config.AuthConfig) (auth.Authenticator, error) {
+	keys := make([]auth.Key, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		keys = append(keys, auth.Key{Name: k.Name, Hash: string(k.Hash), Scopes: k.Scopes, Namespace: k.Namespace})
+	}
+	if cfg.KeyFile != "" {
+		fileKeys, err := auth.ReadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	keyStore, err := auth.NewKeyStore(keys)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.JWT.Enabled() {
+		return keyStore, nil
+	}
+
+	keySet := &auth.KeySet{}
+	if cfg.JWT.JWKSFile != "" {
+		if keySet, err = auth.ReadJWKS(cfg.JWT.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.JWT.HMACSecret != "" {
+		keySet.HMACKey("", []byte(cfg.JWT.HMACSecret))
+	}
+	if keySet.Len() == 0 {
+		return nil, errors.New("no JWT verification keys configured")
+	}
+	verifier := auth.NewJWTVerifier(keySet,
+		auth.WithIssuer(cfg.JWT.Issuer),
+		auth.WithAudience(cfg.JWT.Audience),
+		auth.WithLeeway(cfg.JWT.Leeway),
+		auth.WithNamespaceClaim(cfg.JWT.NamespaceClaim),
+	)
+	return auth.Chain{keyStore, verifier}, nil
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
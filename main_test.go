@@ -3,15 +3,18 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"http_api/internal/auth"
 	"http_api/internal/handlers"
-	"http_api/internal/models"
+	"http_api/internal/middleware"
 	"http_api/internal/services"
 	"http_api/internal/storage"
+	"http_api/models"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServerRoutes(t *testing.T) {
@@ -140,7 +143,7 @@ func TestServerRoutes(t *testing.T) {
 			{"Несуществующая задача", "GET", "/tasks/nonexistent", "", http.StatusNotFound},
 			{"Отмена несуществующей задачи", "POST", "/tasks/nonexistent/cancel", "", http.StatusNotFound},
 			{"Удаление несуществующей задачи", "DELETE", "/tasks/nonexistent", "", http.StatusNotFound},
-			{"Неподдерживаемый метод", "PATCH", "/tasks/123", "", http.StatusMethodNotAllowed},
+			{"Неподдерживаемый метод", "OPTIONS", "/tasks/123", "", http.StatusMethodNotAllowed},
 		}
 
 		for _, tt := range tests {
@@ -172,3 +175,33 @@ func TestServerRoutes(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, w.Code)
 	})
 }
+
+func TestProtectRateLimit(t *testing.T) {
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{Name: "alice", Hash: auth.HashKey("alice-secret"), Scopes: []string{"tasks:read"}},
+		{Name: "bob", Hash: auth.HashKey("bob-secret"), Scopes: []string{"tasks:read"}},
+	})
+	require.NoError(t, err)
+	limiter := middleware.NewRateLimiter(map[string]middleware.Rule{handlers.ClassRead: {PerMinute: 1, Burst: 1}}, 10)
+	handler := newProtect(keys, limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Все запросы приходят с одного адреса, как из-за NAT или балансировщика
+	get := func(token string) int {
+		req := httptest.NewRequest("GET", "/tasks", nil)
+		req.RemoteAddr = "10.0.0.1:5555"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("alice-secret"))
+	assert.Equal(t, http.StatusOK, get("bob-secret"), "keys behind one address share a bucket")
+	assert.Equal(t, http.StatusTooManyRequests, get("alice-secret"))
+
+	// Запросы без субъекта ограничиваются по адресу, в том числе неудачные
+	assert.Equal(t, http.StatusUnauthorized, get("wrong"))
+	assert.Equal(t, http.StatusTooManyRequests, get(""))
+}
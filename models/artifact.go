@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Artifact - файл, сохраненный исполнителем задачи вне записи задачи
+type Artifact struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	// Checksum - SHA-256 содержимого в виде "sha256:<hex>"
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArtifactList - ответ GET /tasks/{id}/artifacts
+type ArtifactList struct {
+	Artifacts []Artifact `json:"artifacts"`
+	Total     int        `json:"total"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// Usage - текущее значение и лимит (0 - без ограничения)
+type Usage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+// QuotaUsage - загрузка пространства имен относительно его квот
+type QuotaUsage struct {
+	Namespace         string `json:"namespace"`
+	Pending           Usage  `json:"pending"`
+	Processing        Usage  `json:"processing"`
+	CreatedLastMinute Usage  `json:"created_last_minute"`
+}
+
+type QuotaList struct {
+	Quotas []QuotaUsage `json:"quotas"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TaskStatus string
+
+const (
+	StatusPending    TaskStatus = "pending"
+	StatusProcessing TaskStatus = "processing"
+	StatusCompleted  TaskStatus = "completed"
+	StatusFailed     TaskStatus = "failed"
+	StatusCancelled  TaskStatus = "cancelled"
+)
+
+// IsTerminal сообщает, что задача в этом статусе больше не изменится
+func (s TaskStatus) IsTerminal() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCancelled
+}
+
+// DefaultTaskType используется, если тип задачи не указан при создании
+const DefaultTaskType = "default"
+
+// DefaultNamespace используется, когда пространство имен не указано.
+// К нему же относятся задачи, созданные до появления пространств имен.
+const DefaultNamespace = "default"
+
+// NamespaceOf возвращает пространство имен задачи с учетом значения по умолчанию
+func NamespaceOf(task *Task) string {
+	if task.Namespace == "" {
+		return DefaultNamespace
+	}
+	return task.Namespace
+}
+
+type Task struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Status      TaskStatus `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	Duration    float64    `json:"duration_seconds,omitempty"`
+	// Attempts - число начатых попыток выполнения с учетом повторов
+	Attempts int `json:"attempts,omitempty"`
+	// Input - входные данные, проверенные по схеме типа задачи
+	Input json.RawMessage `json:"input,omitempty"`
+	// Result - результат исполнителя, проверенный по схеме типа задачи
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error - текст ошибки, сохраненный для совместимости; подробности в ErrorInfo
+	Error       string     `json:"error,omitempty"`
+	ErrorInfo   *TaskError `json:"error_info,omitempty"`
+	Description string     `json:"description,omitempty"`
+	// Priority - задачи с большим приоритетом выбираются из очереди пространства имен раньше
+	Priority  int                    `json:"priority,omitempty"`
+	Labels    map[string]string      `json:"labels,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+	// Version увеличивается при каждом изменении задачи и передается в ETag
+	Version int64 `json:"version"`
+	// CreatedBy - идентификатор клиента, создавшего задачу (при включенной аутентификации)
+	CreatedBy string `json:"created_by,omitempty"`
+	// TTLSeconds - сколько хранить задачу после завершения вместо срока
+	// политики хранения для ее статуса (0 - по политике)
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+type TaskCreate struct {
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description"`
+	Input       json.RawMessage        `json:"input,omitempty"`
+	Priority    int                    `json:"priority,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	TTLSeconds  int                    `json:"ttl_seconds,omitempty"`
+}
+
+// TaskUpdate - изменяемые поля задачи. PUT заменяет их целиком:
+// не переданные поля сбрасываются.
+type TaskUpdate struct {
+	// Description - указатель, как и до появления PATCH, чтобы не ломать
+	// существующих клиентов; nil при PUT сбрасывает описание
+	Description *string                `json:"description,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Priority    int                    `json:"priority"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type TaskList struct {
+	Tasks []Task `json:"tasks"`
+	Total int    `json:"total"`
+}
+
+// BulkResult - итог массовой операции над задачами, выбранными селектором меток
+type BulkResult struct {
+	// Matched - число задач, подошедших под селектор
+	Matched int `json:"matched"`
+	// TaskIDs - задачи, к которым операция действительно применена
+	TaskIDs []string `json:"task_ids"`
+}
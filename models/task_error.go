@@ -0,0 +1,28 @@
+package models
+
+// Коды ошибок задач
+const (
+	// ErrorCodeExecutor - исполнитель вернул ошибку без кода
+	ErrorCodeExecutor = "executor_error"
+	// ErrorCodeTimeout - попытка не уложилась в ограничение времени типа
+	ErrorCodeTimeout = "timeout"
+	// ErrorCodeInvalidInput - исполнитель не смог разобрать вход задачи
+	ErrorCodeInvalidInput = "invalid_input"
+	// ErrorCodeInvalidOutput - результат не подходит под схему типа
+	ErrorCodeInvalidOutput = "invalid_output"
+	// ErrorCodeUnknownType - тип задачи не зарегистрирован
+	ErrorCodeUnknownType = "unknown_type"
+	// ErrorCodePanic - исполнитель аварийно завершился
+	ErrorCodePanic = "panic"
+)
+
+// TaskError - структурированная причина неудачи задачи
+type TaskError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Retryable - повтор может завершиться успешно
+	Retryable bool                   `json:"retryable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	// Stack - стек вызовов, если исполнитель аварийно завершился
+	Stack string `json:"stack,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TaskLogEntry - строка журнала выполнения задачи
+type TaskLogEntry struct {
+	// Seq - номер строки в журнале задачи, начиная с 1
+	Seq    int64     `json:"seq"`
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Line   string    `json:"line"`
+}
+
+// TaskLogList - ответ GET /tasks/{id}/logs
+type TaskLogList struct {
+	Entries []TaskLogEntry `json:"entries"`
+}
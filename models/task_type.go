@@ -0,0 +1,51 @@
+package models
+
+import "encoding/json"
+
+// TaskTypeInfo - описание типа задачи, публикуемое через API
+type TaskTypeInfo struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"input_schema,omitempty"`
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"`
+	// TimeoutSeconds - ограничение времени одной попытки (0 - без ограничения)
+	TimeoutSeconds float64      `json:"timeout_seconds,omitempty"`
+	RetryPolicy    *RetryPolicy `json:"retry_policy,omitempty"`
+	// MaxConcurrency - лимит одновременно выполняющихся задач типа (0 - без ограничения)
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// QueueDepth и Processing - текущее число ожидающих и выполняющихся задач типа
+	QueueDepth int `json:"queue_depth"`
+	Processing int `json:"processing"`
+}
+
+type RetryPolicy struct {
+	MaxAttempts       int     `json:"max_attempts"`
+	BackoffSeconds    float64 `json:"backoff_seconds"`
+	MaxBackoffSeconds float64 `json:"max_backoff_seconds,omitempty"`
+}
+
+type TaskTypeList struct {
+	TaskTypes []TaskTypeInfo `json:"task_types"`
+	Total     int            `json:"total"`
+}
+
+// TaskTypeStats - итоги выполнения задач типа за скользящее окно
+type TaskTypeStats struct {
+	Type          string  `json:"type"`
+	WindowSeconds float64 `json:"window_seconds"`
+	Completed     int     `json:"completed"`
+	Failed        int     `json:"failed"`
+	Cancelled     int     `json:"cancelled"`
+	// SuccessRate - доля completed среди completed и failed; null, пока таких задач нет
+	SuccessRate *float64 `json:"success_rate"`
+	// Duration - перцентили длительности выполнения completed и failed задач
+	Duration *DurationPercentiles `json:"duration_seconds,omitempty"`
+}
+
+type DurationPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}